@@ -0,0 +1,83 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Fake is a deterministic provider for tests and local development. It
+// returns its configured events, or three events derived from the prompt.
+// Each event costs ten output tokens, and it stops at the last event that
+// fits in maxOutputTokens.
+type Fake struct {
+	Events []Event
+	Err    error
+}
+
+func NewFake(events []Event) *Fake {
+	return &Fake{Events: events}
+}
+
+func (f *Fake) Name() string  { return ProviderFake }
+func (f *Fake) Model() string { return "fake" }
+
+// fakeEventTokens is the output tokens the fake charges for each event.
+const fakeEventTokens = 10
+
+func (f *Fake) GenerateEvents(ctx context.Context, prompt string, maxOutputTokens int) (*Result, error) {
+	if f.Err != nil {
+		return nil, f.Err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	events := f.Events
+	if events == nil {
+		events = fakeEvents(prompt)
+	}
+	if maxOutputTokens > 0 && len(events)*fakeEventTokens > maxOutputTokens {
+		events = events[:maxOutputTokens/fakeEventTokens]
+	}
+
+	return &Result{
+		Events: events,
+		Usage: Usage{
+			PromptTokens: len(strings.Fields(prompt)),
+			OutputTokens: fakeEventTokens * len(events),
+		},
+	}, nil
+}
+
+func (f *Fake) StreamEvents(ctx context.Context, prompt string, maxOutputTokens int, emit func(Event) error) (Usage, error) {
+	result, err := f.GenerateEvents(ctx, prompt, maxOutputTokens)
+	if err != nil {
+		return Usage{}, err
+	}
+	for _, event := range result.Events {
+		if err := ctx.Err(); err != nil {
+			return result.Usage, err
+		}
+		if err := emit(event); err != nil {
+			return result.Usage, err
+		}
+	}
+	return result.Usage, nil
+}
+
+func fakeEvents(prompt string) []Event {
+	events := make([]Event, 3)
+	for i := range events {
+		year := int64(2000 + i)
+		events[i] = Event{
+			Title:            fmt.Sprintf("%d", year),
+			CardTitle:        fmt.Sprintf("Event %d", i+1),
+			CardSubtitle:     fmt.Sprintf("Step %d of %q", i+1, prompt),
+			CardDetailedText: fmt.Sprintf("Generated event %d for the prompt %q.", i+1, prompt),
+			StartYear:        &year,
+			DatePrecision:    "year",
+		}
+	}
+	return events
+}
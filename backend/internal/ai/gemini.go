@@ -0,0 +1,109 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+
+	"google.golang.org/genai"
+)
+
+const defaultGeminiModel = "gemini-2.5-flash"
+
+type Gemini struct {
+	client *genai.Client
+	model  string
+}
+
+func NewGemini(ctx context.Context, apiKey, model string) (*Gemini, error) {
+	client, err := genai.NewClient(ctx, &genai.ClientConfig{
+		APIKey:  apiKey,
+		Backend: genai.BackendGeminiAPI,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if model == "" {
+		model = defaultGeminiModel
+	}
+	return &Gemini{client: client, model: model}, nil
+}
+
+func (g *Gemini) Name() string  { return ProviderGemini }
+func (g *Gemini) Model() string { return g.model }
+
+func (g *Gemini) GenerateEvents(ctx context.Context, prompt string, maxOutputTokens int) (*Result, error) {
+	response, err := g.client.Models.GenerateContent(ctx, g.model, genai.Text(prompt), g.config(maxOutputTokens))
+	if err != nil {
+		return nil, err
+	}
+
+	text := response.Text()
+	if text == "" {
+		return nil, ErrEmptyResponse
+	}
+
+	var events []Event
+	if err := json.Unmarshal([]byte(text), &events); err != nil {
+		return nil, err
+	}
+
+	result := &Result{Events: events}
+	if response.UsageMetadata != nil {
+		result.Usage = Usage{
+			PromptTokens: int(response.UsageMetadata.PromptTokenCount),
+			OutputTokens: int(response.UsageMetadata.CandidatesTokenCount),
+		}
+	}
+	return result, nil
+}
+
+func (g *Gemini) StreamEvents(ctx context.Context, prompt string, maxOutputTokens int, emit func(Event) error) (Usage, error) {
+	var usage Usage
+	scanner := newEventScanner(emit)
+
+	for response, err := range g.client.Models.GenerateContentStream(ctx, g.model, genai.Text(prompt), g.config(maxOutputTokens)) {
+		if err != nil {
+			return usage, err
+		}
+		if err := scanner.Write(response.Text()); err != nil {
+			return usage, err
+		}
+		if response.UsageMetadata != nil {
+			usage = Usage{
+				PromptTokens: int(response.UsageMetadata.PromptTokenCount),
+				OutputTokens: int(response.UsageMetadata.CandidatesTokenCount),
+			}
+		}
+	}
+	return usage, scanner.Close()
+}
+
+func (g *Gemini) config(maxOutputTokens int) *genai.GenerateContentConfig {
+	properties := make(map[string]*genai.Schema)
+	for _, name := range textFields {
+		properties[name] = &genai.Schema{Type: genai.TypeString, Description: fieldDescriptions[name]}
+	}
+	for _, name := range dateFields {
+		properties[name] = &genai.Schema{Type: genai.TypeInteger, Description: fieldDescriptions[name]}
+	}
+	properties["date_precision"] = &genai.Schema{
+		Type:        genai.TypeString,
+		Enum:        datePrecisions,
+		Description: fieldDescriptions["date_precision"],
+	}
+
+	return &genai.GenerateContentConfig{
+		SystemInstruction: genai.NewContentFromText(systemPrompt, genai.RoleUser),
+		MaxOutputTokens:   int32(maxOutputTokens),
+		ResponseMIMEType:  "application/json",
+		ResponseSchema: &genai.Schema{
+			Type: genai.TypeArray,
+			Items: &genai.Schema{
+				Type:             genai.TypeObject,
+				Properties:       properties,
+				Required:         requiredFields,
+				PropertyOrdering: propertyOrder,
+			},
+		},
+	}
+}
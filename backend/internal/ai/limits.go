@@ -0,0 +1,62 @@
+package ai
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// Limits caps how much each user can spend on generation. A zero value
+// means no limit.
+//
+// The token quotas are counted from the usage log in the database, so they
+// hold across restarts and servers. Each generation reserves the most it may
+// spend there before it starts, so concurrent requests can't overshoot them.
+// The request rate is only tracked in each
+// server's memory: it starts afresh when a server restarts, and behind a load
+// balancer each server allows the full rate on its own.
+type Limits struct {
+	DailyTokens       int64
+	MonthlyTokens     int64
+	RequestsPerMinute float64
+	Burst             int
+}
+
+// LimitsFromEnv reads AI_DAILY_TOKEN_QUOTA, AI_MONTHLY_TOKEN_QUOTA,
+// AI_REQUESTS_PER_MINUTE and AI_REQUEST_BURST.
+func LimitsFromEnv() (Limits, error) {
+	var limits Limits
+	var err error
+
+	if limits.DailyTokens, err = envInt("AI_DAILY_TOKEN_QUOTA"); err != nil {
+		return limits, err
+	}
+	if limits.MonthlyTokens, err = envInt("AI_MONTHLY_TOKEN_QUOTA"); err != nil {
+		return limits, err
+	}
+	if v := os.Getenv("AI_REQUESTS_PER_MINUTE"); v != "" {
+		limits.RequestsPerMinute, err = strconv.ParseFloat(v, 64)
+		if err != nil || limits.RequestsPerMinute < 0 {
+			return limits, fmt.Errorf("ai: invalid AI_REQUESTS_PER_MINUTE %q", v)
+		}
+	}
+	burst, err := envInt("AI_REQUEST_BURST")
+	if err != nil {
+		return limits, err
+	}
+	limits.Burst = int(burst)
+
+	return limits, nil
+}
+
+func envInt(name string) (int64, error) {
+	v := os.Getenv(name)
+	if v == "" {
+		return 0, nil
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("ai: invalid %s %q", name, v)
+	}
+	return n, nil
+}
@@ -0,0 +1,232 @@
+package ai
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// OpenAI talks to any server that implements the OpenAI chat completions API
+// with JSON schema output, including llama.cpp and Ollama.
+type OpenAI struct {
+	baseURL string
+	apiKey  string
+	model   string
+	client  *http.Client
+}
+
+func NewOpenAI(baseURL, apiKey, model string) (*OpenAI, error) {
+	if baseURL == "" {
+		baseURL = "https://api.openai.com/v1"
+	}
+	if model == "" {
+		return nil, errors.New("ai: a model is required for the openai provider")
+	}
+	return &OpenAI{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		apiKey:  apiKey,
+		model:   model,
+		client:  &http.Client{Timeout: 5 * time.Minute},
+	}, nil
+}
+
+func (o *OpenAI) Name() string  { return ProviderOpenAI }
+func (o *OpenAI) Model() string { return o.model }
+
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type chatRequest struct {
+	Model          string         `json:"model"`
+	Messages       []chatMessage  `json:"messages"`
+	ResponseFormat map[string]any `json:"response_format"`
+	MaxTokens      int            `json:"max_tokens,omitempty"`
+	Stream         bool           `json:"stream,omitempty"`
+	StreamOptions  map[string]any `json:"stream_options,omitempty"`
+}
+
+type chatResponse struct {
+	Choices []struct {
+		Message chatMessage `json:"message"`
+	} `json:"choices"`
+	Usage chatUsage `json:"usage"`
+}
+
+type chatUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+}
+
+type chatChunk struct {
+	Choices []struct {
+		Delta chatMessage `json:"delta"`
+	} `json:"choices"`
+	Usage *chatUsage `json:"usage"`
+}
+
+// The schema has to be an object at the top level, so the events are wrapped.
+type eventList struct {
+	Events []Event `json:"events"`
+}
+
+func (o *OpenAI) GenerateEvents(ctx context.Context, prompt string, maxOutputTokens int) (*Result, error) {
+	res, err := o.post(ctx, o.request(prompt, maxOutputTokens))
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	var response chatResponse
+	if err := json.NewDecoder(res.Body).Decode(&response); err != nil {
+		return nil, err
+	}
+	if len(response.Choices) == 0 || response.Choices[0].Message.Content == "" {
+		return nil, ErrEmptyResponse
+	}
+
+	var list eventList
+	if err := json.Unmarshal([]byte(response.Choices[0].Message.Content), &list); err != nil {
+		return nil, err
+	}
+
+	return &Result{
+		Events: list.Events,
+		Usage: Usage{
+			PromptTokens: response.Usage.PromptTokens,
+			OutputTokens: response.Usage.CompletionTokens,
+		},
+	}, nil
+}
+
+func (o *OpenAI) StreamEvents(ctx context.Context, prompt string, maxOutputTokens int, emit func(Event) error) (Usage, error) {
+	body := o.request(prompt, maxOutputTokens)
+	body.Stream = true
+	body.StreamOptions = map[string]any{"include_usage": true}
+
+	res, err := o.post(ctx, body)
+	if err != nil {
+		return Usage{}, err
+	}
+	defer res.Body.Close()
+
+	var usage Usage
+	scanner := newEventScanner(emit)
+
+	lines := bufio.NewScanner(res.Body)
+	lines.Buffer(make([]byte, 64*1024), 1024*1024)
+	for lines.Scan() {
+		data, ok := strings.CutPrefix(lines.Text(), "data:")
+		if !ok {
+			continue
+		}
+		data = strings.TrimSpace(data)
+		if data == "[DONE]" {
+			break
+		}
+
+		var chunk chatChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return usage, err
+		}
+		if chunk.Usage != nil {
+			usage = Usage{
+				PromptTokens: chunk.Usage.PromptTokens,
+				OutputTokens: chunk.Usage.CompletionTokens,
+			}
+		}
+		if len(chunk.Choices) == 0 {
+			continue
+		}
+		if err := scanner.Write(chunk.Choices[0].Delta.Content); err != nil {
+			return usage, err
+		}
+	}
+	if err := lines.Err(); err != nil {
+		return usage, err
+	}
+	return usage, scanner.Close()
+}
+
+func (o *OpenAI) request(prompt string, maxOutputTokens int) chatRequest {
+	return chatRequest{
+		Model:     o.model,
+		MaxTokens: maxOutputTokens,
+		Messages: []chatMessage{
+			{Role: "system", Content: systemPrompt},
+			{Role: "user", Content: prompt},
+		},
+		ResponseFormat: map[string]any{
+			"type": "json_schema",
+			"json_schema": map[string]any{
+				"name":   "timeline_events",
+				"schema": jsonSchema(),
+			},
+		},
+	}
+}
+
+func (o *OpenAI) post(ctx context.Context, body chatRequest) (*http.Response, error) {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.baseURL+"/chat/completions", bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if o.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+o.apiKey)
+	}
+
+	res, err := o.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode != http.StatusOK {
+		defer res.Body.Close()
+		msg, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		return nil, fmt.Errorf("ai: %s returned %s: %s", o.baseURL, res.Status, bytes.TrimSpace(msg))
+	}
+	return res, nil
+}
+
+func jsonSchema() map[string]any {
+	properties := make(map[string]any)
+	for _, name := range textFields {
+		properties[name] = map[string]any{"type": "string", "description": fieldDescriptions[name]}
+	}
+	for _, name := range dateFields {
+		properties[name] = map[string]any{"type": "integer", "description": fieldDescriptions[name]}
+	}
+	properties["date_precision"] = map[string]any{
+		"type":        "string",
+		"enum":        datePrecisions,
+		"description": fieldDescriptions["date_precision"],
+	}
+
+	return map[string]any{
+		"type":     "object",
+		"required": []string{"events"},
+		"properties": map[string]any{
+			"events": map[string]any{
+				"type": "array",
+				"items": map[string]any{
+					"type":       "object",
+					"required":   requiredFields,
+					"properties": properties,
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,141 @@
+// Package ai generates timeline events from a prompt through a configurable
+// model provider.
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// Event is one timeline event produced by a model. Dates are optional and
+// use astronomical years (1 BCE is 0, 44 BCE is -43); times of day are
+// minutes after midnight UTC.
+type Event struct {
+	Title            string `json:"title"`
+	CardTitle        string `json:"card_title"`
+	CardSubtitle     string `json:"card_subtitle"`
+	CardDetailedText string `json:"card_detailed_text"`
+	StartYear        *int64 `json:"start_year,omitempty"`
+	StartMonth       *int16 `json:"start_month,omitempty"`
+	StartDay         *int16 `json:"start_day,omitempty"`
+	StartMinuteOfDay *int16 `json:"start_minute_of_day,omitempty"`
+	EndYear          *int64 `json:"end_year,omitempty"`
+	EndMonth         *int16 `json:"end_month,omitempty"`
+	EndDay           *int16 `json:"end_day,omitempty"`
+	EndMinuteOfDay   *int16 `json:"end_minute_of_day,omitempty"`
+	DatePrecision    string `json:"date_precision,omitempty"`
+}
+
+type Usage struct {
+	PromptTokens int `json:"prompt_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+type Result struct {
+	Events []Event
+	Usage  Usage
+}
+
+// Provider generates timeline events from a user's prompt. A positive
+// maxOutputTokens caps how many tokens the model may generate, so a call
+// can't spend more than a user has left; zero leaves it to the model.
+type Provider interface {
+	Name() string
+	Model() string
+	GenerateEvents(ctx context.Context, prompt string, maxOutputTokens int) (*Result, error)
+	// StreamEvents calls emit with each event as soon as it has been read
+	// from the model. Returning an error from emit stops the stream.
+	StreamEvents(ctx context.Context, prompt string, maxOutputTokens int, emit func(Event) error) (Usage, error)
+}
+
+const (
+	ProviderGemini = "gemini"
+	ProviderOpenAI = "openai"
+	ProviderFake   = "fake"
+)
+
+var ErrEmptyResponse = errors.New("ai: model returned no content")
+
+type Config struct {
+	Provider string
+	Model    string
+	APIKey   string
+	// BaseURL is the root of an OpenAI-compatible API, such as
+	// http://localhost:11434/v1 for Ollama or http://localhost:8080/v1 for
+	// llama.cpp.
+	BaseURL string
+}
+
+// ConfigFromEnv reads AI_PROVIDER, AI_MODEL, AI_API_KEY and AI_BASE_URL.
+// GEMINI_API_KEY is still honoured for the Gemini provider.
+func ConfigFromEnv() Config {
+	cfg := Config{
+		Provider: os.Getenv("AI_PROVIDER"),
+		Model:    os.Getenv("AI_MODEL"),
+		APIKey:   os.Getenv("AI_API_KEY"),
+		BaseURL:  os.Getenv("AI_BASE_URL"),
+	}
+	if cfg.Provider == "" {
+		cfg.Provider = ProviderGemini
+	}
+	if cfg.APIKey == "" && cfg.Provider == ProviderGemini {
+		cfg.APIKey = os.Getenv("GEMINI_API_KEY")
+	}
+	return cfg
+}
+
+func New(ctx context.Context, cfg Config) (Provider, error) {
+	switch cfg.Provider {
+	case ProviderGemini:
+		return NewGemini(ctx, cfg.APIKey, cfg.Model)
+	case ProviderOpenAI:
+		return NewOpenAI(cfg.BaseURL, cfg.APIKey, cfg.Model)
+	case ProviderFake:
+		return NewFake(nil), nil
+	default:
+		return nil, fmt.Errorf("ai: unknown provider %q", cfg.Provider)
+	}
+}
+
+const systemPrompt = `You build timelines. Reply with the events of the timeline the user asks for, in chronological order.
+Give each event a short date label, a card title, a one-sentence subtitle and a paragraph of detail.
+When an event can be placed on a calendar, also give its start date and, if it spans a period, its end date.
+Years are astronomical: 1 BCE is year 0 and 44 BCE is year -43. Times of day are minutes after midnight UTC.
+Set date_precision to year, month, day or minute, and give exactly the date fields that precision needs.`
+
+// EstimatePromptTokens is a cautious guess at how many tokens a request for
+// the prompt costs before the model answers: the instructions sent with it
+// and the prompt, at about three bytes a token.
+func EstimatePromptTokens(prompt string) int {
+	n := len(systemPrompt) + len(prompt)
+	for _, description := range fieldDescriptions {
+		n += len(description)
+	}
+	return n/3 + 1
+}
+
+var fieldDescriptions = map[string]string{
+	"title":               "The main date or time marker for the event, like 'January 2022' 'Week 1', 'Month 2-3' etc.",
+	"card_title":          "A short, concise title for the timeline card.",
+	"card_subtitle":       "A brief, one-sentence subtitle for the event.",
+	"card_detailed_text":  "A detailed, paragraph-length description of the event that occurred.",
+	"start_year":          "The astronomical year the event started in: 1 BCE is 0, 44 BCE is -43. Omit if the event can't be placed on a calendar.",
+	"start_month":         "The month the event started in, from 1 to 12, if known.",
+	"start_day":           "The day of the month the event started on, if known.",
+	"start_minute_of_day": "The time the event started, in minutes after midnight UTC, if known.",
+	"end_year":            "The astronomical year the event ended in, if it spans a period.",
+	"end_month":           "The month the event ended in, from 1 to 12.",
+	"end_day":             "The day of the month the event ended on.",
+	"end_minute_of_day":   "The time the event ended, in minutes after midnight UTC.",
+	"date_precision":      "How precisely the dates are known. The start and end dates must both set exactly the fields this precision needs.",
+}
+
+var (
+	textFields     = []string{"title", "card_title", "card_subtitle", "card_detailed_text"}
+	dateFields     = []string{"start_year", "start_month", "start_day", "start_minute_of_day", "end_year", "end_month", "end_day", "end_minute_of_day"}
+	datePrecisions = []string{"year", "month", "day", "minute"}
+	propertyOrder  = append(append(append([]string{}, textFields...), dateFields...), "date_precision")
+	requiredFields = textFields
+)
@@ -0,0 +1,87 @@
+package ai
+
+import (
+	"encoding/json"
+)
+
+// eventScanner picks complete events out of a JSON document as it is
+// streamed. Every object that sits directly inside an array is decoded as an
+// event, which covers both a bare array and {"events": [...]}.
+type eventScanner struct {
+	emit func(Event) error
+
+	buf      []byte
+	stack    []byte
+	inString bool
+	escaped  bool
+	start    int
+	count    int
+}
+
+func newEventScanner(emit func(Event) error) *eventScanner {
+	return &eventScanner{emit: emit, start: -1}
+}
+
+func (s *eventScanner) Write(chunk string) error {
+	offset := len(s.buf)
+	s.buf = append(s.buf, chunk...)
+
+	for i := offset; i < len(s.buf); i++ {
+		c := s.buf[i]
+
+		if s.inString {
+			switch {
+			case s.escaped:
+				s.escaped = false
+			case c == '\\':
+				s.escaped = true
+			case c == '"':
+				s.inString = false
+			}
+			continue
+		}
+
+		switch c {
+		case '"':
+			s.inString = true
+		case '[', '{':
+			if c == '{' && len(s.stack) > 0 && s.stack[len(s.stack)-1] == '[' {
+				s.start = i
+			}
+			s.stack = append(s.stack, c)
+		case ']', '}':
+			if len(s.stack) == 0 {
+				continue
+			}
+			s.stack = s.stack[:len(s.stack)-1]
+			if c == '}' && s.start >= 0 && len(s.stack) > 0 && s.stack[len(s.stack)-1] == '[' {
+				var event Event
+				if err := json.Unmarshal(s.buf[s.start:i+1], &event); err != nil {
+					return err
+				}
+				s.start = -1
+				s.count++
+				if err := s.emit(event); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	// Only the event being read needs to be kept.
+	if s.start >= 0 {
+		s.buf = s.buf[s.start:]
+		s.start = 0
+	} else {
+		s.buf = s.buf[:0]
+	}
+	return nil
+}
+
+// Close reports a stream that ended before any event was read.
+func (s *eventScanner) Close() error {
+	if s.count == 0 {
+		return ErrEmptyResponse
+	}
+	return nil
+}
@@ -2,21 +2,37 @@ package app
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"os"
+	"strconv"
 
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/nabsk911/chronify/internal/ai"
+	"github.com/nabsk911/chronify/internal/auth"
 	"github.com/nabsk911/chronify/internal/db"
 	"github.com/nabsk911/chronify/internal/handlers"
+	"github.com/nabsk911/chronify/internal/mail"
+	"github.com/nabsk911/chronify/internal/oidc"
+	"github.com/nabsk911/chronify/internal/storage"
+	"github.com/nabsk911/chronify/internal/trash"
 )
 
 type Application struct {
-	DB              *db.Queries
-	DBConn          *pgxpool.Pool
-	Logger          *log.Logger
-	UserHandler     *handlers.UserHandler
-	TimelineHandler *handlers.TimelineHandler
-	EventHandler    *handlers.EventHandler
+	DB                *db.Queries
+	DBConn            *pgxpool.Pool
+	Logger            *log.Logger
+	TokenKeys         *auth.KeySet
+	UserHandler       *handlers.UserHandler
+	TimelineHandler   *handlers.TimelineHandler
+	EventHandler      *handlers.EventHandler
+	PublicHandler     *handlers.PublicHandler
+	TrashHandler      *handlers.TrashHandler
+	TagHandler        *handlers.TagHandler
+	AttachmentHandler *handlers.AttachmentHandler
+	SSOHandler        *handlers.SSOHandler
+	JWKSHandler       *handlers.JWKSHandler
+	TrashPurger       *trash.Purger
 }
 
 func NewApplication() (*Application, error) {
@@ -31,12 +47,97 @@ func NewApplication() (*Application, error) {
 	logger := log.New(os.Stdout, "", log.Ldate|log.Ltime)
 	queries := db.New(conn)
 
+	tokenKeys, err := auth.KeySetFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	aiProvider, err := ai.New(context.Background(), ai.ConfigFromEnv())
+	if err != nil {
+		return nil, err
+	}
+	aiLimits, err := ai.LimitsFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	logger.Printf("Using AI provider %s (%s)", aiProvider.Name(), aiProvider.Model())
+
+	trashRetention, err := trash.RetentionFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := storage.New(storage.ConfigFromEnv())
+	if err != nil {
+		return nil, err
+	}
+	maxAttachmentBytes, err := storage.MaxBytesFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	logger.Printf("Storing attachments with the %s backend", files.Name())
+
+	mailConfig := mail.ConfigFromEnv()
+	mailer, err := mail.New(mailConfig, logger)
+	if err != nil {
+		return nil, err
+	}
+	requireVerifiedEmail, err := requireEmailVerificationFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	logger.Printf("Sending email with the %s driver", mailer.Name())
+
+	totpBox, err := auth.TOTPSecretBoxFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	if totpBox == nil {
+		logger.Printf("TOTP_ENCRYPTION_KEY is not set, two-factor authentication can't be enabled")
+	}
+
+	oidcConfigs, err := oidc.ConfigFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	ssoProviders, err := oidc.New(oidcConfigs)
+	if err != nil {
+		return nil, err
+	}
+	for _, cfg := range oidcConfigs {
+		logger.Printf("SSO login enabled with %s (%s)", cfg.Name, cfg.Issuer)
+	}
+
+	userHandler := handlers.NewUserHandler(queries, conn, tokenKeys, mailer, mailConfig.AppURL, requireVerifiedEmail, totpBox, logger)
+
 	return &Application{
-		DB:              queries,
-		DBConn:          conn,
-		Logger:          logger,
-		UserHandler:     handlers.NewUserHandler(queries, logger),
-		TimelineHandler: handlers.NewTimelineHandler(queries, logger),
-		EventHandler:    handlers.NewEventHandler(queries, logger),
+		DB:                queries,
+		DBConn:            conn,
+		Logger:            logger,
+		TokenKeys:         tokenKeys,
+		UserHandler:       userHandler,
+		TimelineHandler:   handlers.NewTimelineHandler(queries, conn, logger),
+		EventHandler:      handlers.NewEventHandler(queries, conn, aiProvider, aiLimits, logger),
+		PublicHandler:     handlers.NewPublicHandler(queries, logger),
+		TrashHandler:      handlers.NewTrashHandler(queries, conn, trashRetention, logger),
+		TagHandler:        handlers.NewTagHandler(queries, conn, logger),
+		AttachmentHandler: handlers.NewAttachmentHandler(queries, files, maxAttachmentBytes, logger),
+		SSOHandler:        handlers.NewSSOHandler(queries, conn, ssoProviders, userHandler, logger),
+		JWKSHandler:       handlers.NewJWKSHandler(tokenKeys),
+		TrashPurger:       trash.NewPurger(queries, files, trashRetention, logger),
 	}, nil
 }
+
+// requireEmailVerificationFromEnv reads REQUIRE_EMAIL_VERIFICATION. Unless it
+// is set, users can log in before verifying their email.
+func requireEmailVerificationFromEnv() (bool, error) {
+	value := os.Getenv("REQUIRE_EMAIL_VERIFICATION")
+	if value == "" {
+		return false, nil
+	}
+	required, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("invalid REQUIRE_EMAIL_VERIFICATION %q", value)
+	}
+	return required, nil
+}
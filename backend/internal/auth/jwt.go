@@ -1,41 +1,81 @@
 package auth
 
 import (
-	"os"
+	"context"
+	"errors"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const (
+	AccessTokenTTL = 15 * time.Minute
+
+	// TokenIssuer and TokenAudience are set on every access token, so
+	// services checking tokens against the JWKS can tell Chronify's apart
+	// from others signed with keys they trust.
+	TokenIssuer   = "chronify"
+	TokenAudience = "chronify-api"
+)
+
+var ErrSessionRevoked = errors.New("session has been revoked")
+
 type Claims struct {
-	UserID string `json:"user_id"`
+	UserID    string `json:"user_id"`
+	SessionID string `json:"sid"`
 	jwt.RegisteredClaims
 }
 
-func getSecretKey() []byte {
-	return []byte(os.Getenv("JWT_SECRET_KEY"))
+// SessionStore reports whether the session an access token was issued for is
+// still active. *db.Queries satisfies it.
+type SessionStore interface {
+	IsSessionActive(ctx context.Context, familyID pgtype.UUID) (bool, error)
 }
 
-func GenerateToken(userID string) (string, error) {
-	expirationTime := time.Now().Add(24 * time.Hour)
+// GenerateToken signs an access token with the current signing key, naming
+// it in the kid header.
+func (ks *KeySet) GenerateToken(userID, sessionID string) (string, error) {
+	expirationTime := time.Now().Add(AccessTokenTTL)
 	claims := &Claims{
-		UserID: userID,
+		UserID:    userID,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    TokenIssuer,
+			Audience:  jwt.ClaimStrings{TokenAudience},
 			ExpiresAt: jwt.NewNumericDate(expirationTime),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 		},
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(getSecretKey())
+	token := jwt.NewWithClaims(ks.signing.method, claims)
+	token.Header["kid"] = ks.signing.kid
+	return token.SignedString(ks.signing.private)
 }
 
-func ValidateToken(tokenString string) (*Claims, error) {
+// ValidateToken accepts tokens signed with any key in the set, so tokens
+// signed before a rotation stay valid until they expire.
+func (ks *KeySet) ValidateToken(ctx context.Context, tokenString string, sessions SessionStore) (*Claims, error) {
 	claims := &Claims{}
-	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (any, error) {
-		return getSecretKey(), nil
-	})
+	token, err := jwt.ParseWithClaims(tokenString, claims, ks.verificationKey,
+		jwt.WithValidMethods([]string{jwt.SigningMethodES256.Alg(), jwt.SigningMethodRS256.Alg()}),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuer(TokenIssuer),
+		jwt.WithAudience(TokenAudience),
+	)
 	if err != nil || !token.Valid {
 		return nil, err
 	}
+
+	var sessionID pgtype.UUID
+	if err := sessionID.Scan(claims.SessionID); err != nil {
+		return nil, err
+	}
+	active, err := sessions.IsSessionActive(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if !active {
+		return nil, ErrSessionRevoked
+	}
 	return claims, nil
 }
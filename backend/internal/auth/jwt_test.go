@@ -0,0 +1,152 @@
+package auth
+
+import (
+	"context"
+	"crypto/elliptic"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const testSessionID = "5e000000-0000-0000-0000-000000000001"
+
+// sessionStore answers IsSessionActive with active for every session.
+type sessionStore bool
+
+func (s sessionStore) IsSessionActive(ctx context.Context, familyID pgtype.UUID) (bool, error) {
+	return bool(s), nil
+}
+
+// sign signs claims as the key kid, whatever the key set holds for it.
+func sign(t *testing.T, method jwt.SigningMethod, kid string, key any, claims jwt.Claims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func TestValidateToken(t *testing.T) {
+	current := newECKey(t, elliptic.P256())
+	retired := newRSAKey(t, 2048)
+	other := newECKey(t, elliptic.P256())
+	// 2026-09 signed tokens until the rotation; only its public half is kept.
+	ks, err := LoadKeySet(writeKeys(t, map[string][]byte{
+		"2026-09.pem": publicPEM(t, &retired.PublicKey),
+		"2026-10.pem": privatePEM(t, current),
+	}), "2026-10")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	issued, err := ks.GenerateToken("user-1", testSessionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims := func(modify func(c *Claims)) *Claims {
+		c := &Claims{
+			UserID:    "user-1",
+			SessionID: testSessionID,
+			RegisteredClaims: jwt.RegisteredClaims{
+				Issuer:    TokenIssuer,
+				Audience:  jwt.ClaimStrings{TokenAudience},
+				ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+			},
+		}
+		if modify != nil {
+			modify(c)
+		}
+		return c
+	}
+
+	tests := []struct {
+		name   string
+		token  string
+		wantOK bool
+	}{
+		{"issued by the key set", issued, true},
+		{"signed with the retired key", sign(t, jwt.SigningMethodRS256, "2026-09", retired, claims(nil)), true},
+		{"unknown kid", sign(t, jwt.SigningMethodES256, "2026-11", other, claims(nil)), false},
+		{"no kid", sign(t, jwt.SigningMethodES256, "", current, claims(nil)), false},
+		{"kid of a key it wasn't signed with", sign(t, jwt.SigningMethodES256, "2026-10", other, claims(nil)), false},
+		{"alg that isn't the kid's", sign(t, jwt.SigningMethodES256, "2026-09", current, claims(nil)), false},
+		{"HMAC with the public key", sign(t, jwt.SigningMethodHS256, "2026-10", []byte("2026-10"), claims(nil)), false},
+		{"another issuer", sign(t, jwt.SigningMethodES256, "2026-10", current, claims(func(c *Claims) { c.Issuer = "elsewhere" })), false},
+		{"no issuer", sign(t, jwt.SigningMethodES256, "2026-10", current, claims(func(c *Claims) { c.Issuer = "" })), false},
+		{"another audience", sign(t, jwt.SigningMethodES256, "2026-10", current, claims(func(c *Claims) { c.Audience = jwt.ClaimStrings{"other-api"} })), false},
+		{"no audience", sign(t, jwt.SigningMethodES256, "2026-10", current, claims(func(c *Claims) { c.Audience = nil })), false},
+		{"expired", sign(t, jwt.SigningMethodES256, "2026-10", current, claims(func(c *Claims) {
+			c.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+		})), false},
+		{"no expiry", sign(t, jwt.SigningMethodES256, "2026-10", current, claims(func(c *Claims) { c.ExpiresAt = nil })), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ks.ValidateToken(context.Background(), tt.token, sessionStore(true))
+			if !tt.wantOK {
+				if err == nil {
+					t.Errorf("accepted the token, claims %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("rejected the token: %v", err)
+			}
+			if got.UserID != "user-1" || got.SessionID != testSessionID {
+				t.Errorf("claims = %+v, want user-1's session", got)
+			}
+		})
+	}
+}
+
+func TestGenerateToken(t *testing.T) {
+	ks, err := LoadKeySet(writeKeys(t, map[string][]byte{
+		"2026-10.pem": privatePEM(t, newECKey(t, elliptic.P256())),
+	}), "2026-10")
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed, err := ks.GenerateToken("user-1", testSessionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims := &Claims{}
+	token, _, err := jwt.NewParser().ParseUnverified(signed, claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token.Header["kid"] != "2026-10" || token.Method.Alg() != "ES256" {
+		t.Errorf("header = %v, want kid 2026-10 and ES256", token.Header)
+	}
+	if claims.Issuer != TokenIssuer || len(claims.Audience) != 1 || claims.Audience[0] != TokenAudience {
+		t.Errorf("iss %q and aud %v, want %q and %q", claims.Issuer, claims.Audience, TokenIssuer, TokenAudience)
+	}
+	if ttl := time.Until(claims.ExpiresAt.Time); ttl > AccessTokenTTL || ttl < AccessTokenTTL-time.Minute {
+		t.Errorf("the token expires in %v, want %v", ttl, AccessTokenTTL)
+	}
+}
+
+func TestValidateTokenRevokedSession(t *testing.T) {
+	ks, err := LoadKeySet(writeKeys(t, map[string][]byte{
+		"2026-10.pem": privatePEM(t, newECKey(t, elliptic.P256())),
+	}), "2026-10")
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed, err := ks.GenerateToken("user-1", testSessionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ks.ValidateToken(context.Background(), signed, sessionStore(false)); !errors.Is(err, ErrSessionRevoked) {
+		t.Errorf("err = %v, want ErrSessionRevoked", err)
+	}
+}
@@ -0,0 +1,12 @@
+package auth
+
+// GenerateOIDCState returns the state for an SSO login, which the provider
+// hands back with the code, and the hash that is stored in the
+// oidc_login_states table.
+func GenerateOIDCState() (token string, hash string, err error) {
+	return generateOpaqueToken()
+}
+
+func HashOIDCState(token string) string {
+	return hashOpaqueToken(token)
+}
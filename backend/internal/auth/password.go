@@ -11,7 +11,13 @@ func SetPasswordHash(password string) (string, error) {
 	return string(hash), err
 }
 
+// CheckPasswordHash reports whether password matches hash. Users who signed
+// up through SSO have an empty hash, which matches nothing.
 func CheckPasswordHash(password, hash string) (bool, error) {
+	if hash == "" {
+		return false, nil
+	}
+
 	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
 
 	if err != nil {
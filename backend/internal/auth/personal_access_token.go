@@ -0,0 +1,50 @@
+package auth
+
+import (
+	"slices"
+	"strings"
+)
+
+// Personal access tokens start with this, so they can be told apart from
+// JWTs and spotted if they leak.
+const PersonalAccessTokenPrefix = "chronify_pat_"
+
+// What a personal access token may do. Sessions may do everything.
+const (
+	ScopeTimelinesRead  = "timelines:read"
+	ScopeTimelinesWrite = "timelines:write"
+	ScopeEventsRead     = "events:read"
+	ScopeEventsWrite    = "events:write"
+	ScopeAIGenerate     = "ai:generate"
+)
+
+var Scopes = []string{
+	ScopeTimelinesRead,
+	ScopeTimelinesWrite,
+	ScopeEventsRead,
+	ScopeEventsWrite,
+	ScopeAIGenerate,
+}
+
+func IsValidScope(scope string) bool {
+	return slices.Contains(Scopes, scope)
+}
+
+// GeneratePersonalAccessToken returns a personal access token for the user
+// and the hash that is stored in the personal_access_tokens table.
+func GeneratePersonalAccessToken() (token string, hash string, err error) {
+	token, _, err = generateOpaqueToken()
+	if err != nil {
+		return "", "", err
+	}
+	token = PersonalAccessTokenPrefix + token
+	return token, hashOpaqueToken(token), nil
+}
+
+func HashPersonalAccessToken(token string) string {
+	return hashOpaqueToken(token)
+}
+
+func IsPersonalAccessToken(token string) bool {
+	return strings.HasPrefix(token, PersonalAccessTokenPrefix)
+}
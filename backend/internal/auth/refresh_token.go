@@ -0,0 +1,35 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"time"
+)
+
+const RefreshTokenTTL = 30 * 24 * time.Hour
+
+// GenerateRefreshToken returns an opaque refresh token for the client and the
+// hash that is stored in the sessions table.
+func GenerateRefreshToken() (token string, hash string, err error) {
+	return generateOpaqueToken()
+}
+
+func HashRefreshToken(token string) string {
+	return hashOpaqueToken(token)
+}
+
+func generateOpaqueToken() (token string, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token = base64.RawURLEncoding.EncodeToString(b)
+	return token, hashOpaqueToken(token), nil
+}
+
+func hashOpaqueToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
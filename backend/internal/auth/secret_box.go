@@ -0,0 +1,71 @@
+package auth
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+)
+
+var ErrSecretBoxOpen = errors.New("auth: secret could not be decrypted")
+
+// SecretBox encrypts secrets that have to be read back, such as TOTP
+// secrets, with AES-256-GCM.
+type SecretBox struct {
+	aead cipher.AEAD
+}
+
+func NewSecretBox(key []byte) (*SecretBox, error) {
+	if len(key) != 32 {
+		return nil, fmt.Errorf("auth: encryption key must be 32 bytes, got %d", len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &SecretBox{aead: aead}, nil
+}
+
+// TOTPSecretBoxFromEnv reads TOTP_ENCRYPTION_KEY, a base64-encoded 32-byte
+// key. It returns nil if the key isn't set, in which case two-factor
+// authentication can't be turned on.
+func TOTPSecretBoxFromEnv() (*SecretBox, error) {
+	v := os.Getenv("TOTP_ENCRYPTION_KEY")
+	if v == "" {
+		return nil, nil
+	}
+	key, err := base64.StdEncoding.DecodeString(v)
+	if err != nil {
+		return nil, errors.New("auth: invalid TOTP_ENCRYPTION_KEY: not base64")
+	}
+	return NewSecretBox(key)
+}
+
+// Seal encrypts plaintext for its owner, such as a user ID. Open needs the
+// same owner, so a sealed secret can't be moved to another row.
+func (b *SecretBox) Seal(plaintext, owner []byte) ([]byte, error) {
+	nonce := make([]byte, b.aead.NonceSize(), b.aead.NonceSize()+len(plaintext)+b.aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return b.aead.Seal(nonce, nonce, plaintext, owner), nil
+}
+
+func (b *SecretBox) Open(sealed, owner []byte) ([]byte, error) {
+	size := b.aead.NonceSize()
+	if len(sealed) < size {
+		return nil, ErrSecretBoxOpen
+	}
+	plaintext, err := b.aead.Open(nil, sealed[:size], sealed[size:], owner)
+	if err != nil {
+		return nil, ErrSecretBoxOpen
+	}
+	return plaintext, nil
+}
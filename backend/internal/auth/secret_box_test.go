@@ -0,0 +1,84 @@
+package auth
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func newTestSecretBox(t *testing.T) *SecretBox {
+	t.Helper()
+	box, err := NewSecretBox(bytes.Repeat([]byte{0x42}, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return box
+}
+
+func TestSecretBoxRoundTrip(t *testing.T) {
+	box := newTestSecretBox(t)
+	owner := []byte("user-1")
+
+	sealed, err := box.Seal(rfc6238Secret, owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(sealed, rfc6238Secret) {
+		t.Error("the sealed secret contains the plaintext")
+	}
+	opened, err := box.Open(sealed, owner)
+	if err != nil || !bytes.Equal(opened, rfc6238Secret) {
+		t.Errorf("Open = %q, %v, want the secret back", opened, err)
+	}
+
+	again, _ := box.Seal(rfc6238Secret, owner)
+	if bytes.Equal(sealed, again) {
+		t.Error("sealing twice gave the same bytes; the nonce isn't random")
+	}
+}
+
+func TestSecretBoxRejectsTampering(t *testing.T) {
+	box := newTestSecretBox(t)
+	owner := []byte("user-1")
+	sealed, err := box.Seal(rfc6238Secret, owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := NewSecretBox(bytes.Repeat([]byte{0x43}, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		box    *SecretBox
+		sealed []byte
+		owner  []byte
+	}{
+		{"flipped bit", box, flip(sealed, len(sealed)-1), owner},
+		{"flipped nonce", box, flip(sealed, 0), owner},
+		{"another owner", box, sealed, []byte("user-2")},
+		{"another key", other, sealed, owner},
+		{"truncated", box, sealed[:len(sealed)-1], owner},
+		{"shorter than a nonce", box, sealed[:4], owner},
+	}
+	for _, tt := range tests {
+		if _, err := tt.box.Open(tt.sealed, tt.owner); !errors.Is(err, ErrSecretBoxOpen) {
+			t.Errorf("%s: Open error = %v, want ErrSecretBoxOpen", tt.name, err)
+		}
+	}
+}
+
+func TestNewSecretBoxKeySize(t *testing.T) {
+	for _, size := range []int{0, 16, 31, 33} {
+		if _, err := NewSecretBox(make([]byte, size)); err == nil {
+			t.Errorf("a %d-byte key was accepted", size)
+		}
+	}
+}
+
+func flip(b []byte, i int) []byte {
+	flipped := bytes.Clone(b)
+	flipped[i] ^= 1
+	return flipped
+}
@@ -0,0 +1,11 @@
+package auth
+
+// GenerateShareToken returns the token for a public share link and the hash
+// that is stored in the share_links table.
+func GenerateShareToken() (token string, hash string, err error) {
+	return generateOpaqueToken()
+}
+
+func HashShareToken(token string) string {
+	return hashOpaqueToken(token)
+}
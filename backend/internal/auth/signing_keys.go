@@ -0,0 +1,195 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const minRSAKeyBits = 2048
+
+// signingKey is one key in the key set. Keys that are only kept to check
+// tokens signed before a rotation may have no private half.
+type signingKey struct {
+	kid     string
+	method  jwt.SigningMethod
+	private crypto.Signer
+	public  crypto.PublicKey
+}
+
+// KeySet holds the keys access tokens are signed and checked with. One key
+// signs new tokens; the rest still verify tokens signed before they were
+// rotated out, or are published early so others already have them when
+// they start signing.
+type KeySet struct {
+	signing *signingKey
+	keys    map[string]*signingKey
+}
+
+// KeySetFromEnv loads the keys in JWT_KEYS_DIR and signs with the one named
+// by JWT_SIGNING_KEY_ID. Each key is a PEM file named after its ID, such as
+// 2026-10.pem, holding an EC P-256 key for ES256 or an RSA key of at least
+// 2048 bits for RS256. A file with only a public key can check tokens but
+// not sign them.
+//
+// To rotate, add the new key and wait for other services to pick it up from
+// the JWKS, switch JWT_SIGNING_KEY_ID to it, then remove the old key once
+// the tokens it signed have expired.
+func KeySetFromEnv() (*KeySet, error) {
+	dir := os.Getenv("JWT_KEYS_DIR")
+	if dir == "" {
+		return nil, errors.New("auth: JWT_KEYS_DIR is required")
+	}
+	signingKID := os.Getenv("JWT_SIGNING_KEY_ID")
+	if signingKID == "" {
+		return nil, errors.New("auth: JWT_SIGNING_KEY_ID is required")
+	}
+	return LoadKeySet(dir, signingKID)
+}
+
+// LoadKeySet loads every .pem file in dir as a key. It fails unless the
+// signing key is among them and has its private half.
+func LoadKeySet(dir, signingKID string) (*KeySet, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+
+	ks := &KeySet{keys: make(map[string]*signingKey, len(paths))}
+	for _, path := range paths {
+		kid := strings.TrimSuffix(filepath.Base(path), ".pem")
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("auth: %w", err)
+		}
+		key, err := parseSigningKey(kid, data)
+		if err != nil {
+			return nil, fmt.Errorf("auth: %s: %w", path, err)
+		}
+		ks.keys[kid] = key
+	}
+
+	signing, ok := ks.keys[signingKID]
+	if !ok {
+		return nil, fmt.Errorf("auth: signing key %q not found in %s", signingKID, dir)
+	}
+	if signing.private == nil {
+		return nil, fmt.Errorf("auth: signing key %q has no private key", signingKID)
+	}
+	ks.signing = signing
+	return ks, nil
+}
+
+func parseSigningKey(kid string, data []byte) (*signingKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block")
+	}
+
+	var parsed any
+	var err error
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		parsed, err = x509.ParseECPrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	key := &signingKey{kid: kid}
+	if signer, ok := parsed.(crypto.Signer); ok {
+		key.private = signer
+		key.public = signer.Public()
+	} else {
+		key.public = parsed
+	}
+
+	switch public := key.public.(type) {
+	case *ecdsa.PublicKey:
+		if public.Curve != elliptic.P256() {
+			return nil, errors.New("EC keys must use the P-256 curve")
+		}
+		key.method = jwt.SigningMethodES256
+	case *rsa.PublicKey:
+		if public.N.BitLen() < minRSAKeyBits {
+			return nil, fmt.Errorf("RSA keys must be at least %d bits", minRSAKeyBits)
+		}
+		key.method = jwt.SigningMethodRS256
+	default:
+		return nil, fmt.Errorf("unsupported key type %T", key.public)
+	}
+	return key, nil
+}
+
+// verificationKey returns the key a token says it was signed with, if it is
+// one of ours and matches the token's algorithm.
+func (ks *KeySet) verificationKey(token *jwt.Token) (any, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, ok := ks.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, fmt.Errorf("key %q does not sign with %s", kid, token.Method.Alg())
+	}
+	return key.public, nil
+}
+
+// JWKS returns the public keys as a JSON Web Key Set, for other services to
+// check Chronify's access tokens with.
+func (ks *KeySet) JWKS() map[string]any {
+	kids := make([]string, 0, len(ks.keys))
+	for kid := range ks.keys {
+		kids = append(kids, kid)
+	}
+	slices.Sort(kids)
+
+	keys := make([]map[string]string, 0, len(kids))
+	for _, kid := range kids {
+		key := ks.keys[kid]
+		jwk := map[string]string{
+			"kid": kid,
+			"use": "sig",
+			"alg": key.method.Alg(),
+		}
+		switch public := key.public.(type) {
+		case *ecdsa.PublicKey:
+			point, err := public.Bytes()
+			if err != nil {
+				continue
+			}
+			size := (len(point) - 1) / 2
+			jwk["kty"] = "EC"
+			jwk["crv"] = "P-256"
+			jwk["x"] = base64.RawURLEncoding.EncodeToString(point[1 : 1+size])
+			jwk["y"] = base64.RawURLEncoding.EncodeToString(point[1+size:])
+		case *rsa.PublicKey:
+			jwk["kty"] = "RSA"
+			jwk["n"] = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+			jwk["e"] = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+		}
+		keys = append(keys, jwk)
+	}
+	return map[string]any{"keys": keys}
+}
@@ -0,0 +1,199 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newECKey(t *testing.T, curve elliptic.Curve) *ecdsa.PrivateKey {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(curve, rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func newRSAKey(t *testing.T, bits int) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, bits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+// privatePEM encodes the key the way openssl genpkey writes it.
+func privatePEM(t *testing.T, key any) []byte {
+	t.Helper()
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+}
+
+func publicPEM(t *testing.T, key any) []byte {
+	t.Helper()
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+}
+
+// writeKeys writes each file into a new directory and returns it.
+func writeKeys(t *testing.T, files map[string][]byte) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestLoadKeySet(t *testing.T) {
+	p256 := privatePEM(t, newECKey(t, elliptic.P256()))
+
+	tests := []struct {
+		name    string
+		files   map[string][]byte
+		signing string
+		wantErr string
+	}{
+		{name: "P-256 key", files: map[string][]byte{"2026-10.pem": p256}, signing: "2026-10"},
+		{name: "2048-bit RSA key", files: map[string][]byte{"2026-10.pem": privatePEM(t, newRSAKey(t, 2048))}, signing: "2026-10"},
+		{name: "SEC 1 EC key", files: map[string][]byte{"2026-10.pem": func() []byte {
+			der, err := x509.MarshalECPrivateKey(newECKey(t, elliptic.P256()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			return pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})
+		}()}, signing: "2026-10"},
+		{name: "PKCS 1 RSA key", files: map[string][]byte{"2026-10.pem": pem.EncodeToMemory(&pem.Block{
+			Type:  "RSA PRIVATE KEY",
+			Bytes: x509.MarshalPKCS1PrivateKey(newRSAKey(t, 2048)),
+		})}, signing: "2026-10"},
+		{name: "other files are ignored", files: map[string][]byte{"2026-10.pem": p256, "README": []byte("not a key")}, signing: "2026-10"},
+		{name: "P-384 key", files: map[string][]byte{"2026-10.pem": privatePEM(t, newECKey(t, elliptic.P384()))}, signing: "2026-10", wantErr: "P-256"},
+		{name: "1024-bit RSA key", files: map[string][]byte{"2026-10.pem": privatePEM(t, newRSAKey(t, 1024))}, signing: "2026-10", wantErr: "2048 bits"},
+		{name: "not PEM", files: map[string][]byte{"2026-10.pem": []byte("not a key")}, signing: "2026-10", wantErr: "no PEM block"},
+		{name: "a bad key that doesn't sign", files: map[string][]byte{"2026-10.pem": p256, "2026-09.pem": privatePEM(t, newECKey(t, elliptic.P384()))}, signing: "2026-10", wantErr: "2026-09.pem"},
+		{name: "signing key missing", files: map[string][]byte{"2026-10.pem": p256}, signing: "2026-11", wantErr: `"2026-11" not found`},
+		{name: "signing key public only", files: map[string][]byte{"2026-10.pem": publicPEM(t, &newECKey(t, elliptic.P256()).PublicKey)}, signing: "2026-10", wantErr: "no private key"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ks, err := LoadKeySet(writeKeys(t, tt.files), tt.signing)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want one mentioning %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(ks.keys) != 1 || ks.signing.kid != tt.signing {
+				t.Errorf("keys %v signing with %q, want only %q", ks.keys, ks.signing.kid, tt.signing)
+			}
+		})
+	}
+}
+
+func TestLoadKeySetKIDs(t *testing.T) {
+	ec := newECKey(t, elliptic.P256())
+	rsaKey := newRSAKey(t, 2048)
+	dir := writeKeys(t, map[string][]byte{
+		"2026-09.pem": publicPEM(t, &rsaKey.PublicKey),
+		"2026-10.pem": privatePEM(t, ec),
+	})
+
+	ks, err := LoadKeySet(dir, "2026-10")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for kid, want := range map[string]string{"2026-09": "RS256", "2026-10": "ES256"} {
+		key, ok := ks.keys[kid]
+		if !ok {
+			t.Errorf("no key with the kid %q", kid)
+			continue
+		}
+		if key.kid != kid || key.method.Alg() != want {
+			t.Errorf("key %q = kid %q signing with %s, want %s", kid, key.kid, key.method.Alg(), want)
+		}
+	}
+	if ks.keys["2026-09"].private != nil {
+		t.Error("a public key file was given a private half")
+	}
+}
+
+func TestJWKS(t *testing.T) {
+	ec := newECKey(t, elliptic.P256())
+	rsaKey := newRSAKey(t, 2048)
+	dir := writeKeys(t, map[string][]byte{
+		"2026-10.pem": privatePEM(t, ec),
+		"2026-09.pem": publicPEM(t, &rsaKey.PublicKey),
+	})
+	ks, err := LoadKeySet(dir, "2026-10")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keys := ks.JWKS()["keys"].([]map[string]string)
+	if len(keys) != 2 || keys[0]["kid"] != "2026-09" || keys[1]["kid"] != "2026-10" {
+		t.Fatalf("keys = %v, want 2026-09 and 2026-10 in order", keys)
+	}
+	for _, jwk := range keys {
+		if _, ok := jwk["d"]; ok {
+			t.Errorf("key %s publishes its private half", jwk["kid"])
+		}
+		if jwk["use"] != "sig" {
+			t.Errorf("key %s use = %q, want sig", jwk["kid"], jwk["use"])
+		}
+	}
+
+	decode := func(s string) *big.Int {
+		t.Helper()
+		b, err := base64.RawURLEncoding.DecodeString(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return new(big.Int).SetBytes(b)
+	}
+
+	rsaJWK := keys[0]
+	if rsaJWK["kty"] != "RSA" || rsaJWK["alg"] != "RS256" {
+		t.Errorf("2026-09 = %v, want an RS256 RSA key", rsaJWK)
+	}
+	if decode(rsaJWK["n"]).Cmp(rsaKey.N) != 0 || decode(rsaJWK["e"]).Int64() != int64(rsaKey.E) {
+		t.Error("2026-09's modulus or exponent doesn't match the key")
+	}
+
+	ecJWK := keys[1]
+	if ecJWK["kty"] != "EC" || ecJWK["crv"] != "P-256" || ecJWK["alg"] != "ES256" {
+		t.Errorf("2026-10 = %v, want an ES256 P-256 key", ecJWK)
+	}
+	// The point is 0x04 then x and y, each padded to 32 bytes.
+	point, err := ec.PublicKey.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	x := base64.RawURLEncoding.EncodeToString(point[1:33])
+	y := base64.RawURLEncoding.EncodeToString(point[33:])
+	if ecJWK["x"] != x || ecJWK["y"] != y {
+		t.Error("2026-10's point doesn't match the key")
+	}
+}
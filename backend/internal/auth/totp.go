@@ -0,0 +1,146 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// TOTP codes follow RFC 6238 with the defaults authenticator apps expect:
+// HMAC-SHA1, six digits and a 30 second period.
+const (
+	totpIssuer     = "Chronify"
+	totpSecretSize = 20
+	totpPeriod     = 30
+	totpDigits     = 6
+	// totpSkew is how many periods either side of now a code is accepted
+	// for, to allow for clock drift.
+	totpSkew = 1
+)
+
+const (
+	LoginChallengeTTL         = 5 * time.Minute
+	MaxLoginChallengeAttempts = 5
+	RecoveryCodeCount         = 10
+)
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+func GenerateTOTPSecret() ([]byte, error) {
+	secret := make([]byte, totpSecretSize)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	return secret, nil
+}
+
+// EncodeTOTPSecret returns the secret as authenticator apps take it when it
+// is typed in.
+func EncodeTOTPSecret(secret []byte) string {
+	return totpEncoding.EncodeToString(secret)
+}
+
+// TOTPURI returns the otpauth URI for the secret, usually shown as a QR code.
+func TOTPURI(account string, secret []byte) string {
+	params := url.Values{}
+	params.Set("secret", EncodeTOTPSecret(secret))
+	params.Set("issuer", totpIssuer)
+	params.Set("algorithm", "SHA1")
+	params.Set("digits", fmt.Sprint(totpDigits))
+	params.Set("period", fmt.Sprint(totpPeriod))
+
+	u := url.URL{
+		Scheme:   "otpauth",
+		Host:     "totp",
+		Path:     "/" + totpIssuer + ":" + account,
+		RawQuery: params.Encode(),
+	}
+	return u.String()
+}
+
+// ValidateTOTP reports whether code is valid for the secret at now, and the
+// time step it was generated for. Callers should only accept a step later
+// than the last one they accepted, so that a code can't be replayed.
+func ValidateTOTP(secret []byte, code string, now time.Time) (int64, bool) {
+	code = strings.TrimSpace(code)
+	if len(code) != totpDigits {
+		return 0, false
+	}
+
+	current := now.Unix() / totpPeriod
+	for step := current + totpSkew; step >= current-totpSkew; step-- {
+		if subtle.ConstantTimeCompare([]byte(totpCode(secret, step)), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+func totpCode(secret []byte, step int64) string {
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], uint64(step))
+
+	mac := hmac.New(sha1.New, secret)
+	mac.Write(counter[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	mod := uint32(1)
+	for range totpDigits {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", totpDigits, value%mod)
+}
+
+// GenerateRecoveryCodes returns a fresh set of one-time recovery codes for
+// the user and the hashes that are stored in the totp_recovery_codes table.
+func GenerateRecoveryCodes() (codes []string, hashes []string, err error) {
+	for range RecoveryCodeCount {
+		b := make([]byte, 10)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		raw := strings.ToLower(totpEncoding.EncodeToString(b))
+		code := raw[0:4] + "-" + raw[4:8] + "-" + raw[8:12] + "-" + raw[12:16]
+		codes = append(codes, code)
+		hashes = append(hashes, HashRecoveryCode(code))
+	}
+	return codes, hashes, nil
+}
+
+// HashRecoveryCode ignores case, spaces and dashes, so a code is accepted
+// however it was copied down.
+func HashRecoveryCode(code string) string {
+	normalized := strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, strings.ToLower(code))
+	return hashOpaqueToken(normalized)
+}
+
+// IsRecoveryCode tells a recovery code apart from a TOTP code.
+func IsRecoveryCode(code string) bool {
+	return len(strings.TrimSpace(code)) != totpDigits
+}
+
+// GenerateLoginChallenge returns the token that stands for a correct password
+// until the second factor is given, and the hash that is stored in the
+// login_challenges table.
+func GenerateLoginChallenge() (token string, hash string, err error) {
+	return generateOpaqueToken()
+}
+
+func HashLoginChallenge(token string) string {
+	return hashOpaqueToken(token)
+}
@@ -0,0 +1,105 @@
+package auth
+
+import (
+	"regexp"
+	"testing"
+	"time"
+)
+
+// rfc6238Secret is the SHA1 key from RFC 6238 Appendix B.
+var rfc6238Secret = []byte("12345678901234567890")
+
+func TestTOTPCodeRFC6238(t *testing.T) {
+	// The RFC's codes have eight digits; ours are their last six.
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, tt := range tests {
+		if got := totpCode(rfc6238Secret, tt.unix/totpPeriod); got != tt.want {
+			t.Errorf("code at %d = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestValidateTOTPSkew(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	step := now.Unix() / totpPeriod
+	code := totpCode(rfc6238Secret, step)
+
+	for _, tt := range []struct {
+		offset time.Duration
+		want   bool
+	}{
+		{-2 * totpPeriod * time.Second, false},
+		{-totpPeriod * time.Second, true},
+		{0, true},
+		{totpPeriod * time.Second, true},
+		{2 * totpPeriod * time.Second, false},
+	} {
+		got, ok := ValidateTOTP(rfc6238Secret, code, now.Add(tt.offset))
+		if ok != tt.want {
+			t.Errorf("code checked %v away: valid = %v, want %v", tt.offset, ok, tt.want)
+		}
+		if ok && got != step {
+			t.Errorf("code checked %v away: step = %d, want %d, so replays can be refused", tt.offset, got, step)
+		}
+	}
+
+	if _, ok := ValidateTOTP(rfc6238Secret, " "+code+" ", now); !ok {
+		t.Error("a code with spaces around it was refused")
+	}
+	for _, bad := range []string{"", "05047", "0504711", "950471"} {
+		if _, ok := ValidateTOTP(rfc6238Secret, bad, now); ok {
+			t.Errorf("code %q was accepted", bad)
+		}
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, hashes, err := GenerateRecoveryCodes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(codes) != RecoveryCodeCount || len(hashes) != RecoveryCodeCount {
+		t.Fatalf("%d codes and %d hashes, want %d", len(codes), len(hashes), RecoveryCodeCount)
+	}
+
+	format := regexp.MustCompile(`^[a-z2-7]{4}-[a-z2-7]{4}-[a-z2-7]{4}-[a-z2-7]{4}$`)
+	seen := make(map[string]bool)
+	for i, code := range codes {
+		if !format.MatchString(code) {
+			t.Errorf("code %q isn't four groups of four", code)
+		}
+		if !IsRecoveryCode(code) {
+			t.Errorf("code %q is taken for a TOTP code", code)
+		}
+		if seen[code] {
+			t.Errorf("code %q was given twice", code)
+		}
+		seen[code] = true
+		if hashes[i] != HashRecoveryCode(code) {
+			t.Errorf("hash %d isn't its code's", i)
+		}
+	}
+
+	// A code is accepted however it was copied down.
+	for _, typed := range []string{"ABCD-EFGH-IJKL-MNOP", "abcd efgh ijkl mnop", "abcdefghijklmnop"} {
+		if HashRecoveryCode(typed) != HashRecoveryCode("abcd-efgh-ijkl-mnop") {
+			t.Errorf("%q isn't read as abcd-efgh-ijkl-mnop", typed)
+		}
+	}
+	if HashRecoveryCode("abcd-efgh-ijkl-mnoq") == HashRecoveryCode("abcd-efgh-ijkl-mnop") {
+		t.Error("different codes hash the same")
+	}
+	if IsRecoveryCode("123456") {
+		t.Error("a TOTP code is taken for a recovery code")
+	}
+}
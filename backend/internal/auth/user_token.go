@@ -0,0 +1,24 @@
+package auth
+
+import "time"
+
+// What a user token is for.
+const (
+	PurposeVerifyEmail   = "verify_email"
+	PurposeResetPassword = "reset_password"
+)
+
+const (
+	EmailVerificationTTL = 24 * time.Hour
+	PasswordResetTTL     = time.Hour
+)
+
+// GenerateUserToken returns a single-use token for a link sent by email and
+// the hash that is stored in the user_tokens table.
+func GenerateUserToken() (token string, hash string, err error) {
+	return generateOpaqueToken()
+}
+
+func HashUserToken(token string) string {
+	return hashOpaqueToken(token)
+}
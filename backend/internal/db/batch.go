@@ -24,8 +24,17 @@ SET
     card_title = $3, 
     card_subtitle = $4, 
     card_detailed_text = $5,
+    start_year = $6,
+    start_month = $7,
+    start_day = $8,
+    start_minute_of_day = $9,
+    end_year = $10,
+    end_month = $11,
+    end_day = $12,
+    end_minute_of_day = $13,
+    date_precision = $14,
     updated_at = CURRENT_TIMESTAMP
-WHERE id = $1
+WHERE id = $1 AND timeline_id = $15 AND deleted_at IS NULL
 `
 
 type BulkUpdateEventsBatchResults struct {
@@ -40,6 +49,16 @@ type BulkUpdateEventsParams struct {
 	CardTitle        string      `json:"card_title"`
 	CardSubtitle     pgtype.Text `json:"card_subtitle"`
 	CardDetailedText pgtype.Text `json:"card_detailed_text"`
+	StartYear        pgtype.Int8 `json:"start_year"`
+	StartMonth       pgtype.Int2 `json:"start_month"`
+	StartDay         pgtype.Int2 `json:"start_day"`
+	StartMinuteOfDay pgtype.Int2 `json:"start_minute_of_day"`
+	EndYear          pgtype.Int8 `json:"end_year"`
+	EndMonth         pgtype.Int2 `json:"end_month"`
+	EndDay           pgtype.Int2 `json:"end_day"`
+	EndMinuteOfDay   pgtype.Int2 `json:"end_minute_of_day"`
+	DatePrecision    pgtype.Text `json:"date_precision"`
+	TimelineID       pgtype.UUID `json:"timeline_id"`
 }
 
 func (q *Queries) BulkUpdateEvents(ctx context.Context, arg []BulkUpdateEventsParams) *BulkUpdateEventsBatchResults {
@@ -51,6 +70,16 @@ func (q *Queries) BulkUpdateEvents(ctx context.Context, arg []BulkUpdateEventsPa
 			a.CardTitle,
 			a.CardSubtitle,
 			a.CardDetailedText,
+			a.StartYear,
+			a.StartMonth,
+			a.StartDay,
+			a.StartMinuteOfDay,
+			a.EndYear,
+			a.EndMonth,
+			a.EndDay,
+			a.EndMinuteOfDay,
+			a.DatePrecision,
+			a.TimelineID,
 		}
 		batch.Queue(bulkUpdateEvents, vals...)
 	}
@@ -78,3 +107,56 @@ func (b *BulkUpdateEventsBatchResults) Close() error {
 	b.closed = true
 	return b.br.Close()
 }
+
+const updateEventPositions = `-- name: UpdateEventPositions :batchexec
+UPDATE events
+SET position = $2, updated_at = CURRENT_TIMESTAMP
+WHERE id = $1 AND timeline_id = $3 AND deleted_at IS NULL
+`
+
+type UpdateEventPositionsBatchResults struct {
+	br     pgx.BatchResults
+	tot    int
+	closed bool
+}
+
+type UpdateEventPositionsParams struct {
+	ID         pgtype.UUID `json:"id"`
+	Position   string      `json:"position"`
+	TimelineID pgtype.UUID `json:"timeline_id"`
+}
+
+func (q *Queries) UpdateEventPositions(ctx context.Context, arg []UpdateEventPositionsParams) *UpdateEventPositionsBatchResults {
+	batch := &pgx.Batch{}
+	for _, a := range arg {
+		vals := []interface{}{
+			a.ID,
+			a.Position,
+			a.TimelineID,
+		}
+		batch.Queue(updateEventPositions, vals...)
+	}
+	br := q.db.SendBatch(ctx, batch)
+	return &UpdateEventPositionsBatchResults{br, len(arg), false}
+}
+
+func (b *UpdateEventPositionsBatchResults) Exec(f func(int, error)) {
+	defer b.br.Close()
+	for t := 0; t < b.tot; t++ {
+		if b.closed {
+			if f != nil {
+				f(t, ErrBatchAlreadyClosed)
+			}
+			continue
+		}
+		_, err := b.br.Exec()
+		if f != nil {
+			f(t, err)
+		}
+	}
+}
+
+func (b *UpdateEventPositionsBatchResults) Close() error {
+	b.closed = true
+	return b.br.Close()
+}
@@ -34,6 +34,16 @@ func (r iteratorForBulkCreateEvents) Values() ([]interface{}, error) {
 		r.rows[0].CardTitle,
 		r.rows[0].CardSubtitle,
 		r.rows[0].CardDetailedText,
+		r.rows[0].StartYear,
+		r.rows[0].StartMonth,
+		r.rows[0].StartDay,
+		r.rows[0].StartMinuteOfDay,
+		r.rows[0].EndYear,
+		r.rows[0].EndMonth,
+		r.rows[0].EndDay,
+		r.rows[0].EndMinuteOfDay,
+		r.rows[0].DatePrecision,
+		r.rows[0].Position,
 	}, nil
 }
 
@@ -42,5 +52,53 @@ func (r iteratorForBulkCreateEvents) Err() error {
 }
 
 func (q *Queries) BulkCreateEvents(ctx context.Context, arg []BulkCreateEventsParams) (int64, error) {
-	return q.db.CopyFrom(ctx, []string{"events"}, []string{"timeline_id", "title", "card_title", "card_subtitle", "card_detailed_text"}, &iteratorForBulkCreateEvents{rows: arg})
+	return q.db.CopyFrom(ctx, []string{"events"}, []string{"timeline_id", "title", "card_title", "card_subtitle", "card_detailed_text", "start_year", "start_month", "start_day", "start_minute_of_day", "end_year", "end_month", "end_day", "end_minute_of_day", "date_precision", "position"}, &iteratorForBulkCreateEvents{rows: arg})
+}
+
+// iteratorForRestoreEvents implements pgx.CopyFromSource.
+type iteratorForRestoreEvents struct {
+	rows                 []RestoreEventsParams
+	skippedFirstNextCall bool
+}
+
+func (r *iteratorForRestoreEvents) Next() bool {
+	if len(r.rows) == 0 {
+		return false
+	}
+	if !r.skippedFirstNextCall {
+		r.skippedFirstNextCall = true
+		return true
+	}
+	r.rows = r.rows[1:]
+	return len(r.rows) > 0
+}
+
+func (r iteratorForRestoreEvents) Values() ([]interface{}, error) {
+	return []interface{}{
+		r.rows[0].ID,
+		r.rows[0].TimelineID,
+		r.rows[0].Title,
+		r.rows[0].CardTitle,
+		r.rows[0].CardSubtitle,
+		r.rows[0].CardDetailedText,
+		r.rows[0].StartYear,
+		r.rows[0].StartMonth,
+		r.rows[0].StartDay,
+		r.rows[0].StartMinuteOfDay,
+		r.rows[0].EndYear,
+		r.rows[0].EndMonth,
+		r.rows[0].EndDay,
+		r.rows[0].EndMinuteOfDay,
+		r.rows[0].DatePrecision,
+		r.rows[0].Position,
+		r.rows[0].CreatedAt,
+	}, nil
+}
+
+func (r iteratorForRestoreEvents) Err() error {
+	return nil
+}
+
+func (q *Queries) RestoreEvents(ctx context.Context, arg []RestoreEventsParams) (int64, error) {
+	return q.db.CopyFrom(ctx, []string{"events"}, []string{"id", "timeline_id", "title", "card_title", "card_subtitle", "card_detailed_text", "start_year", "start_month", "start_day", "start_minute_of_day", "end_year", "end_month", "end_day", "end_minute_of_day", "date_precision", "position", "created_at"}, &iteratorForRestoreEvents{rows: arg})
 }
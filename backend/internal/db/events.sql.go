@@ -17,24 +17,202 @@ type BulkCreateEventsParams struct {
 	CardTitle        string      `json:"card_title"`
 	CardSubtitle     pgtype.Text `json:"card_subtitle"`
 	CardDetailedText pgtype.Text `json:"card_detailed_text"`
+	StartYear        pgtype.Int8 `json:"start_year"`
+	StartMonth       pgtype.Int2 `json:"start_month"`
+	StartDay         pgtype.Int2 `json:"start_day"`
+	StartMinuteOfDay pgtype.Int2 `json:"start_minute_of_day"`
+	EndYear          pgtype.Int8 `json:"end_year"`
+	EndMonth         pgtype.Int2 `json:"end_month"`
+	EndDay           pgtype.Int2 `json:"end_day"`
+	EndMinuteOfDay   pgtype.Int2 `json:"end_minute_of_day"`
+	DatePrecision    pgtype.Text `json:"date_precision"`
+	Position         string      `json:"position"`
 }
 
-const deleteEvent = `-- name: DeleteEvent :exec
-DELETE FROM events
-WHERE id = $1
+const countEventsByTimelineId = `-- name: CountEventsByTimelineId :one
+SELECT COUNT(*) FROM events
+WHERE timeline_id = $1
+    AND deleted_at IS NULL
+    AND (
+        $2::UUID[] IS NULL
+        OR EXISTS (
+            SELECT 1 FROM event_tags
+            JOIN tags ON tags.id = event_tags.tag_id
+            WHERE event_tags.event_id = events.id
+                AND tags.user_id = $3
+                AND event_tags.tag_id = ANY($2::UUID[])
+        )
+    )
 `
 
-func (q *Queries) DeleteEvent(ctx context.Context, id pgtype.UUID) error {
-	_, err := q.db.Exec(ctx, deleteEvent, id)
-	return err
+type CountEventsByTimelineIdParams struct {
+	TimelineID pgtype.UUID   `json:"timeline_id"`
+	TagIds     []pgtype.UUID `json:"tag_ids"`
+	UserID     pgtype.UUID   `json:"user_id"`
+}
+
+func (q *Queries) CountEventsByTimelineId(ctx context.Context, arg CountEventsByTimelineIdParams) (int64, error) {
+	row := q.db.QueryRow(ctx, countEventsByTimelineId, arg.TimelineID, arg.TagIds, arg.UserID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const createEvent = `-- name: CreateEvent :one
+INSERT INTO events (
+    timeline_id, title, card_title, card_subtitle, card_detailed_text,
+    start_year, start_month, start_day, start_minute_of_day,
+    end_year, end_month, end_day, end_minute_of_day, date_precision, position
+)
+VALUES ( $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+RETURNING id, timeline_id, title, card_title, card_subtitle, card_detailed_text, created_at, updated_at, start_year, start_month, start_day, start_minute_of_day, end_year, end_month, end_day, end_minute_of_day, date_precision, position, deleted_at, search_vector
+`
+
+type CreateEventParams struct {
+	TimelineID       pgtype.UUID `json:"timeline_id"`
+	Title            string      `json:"title"`
+	CardTitle        string      `json:"card_title"`
+	CardSubtitle     pgtype.Text `json:"card_subtitle"`
+	CardDetailedText pgtype.Text `json:"card_detailed_text"`
+	StartYear        pgtype.Int8 `json:"start_year"`
+	StartMonth       pgtype.Int2 `json:"start_month"`
+	StartDay         pgtype.Int2 `json:"start_day"`
+	StartMinuteOfDay pgtype.Int2 `json:"start_minute_of_day"`
+	EndYear          pgtype.Int8 `json:"end_year"`
+	EndMonth         pgtype.Int2 `json:"end_month"`
+	EndDay           pgtype.Int2 `json:"end_day"`
+	EndMinuteOfDay   pgtype.Int2 `json:"end_minute_of_day"`
+	DatePrecision    pgtype.Text `json:"date_precision"`
+	Position         string      `json:"position"`
+}
+
+func (q *Queries) CreateEvent(ctx context.Context, arg CreateEventParams) (Event, error) {
+	row := q.db.QueryRow(ctx, createEvent,
+		arg.TimelineID,
+		arg.Title,
+		arg.CardTitle,
+		arg.CardSubtitle,
+		arg.CardDetailedText,
+		arg.StartYear,
+		arg.StartMonth,
+		arg.StartDay,
+		arg.StartMinuteOfDay,
+		arg.EndYear,
+		arg.EndMonth,
+		arg.EndDay,
+		arg.EndMinuteOfDay,
+		arg.DatePrecision,
+		arg.Position,
+	)
+	var i Event
+	err := row.Scan(
+		&i.ID,
+		&i.TimelineID,
+		&i.Title,
+		&i.CardTitle,
+		&i.CardSubtitle,
+		&i.CardDetailedText,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.StartYear,
+		&i.StartMonth,
+		&i.StartDay,
+		&i.StartMinuteOfDay,
+		&i.EndYear,
+		&i.EndMonth,
+		&i.EndDay,
+		&i.EndMinuteOfDay,
+		&i.DatePrecision,
+		&i.Position,
+		&i.DeletedAt,
+		&i.SearchVector,
+	)
+	return i, err
+}
+
+const getEventById = `-- name: GetEventById :one
+SELECT id, timeline_id, title, card_title, card_subtitle, card_detailed_text, created_at, updated_at, start_year, start_month, start_day, start_minute_of_day, end_year, end_month, end_day, end_minute_of_day, date_precision, position, deleted_at, search_vector FROM events
+WHERE id = $1 AND timeline_id = $2 AND deleted_at IS NULL
+`
+
+type GetEventByIdParams struct {
+	ID         pgtype.UUID `json:"id"`
+	TimelineID pgtype.UUID `json:"timeline_id"`
+}
+
+func (q *Queries) GetEventById(ctx context.Context, arg GetEventByIdParams) (Event, error) {
+	row := q.db.QueryRow(ctx, getEventById, arg.ID, arg.TimelineID)
+	var i Event
+	err := row.Scan(
+		&i.ID,
+		&i.TimelineID,
+		&i.Title,
+		&i.CardTitle,
+		&i.CardSubtitle,
+		&i.CardDetailedText,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.StartYear,
+		&i.StartMonth,
+		&i.StartDay,
+		&i.StartMinuteOfDay,
+		&i.EndYear,
+		&i.EndMonth,
+		&i.EndDay,
+		&i.EndMinuteOfDay,
+		&i.DatePrecision,
+		&i.Position,
+		&i.DeletedAt,
+		&i.SearchVector,
+	)
+	return i, err
+}
+
+const getEventPositions = `-- name: GetEventPositions :many
+SELECT id, position FROM events
+WHERE timeline_id = $1 AND deleted_at IS NULL
+ORDER BY position ASC
+FOR UPDATE
+`
+
+type GetEventPositionsRow struct {
+	ID       pgtype.UUID `json:"id"`
+	Position string      `json:"position"`
+}
+
+func (q *Queries) GetEventPositions(ctx context.Context, timelineID pgtype.UUID) ([]GetEventPositionsRow, error) {
+	rows, err := q.db.Query(ctx, getEventPositions, timelineID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetEventPositionsRow
+	for rows.Next() {
+		var i GetEventPositionsRow
+		if err := rows.Scan(&i.ID, &i.Position); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
 }
 
 const getEventsByTimelineId = `-- name: GetEventsByTimelineId :many
-SELECT id, timeline_id, title, card_title, card_subtitle, card_detailed_text, created_at, updated_at FROM events
-WHERE timeline_id = $1
-ORDER BY created_at ASC
+SELECT id, timeline_id, title, card_title, card_subtitle, card_detailed_text, created_at, updated_at, start_year, start_month, start_day, start_minute_of_day, end_year, end_month, end_day, end_minute_of_day, date_precision, position, deleted_at, search_vector FROM events
+WHERE timeline_id = $1 AND deleted_at IS NULL
+ORDER BY
+    start_year ASC NULLS LAST,
+    start_month ASC NULLS FIRST,
+    start_day ASC NULLS FIRST,
+    start_minute_of_day ASC NULLS FIRST,
+    created_at ASC
 `
 
+// Undated events sort after dated ones; a coarser date sorts before finer
+// dates in the same period.
 func (q *Queries) GetEventsByTimelineId(ctx context.Context, timelineID pgtype.UUID) ([]Event, error) {
 	rows, err := q.db.Query(ctx, getEventsByTimelineId, timelineID)
 	if err != nil {
@@ -53,6 +231,176 @@ func (q *Queries) GetEventsByTimelineId(ctx context.Context, timelineID pgtype.U
 			&i.CardDetailedText,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.StartYear,
+			&i.StartMonth,
+			&i.StartDay,
+			&i.StartMinuteOfDay,
+			&i.EndYear,
+			&i.EndMonth,
+			&i.EndDay,
+			&i.EndMinuteOfDay,
+			&i.DatePrecision,
+			&i.Position,
+			&i.DeletedAt,
+			&i.SearchVector,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getEventsByTimelineIdByPosition = `-- name: GetEventsByTimelineIdByPosition :many
+SELECT id, timeline_id, title, card_title, card_subtitle, card_detailed_text, created_at, updated_at, start_year, start_month, start_day, start_minute_of_day, end_year, end_month, end_day, end_minute_of_day, date_precision, position, deleted_at, search_vector FROM events
+WHERE timeline_id = $1 AND deleted_at IS NULL
+ORDER BY position ASC, created_at ASC
+`
+
+func (q *Queries) GetEventsByTimelineIdByPosition(ctx context.Context, timelineID pgtype.UUID) ([]Event, error) {
+	rows, err := q.db.Query(ctx, getEventsByTimelineIdByPosition, timelineID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Event
+	for rows.Next() {
+		var i Event
+		if err := rows.Scan(
+			&i.ID,
+			&i.TimelineID,
+			&i.Title,
+			&i.CardTitle,
+			&i.CardSubtitle,
+			&i.CardDetailedText,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.StartYear,
+			&i.StartMonth,
+			&i.StartDay,
+			&i.StartMinuteOfDay,
+			&i.EndYear,
+			&i.EndMonth,
+			&i.EndDay,
+			&i.EndMinuteOfDay,
+			&i.DatePrecision,
+			&i.Position,
+			&i.DeletedAt,
+			&i.SearchVector,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getEventsPageByDate = `-- name: GetEventsPageByDate :many
+SELECT id, timeline_id, title, card_title, card_subtitle, card_detailed_text, created_at, updated_at, start_year, start_month, start_day, start_minute_of_day, end_year, end_month, end_day, end_minute_of_day, date_precision, position, deleted_at, search_vector FROM events
+WHERE timeline_id = $1
+    AND deleted_at IS NULL
+    AND (
+        $2::UUID IS NULL
+        OR (
+            COALESCE(start_year, 9223372036854775807),
+            COALESCE(start_month, 0),
+            COALESCE(start_day, 0),
+            COALESCE(start_minute_of_day, -1),
+            created_at,
+            id
+        ) > (
+            COALESCE($3::BIGINT, 9223372036854775807),
+            COALESCE($4::SMALLINT, 0),
+            COALESCE($5::SMALLINT, 0),
+            COALESCE($6::SMALLINT, -1),
+            $7::TIMESTAMPTZ,
+            $2::UUID
+        )
+    )
+    AND (
+        $8::UUID[] IS NULL
+        OR EXISTS (
+            SELECT 1 FROM event_tags
+            JOIN tags ON tags.id = event_tags.tag_id
+            WHERE event_tags.event_id = events.id
+                AND tags.user_id = $9
+                AND event_tags.tag_id = ANY($8::UUID[])
+        )
+    )
+ORDER BY
+    COALESCE(start_year, 9223372036854775807),
+    COALESCE(start_month, 0),
+    COALESCE(start_day, 0),
+    COALESCE(start_minute_of_day, -1),
+    created_at,
+    id
+LIMIT $10
+`
+
+type GetEventsPageByDateParams struct {
+	TimelineID             pgtype.UUID        `json:"timeline_id"`
+	CursorID               pgtype.UUID        `json:"cursor_id"`
+	CursorStartYear        pgtype.Int8        `json:"cursor_start_year"`
+	CursorStartMonth       pgtype.Int2        `json:"cursor_start_month"`
+	CursorStartDay         pgtype.Int2        `json:"cursor_start_day"`
+	CursorStartMinuteOfDay pgtype.Int2        `json:"cursor_start_minute_of_day"`
+	CursorCreatedAt        pgtype.Timestamptz `json:"cursor_created_at"`
+	TagIds                 []pgtype.UUID      `json:"tag_ids"`
+	UserID                 pgtype.UUID        `json:"user_id"`
+	MaxRows                int32              `json:"max_rows"`
+}
+
+// A page of the timeline's events in the order of GetEventsByTimelineId,
+// starting after the cursor when one is given. Missing date parts are
+// replaced with values that sort the same way, so rows can be compared.
+// With tag_ids, only events the user tagged with one of them match.
+func (q *Queries) GetEventsPageByDate(ctx context.Context, arg GetEventsPageByDateParams) ([]Event, error) {
+	rows, err := q.db.Query(ctx, getEventsPageByDate,
+		arg.TimelineID,
+		arg.CursorID,
+		arg.CursorStartYear,
+		arg.CursorStartMonth,
+		arg.CursorStartDay,
+		arg.CursorStartMinuteOfDay,
+		arg.CursorCreatedAt,
+		arg.TagIds,
+		arg.UserID,
+		arg.MaxRows,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Event
+	for rows.Next() {
+		var i Event
+		if err := rows.Scan(
+			&i.ID,
+			&i.TimelineID,
+			&i.Title,
+			&i.CardTitle,
+			&i.CardSubtitle,
+			&i.CardDetailedText,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.StartYear,
+			&i.StartMonth,
+			&i.StartDay,
+			&i.StartMinuteOfDay,
+			&i.EndYear,
+			&i.EndMonth,
+			&i.EndDay,
+			&i.EndMinuteOfDay,
+			&i.DatePrecision,
+			&i.Position,
+			&i.DeletedAt,
+			&i.SearchVector,
 		); err != nil {
 			return nil, err
 		}
@@ -63,3 +411,300 @@ func (q *Queries) GetEventsByTimelineId(ctx context.Context, timelineID pgtype.U
 	}
 	return items, nil
 }
+
+const getEventsPageByPosition = `-- name: GetEventsPageByPosition :many
+SELECT id, timeline_id, title, card_title, card_subtitle, card_detailed_text, created_at, updated_at, start_year, start_month, start_day, start_minute_of_day, end_year, end_month, end_day, end_minute_of_day, date_precision, position, deleted_at, search_vector FROM events
+WHERE timeline_id = $1
+    AND deleted_at IS NULL
+    AND (
+        $2::UUID IS NULL
+        OR (position, created_at, id) > ($3::TEXT, $4::TIMESTAMPTZ, $2::UUID)
+    )
+    AND (
+        $5::UUID[] IS NULL
+        OR EXISTS (
+            SELECT 1 FROM event_tags
+            JOIN tags ON tags.id = event_tags.tag_id
+            WHERE event_tags.event_id = events.id
+                AND tags.user_id = $6
+                AND event_tags.tag_id = ANY($5::UUID[])
+        )
+    )
+ORDER BY position ASC, created_at ASC, id ASC
+LIMIT $7
+`
+
+type GetEventsPageByPositionParams struct {
+	TimelineID      pgtype.UUID        `json:"timeline_id"`
+	CursorID        pgtype.UUID        `json:"cursor_id"`
+	CursorPosition  pgtype.Text        `json:"cursor_position"`
+	CursorCreatedAt pgtype.Timestamptz `json:"cursor_created_at"`
+	TagIds          []pgtype.UUID      `json:"tag_ids"`
+	UserID          pgtype.UUID        `json:"user_id"`
+	MaxRows         int32              `json:"max_rows"`
+}
+
+func (q *Queries) GetEventsPageByPosition(ctx context.Context, arg GetEventsPageByPositionParams) ([]Event, error) {
+	rows, err := q.db.Query(ctx, getEventsPageByPosition,
+		arg.TimelineID,
+		arg.CursorID,
+		arg.CursorPosition,
+		arg.CursorCreatedAt,
+		arg.TagIds,
+		arg.UserID,
+		arg.MaxRows,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Event
+	for rows.Next() {
+		var i Event
+		if err := rows.Scan(
+			&i.ID,
+			&i.TimelineID,
+			&i.Title,
+			&i.CardTitle,
+			&i.CardSubtitle,
+			&i.CardDetailedText,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.StartYear,
+			&i.StartMonth,
+			&i.StartDay,
+			&i.StartMinuteOfDay,
+			&i.EndYear,
+			&i.EndMonth,
+			&i.EndDay,
+			&i.EndMinuteOfDay,
+			&i.DatePrecision,
+			&i.Position,
+			&i.DeletedAt,
+			&i.SearchVector,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getTrashedEventTimelineId = `-- name: GetTrashedEventTimelineId :one
+SELECT events.timeline_id FROM events
+JOIN timelines ON timelines.id = events.timeline_id
+JOIN timeline_members ON timeline_members.timeline_id = events.timeline_id
+WHERE events.id = $1
+    AND timeline_members.user_id = $2
+    AND timeline_members.role IN ('editor', 'owner')
+    AND timelines.deleted_at IS NULL
+    AND events.deleted_at IS NOT NULL
+`
+
+type GetTrashedEventTimelineIdParams struct {
+	ID     pgtype.UUID `json:"id"`
+	UserID pgtype.UUID `json:"user_id"`
+}
+
+func (q *Queries) GetTrashedEventTimelineId(ctx context.Context, arg GetTrashedEventTimelineIdParams) (pgtype.UUID, error) {
+	row := q.db.QueryRow(ctx, getTrashedEventTimelineId, arg.ID, arg.UserID)
+	var timeline_id pgtype.UUID
+	err := row.Scan(&timeline_id)
+	return timeline_id, err
+}
+
+const getTrashedEventsByUserId = `-- name: GetTrashedEventsByUserId :many
+SELECT events.id, events.timeline_id, timelines.title AS timeline_title,
+    events.title, events.card_title, events.deleted_at
+FROM events
+JOIN timelines ON timelines.id = events.timeline_id
+JOIN timeline_members ON timeline_members.timeline_id = events.timeline_id
+WHERE timeline_members.user_id = $1
+    AND timeline_members.role IN ('editor', 'owner')
+    AND timelines.deleted_at IS NULL
+    AND events.deleted_at IS NOT NULL
+ORDER BY events.deleted_at DESC
+`
+
+type GetTrashedEventsByUserIdRow struct {
+	ID            pgtype.UUID        `json:"id"`
+	TimelineID    pgtype.UUID        `json:"timeline_id"`
+	TimelineTitle string             `json:"timeline_title"`
+	Title         string             `json:"title"`
+	CardTitle     string             `json:"card_title"`
+	DeletedAt     pgtype.Timestamptz `json:"deleted_at"`
+}
+
+// Events trashed from timelines the user can edit. Events of a trashed
+// timeline are left out; they come back with it.
+func (q *Queries) GetTrashedEventsByUserId(ctx context.Context, userID pgtype.UUID) ([]GetTrashedEventsByUserIdRow, error) {
+	rows, err := q.db.Query(ctx, getTrashedEventsByUserId, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetTrashedEventsByUserIdRow
+	for rows.Next() {
+		var i GetTrashedEventsByUserIdRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.TimelineID,
+			&i.TimelineTitle,
+			&i.Title,
+			&i.CardTitle,
+			&i.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const purgeEvent = `-- name: PurgeEvent :execrows
+DELETE FROM events
+WHERE id = $1 AND deleted_at IS NOT NULL AND timeline_id IN (
+    SELECT timelines.id FROM timelines
+    JOIN timeline_members ON timeline_members.timeline_id = timelines.id
+    WHERE timeline_members.user_id = $2
+        AND timeline_members.role IN ('editor', 'owner')
+        AND timelines.deleted_at IS NULL
+)
+`
+
+type PurgeEventParams struct {
+	ID     pgtype.UUID `json:"id"`
+	UserID pgtype.UUID `json:"user_id"`
+}
+
+func (q *Queries) PurgeEvent(ctx context.Context, arg PurgeEventParams) (int64, error) {
+	result, err := q.db.Exec(ctx, purgeEvent, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const purgeEventsByIds = `-- name: PurgeEventsByIds :exec
+DELETE FROM events
+WHERE timeline_id = $1 AND id = ANY($2::UUID[])
+`
+
+type PurgeEventsByIdsParams struct {
+	TimelineID pgtype.UUID   `json:"timeline_id"`
+	Ids        []pgtype.UUID `json:"ids"`
+}
+
+// Makes way for RestoreEvents to put the events back with the same IDs.
+func (q *Queries) PurgeEventsByIds(ctx context.Context, arg PurgeEventsByIdsParams) error {
+	_, err := q.db.Exec(ctx, purgeEventsByIds, arg.TimelineID, arg.Ids)
+	return err
+}
+
+const purgeEventsByUserId = `-- name: PurgeEventsByUserId :execrows
+DELETE FROM events
+WHERE deleted_at IS NOT NULL AND timeline_id IN (
+    SELECT timelines.id FROM timelines
+    JOIN timeline_members ON timeline_members.timeline_id = timelines.id
+    WHERE timeline_members.user_id = $1
+        AND timeline_members.role IN ('editor', 'owner')
+        AND timelines.deleted_at IS NULL
+)
+`
+
+func (q *Queries) PurgeEventsByUserId(ctx context.Context, userID pgtype.UUID) (int64, error) {
+	result, err := q.db.Exec(ctx, purgeEventsByUserId, userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const purgeTrashedEvents = `-- name: PurgeTrashedEvents :execrows
+DELETE FROM events
+WHERE deleted_at < $1
+`
+
+// For the purge job: removes events trashed before the cutoff.
+func (q *Queries) PurgeTrashedEvents(ctx context.Context, deletedAt pgtype.Timestamptz) (int64, error) {
+	result, err := q.db.Exec(ctx, purgeTrashedEvents, deletedAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const restoreEvent = `-- name: RestoreEvent :execrows
+UPDATE events
+SET deleted_at = NULL, updated_at = CURRENT_TIMESTAMP
+WHERE id = $1 AND timeline_id = $2 AND deleted_at IS NOT NULL
+`
+
+type RestoreEventParams struct {
+	ID         pgtype.UUID `json:"id"`
+	TimelineID pgtype.UUID `json:"timeline_id"`
+}
+
+func (q *Queries) RestoreEvent(ctx context.Context, arg RestoreEventParams) (int64, error) {
+	result, err := q.db.Exec(ctx, restoreEvent, arg.ID, arg.TimelineID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+type RestoreEventsParams struct {
+	ID               pgtype.UUID        `json:"id"`
+	TimelineID       pgtype.UUID        `json:"timeline_id"`
+	Title            string             `json:"title"`
+	CardTitle        string             `json:"card_title"`
+	CardSubtitle     pgtype.Text        `json:"card_subtitle"`
+	CardDetailedText pgtype.Text        `json:"card_detailed_text"`
+	StartYear        pgtype.Int8        `json:"start_year"`
+	StartMonth       pgtype.Int2        `json:"start_month"`
+	StartDay         pgtype.Int2        `json:"start_day"`
+	StartMinuteOfDay pgtype.Int2        `json:"start_minute_of_day"`
+	EndYear          pgtype.Int8        `json:"end_year"`
+	EndMonth         pgtype.Int2        `json:"end_month"`
+	EndDay           pgtype.Int2        `json:"end_day"`
+	EndMinuteOfDay   pgtype.Int2        `json:"end_minute_of_day"`
+	DatePrecision    pgtype.Text        `json:"date_precision"`
+	Position         string             `json:"position"`
+	CreatedAt        pgtype.Timestamptz `json:"created_at"`
+}
+
+const trashEvent = `-- name: TrashEvent :execrows
+UPDATE events
+SET deleted_at = CURRENT_TIMESTAMP
+WHERE id = $1 AND timeline_id = $2 AND deleted_at IS NULL
+`
+
+type TrashEventParams struct {
+	ID         pgtype.UUID `json:"id"`
+	TimelineID pgtype.UUID `json:"timeline_id"`
+}
+
+func (q *Queries) TrashEvent(ctx context.Context, arg TrashEventParams) (int64, error) {
+	result, err := q.db.Exec(ctx, trashEvent, arg.ID, arg.TimelineID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const trashEventsByTimelineId = `-- name: TrashEventsByTimelineId :exec
+UPDATE events
+SET deleted_at = CURRENT_TIMESTAMP
+WHERE timeline_id = $1 AND deleted_at IS NULL
+`
+
+func (q *Queries) TrashEventsByTimelineId(ctx context.Context, timelineID pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, trashEventsByTimelineId, timelineID)
+	return err
+}
@@ -8,6 +8,33 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+type AiUsage struct {
+	ID             pgtype.UUID        `json:"id"`
+	UserID         pgtype.UUID        `json:"user_id"`
+	TimelineID     pgtype.UUID        `json:"timeline_id"`
+	Provider       string             `json:"provider"`
+	Model          string             `json:"model"`
+	PromptTokens   int32              `json:"prompt_tokens"`
+	OutputTokens   int32              `json:"output_tokens"`
+	LatencyMs      int32              `json:"latency_ms"`
+	Success        bool               `json:"success"`
+	CreatedAt      pgtype.Timestamptz `json:"created_at"`
+	ReservedTokens int32              `json:"reserved_tokens"`
+}
+
+type Attachment struct {
+	ID          pgtype.UUID        `json:"id"`
+	EventID     pgtype.UUID        `json:"event_id"`
+	UploadedBy  pgtype.UUID        `json:"uploaded_by"`
+	Filename    string             `json:"filename"`
+	ContentType string             `json:"content_type"`
+	SizeBytes   int64              `json:"size_bytes"`
+	Width       pgtype.Int4        `json:"width"`
+	Height      pgtype.Int4        `json:"height"`
+	StorageKey  string             `json:"-"`
+	CreatedAt   pgtype.Timestamptz `json:"created_at"`
+}
+
 type Event struct {
 	ID               pgtype.UUID        `json:"id"`
 	TimelineID       pgtype.UUID        `json:"timeline_id"`
@@ -17,22 +44,164 @@ type Event struct {
 	CardDetailedText pgtype.Text        `json:"card_detailed_text"`
 	CreatedAt        pgtype.Timestamptz `json:"created_at"`
 	UpdatedAt        pgtype.Timestamptz `json:"updated_at"`
+	StartYear        pgtype.Int8        `json:"start_year"`
+	StartMonth       pgtype.Int2        `json:"start_month"`
+	StartDay         pgtype.Int2        `json:"start_day"`
+	StartMinuteOfDay pgtype.Int2        `json:"start_minute_of_day"`
+	EndYear          pgtype.Int8        `json:"end_year"`
+	EndMonth         pgtype.Int2        `json:"end_month"`
+	EndDay           pgtype.Int2        `json:"end_day"`
+	EndMinuteOfDay   pgtype.Int2        `json:"end_minute_of_day"`
+	DatePrecision    pgtype.Text        `json:"date_precision"`
+	Position         string             `json:"position"`
+	DeletedAt        pgtype.Timestamptz `json:"deleted_at"`
+	SearchVector     string             `json:"-"`
+}
+
+type EventTag struct {
+	EventID pgtype.UUID `json:"event_id"`
+	TagID   pgtype.UUID `json:"tag_id"`
+}
+
+type LoginChallenge struct {
+	ID        pgtype.UUID        `json:"id"`
+	UserID    pgtype.UUID        `json:"user_id"`
+	TokenHash string             `json:"token_hash"`
+	Attempts  int32              `json:"attempts"`
+	ExpiresAt pgtype.Timestamptz `json:"expires_at"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+}
+
+type OidcLoginState struct {
+	ID           pgtype.UUID        `json:"id"`
+	Provider     string             `json:"provider"`
+	StateHash    string             `json:"state_hash"`
+	Nonce        string             `json:"nonce"`
+	CodeVerifier string             `json:"code_verifier"`
+	ExpiresAt    pgtype.Timestamptz `json:"expires_at"`
+	CreatedAt    pgtype.Timestamptz `json:"created_at"`
+}
+
+type PersonalAccessToken struct {
+	ID         pgtype.UUID        `json:"id"`
+	UserID     pgtype.UUID        `json:"user_id"`
+	Name       string             `json:"name"`
+	TokenHash  string             `json:"token_hash"`
+	Scopes     []string           `json:"scopes"`
+	ExpiresAt  pgtype.Timestamptz `json:"expires_at"`
+	LastUsedAt pgtype.Timestamptz `json:"last_used_at"`
+	RevokedAt  pgtype.Timestamptz `json:"revoked_at"`
+	CreatedAt  pgtype.Timestamptz `json:"created_at"`
+}
+
+type Session struct {
+	ID               pgtype.UUID        `json:"id"`
+	UserID           pgtype.UUID        `json:"user_id"`
+	FamilyID         pgtype.UUID        `json:"family_id"`
+	RefreshTokenHash string             `json:"refresh_token_hash"`
+	ExpiresAt        pgtype.Timestamptz `json:"expires_at"`
+	RotatedAt        pgtype.Timestamptz `json:"rotated_at"`
+	RevokedAt        pgtype.Timestamptz `json:"revoked_at"`
+	CreatedAt        pgtype.Timestamptz `json:"created_at"`
+}
+
+type ShareLink struct {
+	ID           pgtype.UUID        `json:"id"`
+	TimelineID   pgtype.UUID        `json:"timeline_id"`
+	TokenHash    string             `json:"token_hash"`
+	PasswordHash pgtype.Text        `json:"password_hash"`
+	ExpiresAt    pgtype.Timestamptz `json:"expires_at"`
+	RevokedAt    pgtype.Timestamptz `json:"revoked_at"`
+	CreatedBy    pgtype.UUID        `json:"created_by"`
+	CreatedAt    pgtype.Timestamptz `json:"created_at"`
+}
+
+type Tag struct {
+	ID        pgtype.UUID        `json:"id"`
+	UserID    pgtype.UUID        `json:"user_id"`
+	Name      string             `json:"name"`
+	Color     string             `json:"color"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt pgtype.Timestamptz `json:"updated_at"`
 }
 
 type Timeline struct {
+	ID           pgtype.UUID        `json:"id"`
+	UserID       pgtype.UUID        `json:"user_id"`
+	Title        string             `json:"title"`
+	Description  pgtype.Text        `json:"description"`
+	CreatedAt    pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt    pgtype.Timestamptz `json:"updated_at"`
+	DeletedAt    pgtype.Timestamptz `json:"deleted_at"`
+	SearchVector string             `json:"-"`
+}
+
+type TimelineMember struct {
+	TimelineID pgtype.UUID        `json:"timeline_id"`
+	UserID     pgtype.UUID        `json:"user_id"`
+	Role       string             `json:"role"`
+	CreatedAt  pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt  pgtype.Timestamptz `json:"updated_at"`
+}
+
+type TimelineRevision struct {
+	ID           pgtype.UUID        `json:"id"`
+	TimelineID   pgtype.UUID        `json:"timeline_id"`
+	Revision     int32              `json:"revision"`
+	UserID       pgtype.UUID        `json:"user_id"`
+	Action       string             `json:"action"`
+	RestoredFrom pgtype.Int4        `json:"restored_from"`
+	Snapshot     []byte             `json:"snapshot"`
+	CreatedAt    pgtype.Timestamptz `json:"created_at"`
+}
+
+type TimelineTag struct {
+	TimelineID pgtype.UUID `json:"timeline_id"`
+	TagID      pgtype.UUID `json:"tag_id"`
+}
+
+type TotpRecoveryCode struct {
+	ID        pgtype.UUID        `json:"id"`
+	UserID    pgtype.UUID        `json:"user_id"`
+	CodeHash  string             `json:"code_hash"`
+	UsedAt    pgtype.Timestamptz `json:"used_at"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+}
+
+type User struct {
+	ID              pgtype.UUID        `json:"id"`
+	Email           string             `json:"email"`
+	Username        string             `json:"username"`
+	PasswordHash    string             `json:"password_hash"`
+	CreatedAt       pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt       pgtype.Timestamptz `json:"updated_at"`
+	EmailVerifiedAt pgtype.Timestamptz `json:"email_verified_at"`
+}
+
+type UserIdentity struct {
 	ID          pgtype.UUID        `json:"id"`
 	UserID      pgtype.UUID        `json:"user_id"`
-	Title       string             `json:"title"`
-	Description pgtype.Text        `json:"description"`
+	Provider    string             `json:"provider"`
+	Subject     string             `json:"subject"`
+	Email       string             `json:"email"`
 	CreatedAt   pgtype.Timestamptz `json:"created_at"`
-	UpdatedAt   pgtype.Timestamptz `json:"updated_at"`
+	LastLoginAt pgtype.Timestamptz `json:"last_login_at"`
 }
 
-type User struct {
-	ID           pgtype.UUID        `json:"id"`
-	Email        string             `json:"email"`
-	Username     string             `json:"username"`
-	PasswordHash string             `json:"password_hash"`
+type UserToken struct {
+	ID        pgtype.UUID        `json:"id"`
+	UserID    pgtype.UUID        `json:"user_id"`
+	Purpose   string             `json:"purpose"`
+	TokenHash string             `json:"token_hash"`
+	ExpiresAt pgtype.Timestamptz `json:"expires_at"`
+	UsedAt    pgtype.Timestamptz `json:"used_at"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+}
+
+type UserTotp struct {
+	UserID       pgtype.UUID        `json:"user_id"`
+	Secret       []byte             `json:"-"`
+	ConfirmedAt  pgtype.Timestamptz `json:"confirmed_at"`
+	LastUsedStep int64              `json:"last_used_step"`
 	CreatedAt    pgtype.Timestamptz `json:"created_at"`
-	UpdatedAt    pgtype.Timestamptz `json:"updated_at"`
 }
@@ -11,6 +11,37 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const countTimelinesByUserId = `-- name: CountTimelinesByUserId :one
+SELECT COUNT(*) FROM timelines
+JOIN timeline_members ON timeline_members.timeline_id = timelines.id
+WHERE timeline_members.user_id = $1
+    AND timelines.deleted_at IS NULL
+    AND timelines.title ILIKE '%' || $2::TEXT || '%'
+    AND (
+        $3::UUID[] IS NULL
+        OR EXISTS (
+            SELECT 1 FROM timeline_tags
+            JOIN tags ON tags.id = timeline_tags.tag_id
+            WHERE timeline_tags.timeline_id = timelines.id
+                AND tags.user_id = $1
+                AND timeline_tags.tag_id = ANY($3::UUID[])
+        )
+    )
+`
+
+type CountTimelinesByUserIdParams struct {
+	UserID pgtype.UUID   `json:"user_id"`
+	Title  string        `json:"title"`
+	TagIds []pgtype.UUID `json:"tag_ids"`
+}
+
+func (q *Queries) CountTimelinesByUserId(ctx context.Context, arg CountTimelinesByUserIdParams) (int64, error) {
+	row := q.db.QueryRow(ctx, countTimelinesByUserId, arg.UserID, arg.Title, arg.TagIds)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createTimeline = `-- name: CreateTimeline :one
 INSERT INTO TIMELINES (user_id, title, description)
 VALUES ($1, $2, $3)
@@ -44,24 +75,59 @@ func (q *Queries) CreateTimeline(ctx context.Context, arg CreateTimelineParams)
 	return i, err
 }
 
-const deleteTimeline = `-- name: DeleteTimeline :exec
-DELETE FROM timelines
-WHERE id = $1
+const getSharedTimeline = `-- name: GetSharedTimeline :one
+SELECT id, title, description, created_at, updated_at FROM timelines
+WHERE id = $1 AND deleted_at IS NULL
 `
 
-func (q *Queries) DeleteTimeline(ctx context.Context, id pgtype.UUID) error {
-	_, err := q.db.Exec(ctx, deleteTimeline, id)
-	return err
+type GetSharedTimelineRow struct {
+	ID          pgtype.UUID        `json:"id"`
+	Title       string             `json:"title"`
+	Description pgtype.Text        `json:"description"`
+	CreatedAt   pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt   pgtype.Timestamptz `json:"updated_at"`
+}
+
+// For public share links, so it doesn't check membership.
+func (q *Queries) GetSharedTimeline(ctx context.Context, id pgtype.UUID) (GetSharedTimelineRow, error) {
+	row := q.db.QueryRow(ctx, getSharedTimeline, id)
+	var i GetSharedTimelineRow
+	err := row.Scan(
+		&i.ID,
+		&i.Title,
+		&i.Description,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
 }
 
 const getTimeLineById = `-- name: GetTimeLineById :one
-SELECT id, user_id, title, description, created_at, updated_at FROM timelines
-WHERE id = $1
+SELECT timelines.id, timelines.user_id, timelines.title, timelines.description, timelines.created_at, timelines.updated_at, timelines.deleted_at, timelines.search_vector, timeline_members.role FROM timelines
+JOIN timeline_members ON timeline_members.timeline_id = timelines.id
+WHERE timelines.id = $1 AND timeline_members.user_id = $2 AND timelines.deleted_at IS NULL
 `
 
-func (q *Queries) GetTimeLineById(ctx context.Context, id pgtype.UUID) (Timeline, error) {
-	row := q.db.QueryRow(ctx, getTimeLineById, id)
-	var i Timeline
+type GetTimeLineByIdParams struct {
+	ID     pgtype.UUID `json:"id"`
+	UserID pgtype.UUID `json:"user_id"`
+}
+
+type GetTimeLineByIdRow struct {
+	ID           pgtype.UUID        `json:"id"`
+	UserID       pgtype.UUID        `json:"user_id"`
+	Title        string             `json:"title"`
+	Description  pgtype.Text        `json:"description"`
+	CreatedAt    pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt    pgtype.Timestamptz `json:"updated_at"`
+	DeletedAt    pgtype.Timestamptz `json:"deleted_at"`
+	SearchVector string             `json:"-"`
+	Role         string             `json:"role"`
+}
+
+func (q *Queries) GetTimeLineById(ctx context.Context, arg GetTimeLineByIdParams) (GetTimeLineByIdRow, error) {
+	row := q.db.QueryRow(ctx, getTimeLineById, arg.ID, arg.UserID)
+	var i GetTimeLineByIdRow
 	err := row.Scan(
 		&i.ID,
 		&i.UserID,
@@ -69,25 +135,41 @@ func (q *Queries) GetTimeLineById(ctx context.Context, id pgtype.UUID) (Timeline
 		&i.Description,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.SearchVector,
+		&i.Role,
 	)
 	return i, err
 }
 
 const getTimelinesByUserId = `-- name: GetTimelinesByUserId :many
-SELECT id, user_id, title, description, created_at, updated_at FROM timelines
-WHERE user_id = $1
-ORDER BY created_at DESC
+SELECT timelines.id, timelines.user_id, timelines.title, timelines.description, timelines.created_at, timelines.updated_at, timelines.deleted_at, timelines.search_vector, timeline_members.role FROM timelines
+JOIN timeline_members ON timeline_members.timeline_id = timelines.id
+WHERE timeline_members.user_id = $1 AND timelines.deleted_at IS NULL
+ORDER BY timelines.created_at DESC
 `
 
-func (q *Queries) GetTimelinesByUserId(ctx context.Context, userID pgtype.UUID) ([]Timeline, error) {
+type GetTimelinesByUserIdRow struct {
+	ID           pgtype.UUID        `json:"id"`
+	UserID       pgtype.UUID        `json:"user_id"`
+	Title        string             `json:"title"`
+	Description  pgtype.Text        `json:"description"`
+	CreatedAt    pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt    pgtype.Timestamptz `json:"updated_at"`
+	DeletedAt    pgtype.Timestamptz `json:"deleted_at"`
+	SearchVector string             `json:"-"`
+	Role         string             `json:"role"`
+}
+
+func (q *Queries) GetTimelinesByUserId(ctx context.Context, userID pgtype.UUID) ([]GetTimelinesByUserIdRow, error) {
 	rows, err := q.db.Query(ctx, getTimelinesByUserId, userID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []Timeline
+	var items []GetTimelinesByUserIdRow
 	for rows.Next() {
-		var i Timeline
+		var i GetTimelinesByUserIdRow
 		if err := rows.Scan(
 			&i.ID,
 			&i.UserID,
@@ -95,6 +177,9 @@ func (q *Queries) GetTimelinesByUserId(ctx context.Context, userID pgtype.UUID)
 			&i.Description,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.DeletedAt,
+			&i.SearchVector,
+			&i.Role,
 		); err != nil {
 			return nil, err
 		}
@@ -106,26 +191,77 @@ func (q *Queries) GetTimelinesByUserId(ctx context.Context, userID pgtype.UUID)
 	return items, nil
 }
 
-const getTimelinesByUserIdAndTitle = `-- name: GetTimelinesByUserIdAndTitle :many
-SELECT id, user_id, title, description, created_at, updated_at FROM timelines
-WHERE user_id = $1 AND title ILIKE '%' || $2 || '%'
-ORDER BY created_at DESC
+const getTimelinesPageByCreatedAt = `-- name: GetTimelinesPageByCreatedAt :many
+SELECT timelines.id, timelines.user_id, timelines.title, timelines.description, timelines.created_at, timelines.updated_at, timelines.deleted_at, timelines.search_vector, timeline_members.role FROM timelines
+JOIN timeline_members ON timeline_members.timeline_id = timelines.id
+WHERE timeline_members.user_id = $1
+    AND timelines.deleted_at IS NULL
+    AND timelines.title ILIKE '%' || $2::TEXT || '%'
+    AND (
+        $3::UUID IS NULL
+        OR ($4::BOOLEAN AND (timelines.created_at, timelines.id) < ($5::TIMESTAMPTZ, $3::UUID))
+        OR (NOT $4::BOOLEAN AND (timelines.created_at, timelines.id) > ($5::TIMESTAMPTZ, $3::UUID))
+    )
+    AND (
+        $6::UUID[] IS NULL
+        OR EXISTS (
+            SELECT 1 FROM timeline_tags
+            JOIN tags ON tags.id = timeline_tags.tag_id
+            WHERE timeline_tags.timeline_id = timelines.id
+                AND tags.user_id = $1
+                AND timeline_tags.tag_id = ANY($6::UUID[])
+        )
+    )
+ORDER BY
+    CASE WHEN $4::BOOLEAN THEN timelines.created_at END DESC,
+    CASE WHEN $4::BOOLEAN THEN timelines.id END DESC,
+    timelines.created_at ASC,
+    timelines.id ASC
+LIMIT $7
 `
 
-type GetTimelinesByUserIdAndTitleParams struct {
-	UserID  pgtype.UUID `json:"user_id"`
-	Column2 pgtype.Text `json:"column_2"`
+type GetTimelinesPageByCreatedAtParams struct {
+	UserID          pgtype.UUID        `json:"user_id"`
+	Title           string             `json:"title"`
+	CursorID        pgtype.UUID        `json:"cursor_id"`
+	Descending      bool               `json:"descending"`
+	CursorCreatedAt pgtype.Timestamptz `json:"cursor_created_at"`
+	TagIds          []pgtype.UUID      `json:"tag_ids"`
+	MaxRows         int32              `json:"max_rows"`
 }
 
-func (q *Queries) GetTimelinesByUserIdAndTitle(ctx context.Context, arg GetTimelinesByUserIdAndTitleParams) ([]Timeline, error) {
-	rows, err := q.db.Query(ctx, getTimelinesByUserIdAndTitle, arg.UserID, arg.Column2)
+type GetTimelinesPageByCreatedAtRow struct {
+	ID           pgtype.UUID        `json:"id"`
+	UserID       pgtype.UUID        `json:"user_id"`
+	Title        string             `json:"title"`
+	Description  pgtype.Text        `json:"description"`
+	CreatedAt    pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt    pgtype.Timestamptz `json:"updated_at"`
+	DeletedAt    pgtype.Timestamptz `json:"deleted_at"`
+	SearchVector string             `json:"-"`
+	Role         string             `json:"role"`
+}
+
+// A page of the user's timelines by creation time, starting after the
+// cursor when one is given. An empty title matches every timeline; with
+// tag_ids, only timelines the user tagged with one of them match.
+func (q *Queries) GetTimelinesPageByCreatedAt(ctx context.Context, arg GetTimelinesPageByCreatedAtParams) ([]GetTimelinesPageByCreatedAtRow, error) {
+	rows, err := q.db.Query(ctx, getTimelinesPageByCreatedAt,
+		arg.UserID,
+		arg.Title,
+		arg.CursorID,
+		arg.Descending,
+		arg.CursorCreatedAt,
+		arg.TagIds,
+		arg.MaxRows,
+	)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []Timeline
+	var items []GetTimelinesPageByCreatedAtRow
 	for rows.Next() {
-		var i Timeline
+		var i GetTimelinesPageByCreatedAtRow
 		if err := rows.Scan(
 			&i.ID,
 			&i.UserID,
@@ -133,6 +269,143 @@ func (q *Queries) GetTimelinesByUserIdAndTitle(ctx context.Context, arg GetTimel
 			&i.Description,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.DeletedAt,
+			&i.SearchVector,
+			&i.Role,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getTimelinesPageByTitle = `-- name: GetTimelinesPageByTitle :many
+SELECT timelines.id, timelines.user_id, timelines.title, timelines.description, timelines.created_at, timelines.updated_at, timelines.deleted_at, timelines.search_vector, timeline_members.role FROM timelines
+JOIN timeline_members ON timeline_members.timeline_id = timelines.id
+WHERE timeline_members.user_id = $1
+    AND timelines.deleted_at IS NULL
+    AND timelines.title ILIKE '%' || $2::TEXT || '%'
+    AND (
+        $3::UUID IS NULL
+        OR ($4::BOOLEAN AND (lower(timelines.title), timelines.id) < (lower($5::TEXT), $3::UUID))
+        OR (NOT $4::BOOLEAN AND (lower(timelines.title), timelines.id) > (lower($5::TEXT), $3::UUID))
+    )
+    AND (
+        $6::UUID[] IS NULL
+        OR EXISTS (
+            SELECT 1 FROM timeline_tags
+            JOIN tags ON tags.id = timeline_tags.tag_id
+            WHERE timeline_tags.timeline_id = timelines.id
+                AND tags.user_id = $1
+                AND timeline_tags.tag_id = ANY($6::UUID[])
+        )
+    )
+ORDER BY
+    CASE WHEN $4::BOOLEAN THEN lower(timelines.title) END DESC,
+    CASE WHEN $4::BOOLEAN THEN timelines.id END DESC,
+    lower(timelines.title) ASC,
+    timelines.id ASC
+LIMIT $7
+`
+
+type GetTimelinesPageByTitleParams struct {
+	UserID      pgtype.UUID   `json:"user_id"`
+	Title       string        `json:"title"`
+	CursorID    pgtype.UUID   `json:"cursor_id"`
+	Descending  bool          `json:"descending"`
+	CursorTitle pgtype.Text   `json:"cursor_title"`
+	TagIds      []pgtype.UUID `json:"tag_ids"`
+	MaxRows     int32         `json:"max_rows"`
+}
+
+type GetTimelinesPageByTitleRow struct {
+	ID           pgtype.UUID        `json:"id"`
+	UserID       pgtype.UUID        `json:"user_id"`
+	Title        string             `json:"title"`
+	Description  pgtype.Text        `json:"description"`
+	CreatedAt    pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt    pgtype.Timestamptz `json:"updated_at"`
+	DeletedAt    pgtype.Timestamptz `json:"deleted_at"`
+	SearchVector string             `json:"-"`
+	Role         string             `json:"role"`
+}
+
+// Like GetTimelinesPageByCreatedAt, ordered by title ignoring case.
+func (q *Queries) GetTimelinesPageByTitle(ctx context.Context, arg GetTimelinesPageByTitleParams) ([]GetTimelinesPageByTitleRow, error) {
+	rows, err := q.db.Query(ctx, getTimelinesPageByTitle,
+		arg.UserID,
+		arg.Title,
+		arg.CursorID,
+		arg.Descending,
+		arg.CursorTitle,
+		arg.TagIds,
+		arg.MaxRows,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetTimelinesPageByTitleRow
+	for rows.Next() {
+		var i GetTimelinesPageByTitleRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Title,
+			&i.Description,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.DeletedAt,
+			&i.SearchVector,
+			&i.Role,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getTrashedTimelinesByUserId = `-- name: GetTrashedTimelinesByUserId :many
+SELECT timelines.id, timelines.title, timelines.description, timelines.created_at, timelines.deleted_at
+FROM timelines
+JOIN timeline_members ON timeline_members.timeline_id = timelines.id
+WHERE timeline_members.user_id = $1
+    AND timeline_members.role = 'owner'
+    AND timelines.deleted_at IS NOT NULL
+ORDER BY timelines.deleted_at DESC
+`
+
+type GetTrashedTimelinesByUserIdRow struct {
+	ID          pgtype.UUID        `json:"id"`
+	Title       string             `json:"title"`
+	Description pgtype.Text        `json:"description"`
+	CreatedAt   pgtype.Timestamptz `json:"created_at"`
+	DeletedAt   pgtype.Timestamptz `json:"deleted_at"`
+}
+
+func (q *Queries) GetTrashedTimelinesByUserId(ctx context.Context, userID pgtype.UUID) ([]GetTrashedTimelinesByUserIdRow, error) {
+	rows, err := q.db.Query(ctx, getTrashedTimelinesByUserId, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetTrashedTimelinesByUserIdRow
+	for rows.Next() {
+		var i GetTrashedTimelinesByUserIdRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Title,
+			&i.Description,
+			&i.CreatedAt,
+			&i.DeletedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -144,10 +417,151 @@ func (q *Queries) GetTimelinesByUserIdAndTitle(ctx context.Context, arg GetTimel
 	return items, nil
 }
 
+const lockTimeline = `-- name: LockTimeline :exec
+SELECT id FROM timelines
+WHERE id = $1
+FOR NO KEY UPDATE
+`
+
+// Serializes changes to a timeline, so each gets its own revision number.
+// Take it before touching the timeline's events to avoid deadlocks.
+func (q *Queries) LockTimeline(ctx context.Context, id pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, lockTimeline, id)
+	return err
+}
+
+const purgeTimeline = `-- name: PurgeTimeline :execrows
+DELETE FROM timelines
+WHERE id = $1 AND deleted_at IS NOT NULL AND EXISTS (
+    SELECT 1 FROM timeline_members
+    WHERE timeline_members.timeline_id = timelines.id
+        AND timeline_members.user_id = $2
+        AND timeline_members.role = 'owner'
+)
+`
+
+type PurgeTimelineParams struct {
+	ID     pgtype.UUID `json:"id"`
+	UserID pgtype.UUID `json:"user_id"`
+}
+
+func (q *Queries) PurgeTimeline(ctx context.Context, arg PurgeTimelineParams) (int64, error) {
+	result, err := q.db.Exec(ctx, purgeTimeline, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const purgeTimelinesByUserId = `-- name: PurgeTimelinesByUserId :execrows
+DELETE FROM timelines
+WHERE deleted_at IS NOT NULL AND EXISTS (
+    SELECT 1 FROM timeline_members
+    WHERE timeline_members.timeline_id = timelines.id
+        AND timeline_members.user_id = $1
+        AND timeline_members.role = 'owner'
+)
+`
+
+func (q *Queries) PurgeTimelinesByUserId(ctx context.Context, userID pgtype.UUID) (int64, error) {
+	result, err := q.db.Exec(ctx, purgeTimelinesByUserId, userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const purgeTrashedTimelines = `-- name: PurgeTrashedTimelines :execrows
+DELETE FROM timelines
+WHERE deleted_at < $1
+`
+
+// For the purge job: removes timelines trashed before the cutoff.
+func (q *Queries) PurgeTrashedTimelines(ctx context.Context, deletedAt pgtype.Timestamptz) (int64, error) {
+	result, err := q.db.Exec(ctx, purgeTrashedTimelines, deletedAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const restoreTimeline = `-- name: RestoreTimeline :execrows
+UPDATE timelines
+SET deleted_at = NULL
+WHERE id = $1 AND deleted_at IS NOT NULL AND EXISTS (
+    SELECT 1 FROM timeline_members
+    WHERE timeline_members.timeline_id = timelines.id
+        AND timeline_members.user_id = $2
+        AND timeline_members.role = 'owner'
+)
+`
+
+type RestoreTimelineParams struct {
+	ID     pgtype.UUID `json:"id"`
+	UserID pgtype.UUID `json:"user_id"`
+}
+
+func (q *Queries) RestoreTimeline(ctx context.Context, arg RestoreTimelineParams) (int64, error) {
+	result, err := q.db.Exec(ctx, restoreTimeline, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const timelineTitleExists = `-- name: TimelineTitleExists :one
+SELECT EXISTS (
+    SELECT 1 FROM timelines
+    WHERE user_id = $1 AND lower(title) = lower($2::TEXT) AND deleted_at IS NULL
+)
+`
+
+type TimelineTitleExistsParams struct {
+	UserID pgtype.UUID `json:"user_id"`
+	Title  string      `json:"title"`
+}
+
+// Matches the timelines_user_id_title_key index.
+func (q *Queries) TimelineTitleExists(ctx context.Context, arg TimelineTitleExistsParams) (bool, error) {
+	row := q.db.QueryRow(ctx, timelineTitleExists, arg.UserID, arg.Title)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+const trashTimeline = `-- name: TrashTimeline :execrows
+UPDATE timelines
+SET deleted_at = CURRENT_TIMESTAMP
+WHERE id = $1 AND deleted_at IS NULL AND EXISTS (
+    SELECT 1 FROM timeline_members
+    WHERE timeline_members.timeline_id = timelines.id
+        AND timeline_members.user_id = $2
+        AND timeline_members.role = 'owner'
+)
+`
+
+type TrashTimelineParams struct {
+	ID     pgtype.UUID `json:"id"`
+	UserID pgtype.UUID `json:"user_id"`
+}
+
+func (q *Queries) TrashTimeline(ctx context.Context, arg TrashTimelineParams) (int64, error) {
+	result, err := q.db.Exec(ctx, trashTimeline, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const updateTimeline = `-- name: UpdateTimeline :one
 UPDATE timelines
 SET title = $2, description = $3
-WHERE id = $1
+WHERE id = $1 AND deleted_at IS NULL AND EXISTS (
+    SELECT 1 FROM timeline_members
+    WHERE timeline_members.timeline_id = timelines.id
+        AND timeline_members.user_id = $4
+        AND timeline_members.role IN ('editor', 'owner')
+)
 RETURNING id, user_id, title, description
 `
 
@@ -155,6 +569,7 @@ type UpdateTimelineParams struct {
 	ID          pgtype.UUID `json:"id"`
 	Title       string      `json:"title"`
 	Description pgtype.Text `json:"description"`
+	UserID      pgtype.UUID `json:"user_id"`
 }
 
 type UpdateTimelineRow struct {
@@ -165,7 +580,12 @@ type UpdateTimelineRow struct {
 }
 
 func (q *Queries) UpdateTimeline(ctx context.Context, arg UpdateTimelineParams) (UpdateTimelineRow, error) {
-	row := q.db.QueryRow(ctx, updateTimeline, arg.ID, arg.Title, arg.Description)
+	row := q.db.QueryRow(ctx, updateTimeline,
+		arg.ID,
+		arg.Title,
+		arg.Description,
+		arg.UserID,
+	)
 	var i UpdateTimelineRow
 	err := row.Scan(
 		&i.ID,
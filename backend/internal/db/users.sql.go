@@ -36,8 +36,38 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (CreateU
 	return i, err
 }
 
+const createUserWithoutPassword = `-- name: CreateUserWithoutPassword :one
+INSERT INTO users (email, username, password_hash, email_verified_at)
+VALUES ($1, $2, '', CURRENT_TIMESTAMP)
+ON CONFLICT (username) DO NOTHING
+RETURNING id, email, username, password_hash, created_at, updated_at, email_verified_at
+`
+
+type CreateUserWithoutPasswordParams struct {
+	Email    string `json:"email"`
+	Username string `json:"username"`
+}
+
+// For users who sign up through an SSO provider. The empty hash matches no
+// password; one can be set later with a password reset. A taken username
+// returns no row, rather than failing the transaction it runs in.
+func (q *Queries) CreateUserWithoutPassword(ctx context.Context, arg CreateUserWithoutPasswordParams) (User, error) {
+	row := q.db.QueryRow(ctx, createUserWithoutPassword, arg.Email, arg.Username)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.Username,
+		&i.PasswordHash,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.EmailVerifiedAt,
+	)
+	return i, err
+}
+
 const getUserByEmail = `-- name: GetUserByEmail :one
-SELECT id, email, username, password_hash, created_at, updated_at FROM users
+SELECT id, email, username, password_hash, created_at, updated_at, email_verified_at FROM users
 WHERE email = $1
 `
 
@@ -51,6 +81,74 @@ func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error
 		&i.PasswordHash,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.EmailVerifiedAt,
+	)
+	return i, err
+}
+
+const getUserById = `-- name: GetUserById :one
+SELECT id, email, username, password_hash, created_at, updated_at, email_verified_at FROM users
+WHERE id = $1
+`
+
+func (q *Queries) GetUserById(ctx context.Context, id pgtype.UUID) (User, error) {
+	row := q.db.QueryRow(ctx, getUserById, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.Username,
+		&i.PasswordHash,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.EmailVerifiedAt,
 	)
 	return i, err
 }
+
+const getUserByUsernameOrEmail = `-- name: GetUserByUsernameOrEmail :one
+SELECT id, email, username, password_hash, created_at, updated_at, email_verified_at FROM users
+WHERE username = $1 OR email = $1
+`
+
+func (q *Queries) GetUserByUsernameOrEmail(ctx context.Context, identifier string) (User, error) {
+	row := q.db.QueryRow(ctx, getUserByUsernameOrEmail, identifier)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.Username,
+		&i.PasswordHash,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.EmailVerifiedAt,
+	)
+	return i, err
+}
+
+const markEmailVerified = `-- name: MarkEmailVerified :exec
+UPDATE users
+SET email_verified_at = COALESCE(email_verified_at, CURRENT_TIMESTAMP)
+WHERE id = $1
+`
+
+func (q *Queries) MarkEmailVerified(ctx context.Context, id pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, markEmailVerified, id)
+	return err
+}
+
+const updateUserPassword = `-- name: UpdateUserPassword :exec
+UPDATE users
+SET password_hash = $2, updated_at = CURRENT_TIMESTAMP
+WHERE id = $1
+`
+
+type UpdateUserPasswordParams struct {
+	ID           pgtype.UUID `json:"id"`
+	PasswordHash string      `json:"password_hash"`
+}
+
+func (q *Queries) UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) error {
+	_, err := q.db.Exec(ctx, updateUserPassword, arg.ID, arg.PasswordHash)
+	return err
+}
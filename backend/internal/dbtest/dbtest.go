@@ -0,0 +1,340 @@
+// Package dbtest fakes the database behind *db.Queries for handler tests.
+//
+// A DB answers each sqlc query by its name, such as GetTimeLineById, with a
+// function the test registers. Rows are returned as the sqlc row structs and
+// scanned back field by field, so a query's function returns the same type
+// its *db.Queries method does. Queries nobody registered fail, and every
+// query is recorded so tests can check what ran.
+package dbtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Query answers one query. It returns a row struct or a scalar for :one
+// queries, a slice of them for :many queries, and for :exec and :execrows
+// queries the number of rows affected as an int64, or nil.
+type Query func(args []any) (any, error)
+
+// CopyFrom answers a bulk insert with the rows' values, in column order.
+type CopyFrom func(columns []string, rows [][]any) (int64, error)
+
+// Call is a query that ran.
+type Call struct {
+	Name string
+	Args []any
+}
+
+// DB is a fake database. It satisfies db.DBTX, and begins transactions that
+// run their queries against the same functions.
+type DB struct {
+	mu        sync.Mutex
+	queries   map[string]Query
+	copies    map[string]CopyFrom
+	calls     []Call
+	commits   int
+	rollbacks int
+}
+
+func New() *DB {
+	return &DB{
+		queries: make(map[string]Query),
+		copies:  make(map[string]CopyFrom),
+	}
+}
+
+// On answers the query with the name.
+func (d *DB) On(name string, query Query) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.queries[name] = query
+}
+
+// OnCopyFrom answers bulk inserts into the table.
+func (d *DB) OnCopyFrom(table string, copyFrom CopyFrom) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.copies[table] = copyFrom
+}
+
+// Calls returns the queries that ran, in order.
+func (d *DB) Calls() []Call {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]Call(nil), d.calls...)
+}
+
+// Called returns how many times the query ran.
+func (d *DB) Called(name string) int {
+	n := 0
+	for _, call := range d.Calls() {
+		if call.Name == name {
+			n++
+		}
+	}
+	return n
+}
+
+// Commits returns how many transactions were committed.
+func (d *DB) Commits() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.commits
+}
+
+// Rollbacks returns how many transactions were rolled back.
+func (d *DB) Rollbacks() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.rollbacks
+}
+
+// queryName reads the name sqlc puts on the first line of each query.
+func queryName(sql string) string {
+	line, _, _ := strings.Cut(sql, "\n")
+	fields := strings.Fields(line)
+	if len(fields) >= 3 && fields[0] == "--" && fields[1] == "name:" {
+		return fields[2]
+	}
+	return line
+}
+
+func (d *DB) run(sql string, args []any) (any, error) {
+	name := queryName(sql)
+	d.mu.Lock()
+	d.calls = append(d.calls, Call{Name: name, Args: args})
+	query, ok := d.queries[name]
+	d.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("dbtest: unexpected query %s", name)
+	}
+	return query(args)
+}
+
+func (d *DB) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	result, err := d.run(sql, args)
+	if err != nil {
+		return pgconn.CommandTag{}, err
+	}
+	affected, _ := result.(int64)
+	return pgconn.NewCommandTag(fmt.Sprintf("UPDATE %d", affected)), nil
+}
+
+func (d *DB) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	result, err := d.run(sql, args)
+	if err != nil {
+		return nil, err
+	}
+	rows := &rows{}
+	if result != nil {
+		values := reflect.ValueOf(result)
+		if values.Kind() != reflect.Slice {
+			return nil, fmt.Errorf("dbtest: %s returned %T, not a slice", queryName(sql), result)
+		}
+		for i := range values.Len() {
+			rows.values = append(rows.values, values.Index(i).Interface())
+		}
+	}
+	return rows, nil
+}
+
+func (d *DB) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	result, err := d.run(sql, args)
+	return row{value: result, err: err}
+}
+
+func (d *DB) CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error) {
+	table := tableName.Sanitize()
+	table = strings.Trim(table, `"`)
+
+	var values [][]any
+	for rowSrc.Next() {
+		row, err := rowSrc.Values()
+		if err != nil {
+			return 0, err
+		}
+		values = append(values, row)
+	}
+	if err := rowSrc.Err(); err != nil {
+		return 0, err
+	}
+
+	d.mu.Lock()
+	d.calls = append(d.calls, Call{Name: "CopyFrom " + table, Args: []any{values}})
+	copyFrom, ok := d.copies[table]
+	d.mu.Unlock()
+	if !ok {
+		return 0, fmt.Errorf("dbtest: unexpected copy into %s", table)
+	}
+	return copyFrom(columnNames, values)
+}
+
+func (d *DB) SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults {
+	return &batchResults{db: d, queued: b.QueuedQueries}
+}
+
+// Begin starts a transaction. Its queries run straight away; committing or
+// rolling back only counts the transaction.
+func (d *DB) Begin(ctx context.Context) (pgx.Tx, error) {
+	return &tx{DB: d}, nil
+}
+
+// row scans a row struct's fields, or a scalar, into the destinations.
+type row struct {
+	value any
+	err   error
+}
+
+func (r row) Scan(dest ...any) error {
+	if r.err != nil {
+		return r.err
+	}
+	if r.value == nil {
+		return pgx.ErrNoRows
+	}
+	return scan(r.value, dest)
+}
+
+func scan(value any, dest []any) error {
+	v := reflect.ValueOf(value)
+	if v.Kind() == reflect.Pointer {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct || len(dest) == 1 && v.Type().AssignableTo(reflect.TypeOf(dest[0]).Elem()) {
+		return assign(dest[0], v)
+	}
+	if v.NumField() != len(dest) {
+		return fmt.Errorf("dbtest: %s has %d fields, scanning %d columns", v.Type(), v.NumField(), len(dest))
+	}
+	for i := range dest {
+		if err := assign(dest[i], v.Field(i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func assign(dest any, v reflect.Value) error {
+	target := reflect.ValueOf(dest).Elem()
+	if !v.Type().AssignableTo(target.Type()) {
+		return fmt.Errorf("dbtest: can't scan %s into %s", v.Type(), target.Type())
+	}
+	target.Set(v)
+	return nil
+}
+
+type rows struct {
+	values []any
+	next   int
+	err    error
+}
+
+func (r *rows) Close()                                       {}
+func (r *rows) Err() error                                   { return r.err }
+func (r *rows) CommandTag() pgconn.CommandTag                { return pgconn.NewCommandTag("SELECT") }
+func (r *rows) FieldDescriptions() []pgconn.FieldDescription { return nil }
+func (r *rows) RawValues() [][]byte                          { return nil }
+func (r *rows) Conn() *pgx.Conn                              { return nil }
+
+func (r *rows) Next() bool {
+	if r.next >= len(r.values) {
+		return false
+	}
+	r.next++
+	return true
+}
+
+func (r *rows) Scan(dest ...any) error {
+	return scan(r.values[r.next-1], dest)
+}
+
+func (r *rows) Values() ([]any, error) {
+	return nil, errors.New("dbtest: Values is not supported")
+}
+
+type batchResults struct {
+	db     *DB
+	queued []*pgx.QueuedQuery
+	next   int
+}
+
+func (b *batchResults) pop() (*pgx.QueuedQuery, error) {
+	if b.next >= len(b.queued) {
+		return nil, errors.New("dbtest: no more queued queries")
+	}
+	q := b.queued[b.next]
+	b.next++
+	return q, nil
+}
+
+func (b *batchResults) Exec() (pgconn.CommandTag, error) {
+	q, err := b.pop()
+	if err != nil {
+		return pgconn.CommandTag{}, err
+	}
+	return b.db.Exec(context.Background(), q.SQL, q.Arguments...)
+}
+
+func (b *batchResults) Query() (pgx.Rows, error) {
+	q, err := b.pop()
+	if err != nil {
+		return nil, err
+	}
+	return b.db.Query(context.Background(), q.SQL, q.Arguments...)
+}
+
+func (b *batchResults) QueryRow() pgx.Row {
+	q, err := b.pop()
+	if err != nil {
+		return row{err: err}
+	}
+	return b.db.QueryRow(context.Background(), q.SQL, q.Arguments...)
+}
+
+func (b *batchResults) Close() error { return nil }
+
+// tx is a transaction on a DB.
+type tx struct {
+	*DB
+	done bool
+}
+
+func (t *tx) Begin(ctx context.Context) (pgx.Tx, error) { return &tx{DB: t.DB}, nil }
+
+func (t *tx) Commit(ctx context.Context) error {
+	if t.done {
+		return pgx.ErrTxClosed
+	}
+	t.done = true
+	t.mu.Lock()
+	t.commits++
+	t.mu.Unlock()
+	return nil
+}
+
+func (t *tx) Rollback(ctx context.Context) error {
+	if t.done {
+		return pgx.ErrTxClosed
+	}
+	t.done = true
+	t.mu.Lock()
+	t.rollbacks++
+	t.mu.Unlock()
+	return nil
+}
+
+func (t *tx) LargeObjects() pgx.LargeObjects { return pgx.LargeObjects{} }
+
+func (t *tx) Prepare(ctx context.Context, name, sql string) (*pgconn.StatementDescription, error) {
+	return nil, errors.New("dbtest: Prepare is not supported")
+}
+
+func (t *tx) Conn() *pgx.Conn { return nil }
@@ -0,0 +1,74 @@
+package export
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/nabsk911/chronify/internal/db"
+)
+
+// CSVHeader names the columns written by CSV, using the events' JSON field
+// names.
+var CSVHeader = []string{
+	"id", "title", "card_title", "card_subtitle", "card_detailed_text",
+	"start_year", "start_month", "start_day", "start_minute_of_day",
+	"end_year", "end_month", "end_day", "end_minute_of_day",
+	"date_precision", "position",
+}
+
+// CSV writes one row per event. Text that a spreadsheet would run as a
+// formula is written with a leading apostrophe; see SpreadsheetSafe.
+func CSV(w io.Writer, _ db.Timeline, events []Event) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(CSVHeader); err != nil {
+		return err
+	}
+
+	for _, e := range events {
+		record := []string{
+			e.ID.String(), SpreadsheetSafe(e.Title), SpreadsheetSafe(e.CardTitle),
+			SpreadsheetSafe(text(e.CardSubtitle)), SpreadsheetSafe(text(e.CardDetailedText)),
+			int8String(e.StartYear), int2String(e.StartMonth), int2String(e.StartDay), int2String(e.StartMinuteOfDay),
+			int8String(e.EndYear), int2String(e.EndMonth), int2String(e.EndDay), int2String(e.EndMinuteOfDay),
+			text(e.DatePrecision), e.Position,
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// formulaPrefixes are the characters a spreadsheet reads a cell starting
+// with as a formula, and the apostrophe that guards them.
+const formulaPrefixes = "=+-@\t\r'"
+
+// SpreadsheetSafe keeps user text from running as a formula when the file is
+// opened in a spreadsheet, by putting an apostrophe in front of it. Text that
+// already starts with an apostrophe gets another, so importer.ReadCSV can
+// always take exactly one off again.
+func SpreadsheetSafe(s string) string {
+	if s != "" && strings.ContainsRune(formulaPrefixes, rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+func int8String(n pgtype.Int8) string {
+	if !n.Valid {
+		return ""
+	}
+	return strconv.FormatInt(n.Int64, 10)
+}
+
+func int2String(n pgtype.Int2) string {
+	if !n.Valid {
+		return ""
+	}
+	return strconv.Itoa(int(n.Int16))
+}
@@ -0,0 +1,129 @@
+// Package export writes a timeline and its events out as JSON, CSV, Markdown
+// or iCalendar.
+package export
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/nabsk911/chronify/internal/db"
+)
+
+// Event is an event with the tags members gave it.
+type Event struct {
+	db.Event
+	Tags []Tag
+}
+
+// Tag is a tag as it is shown on an event.
+type Tag struct {
+	Name  string `json:"name"`
+	Color string `json:"color"`
+}
+
+// Format is one way of writing a timeline out.
+type Format struct {
+	ContentType string
+	Extension   string
+	Write       func(w io.Writer, timeline db.Timeline, events []Event) error
+}
+
+var Formats = map[string]Format{
+	"json":     {ContentType: "application/json", Extension: "json", Write: JSON},
+	"csv":      {ContentType: "text/csv; charset=utf-8", Extension: "csv", Write: CSV},
+	"markdown": {ContentType: "text/markdown; charset=utf-8", Extension: "md", Write: Markdown},
+	"ics":      {ContentType: "text/calendar; charset=utf-8", Extension: "ics", Write: ICS},
+}
+
+// Filename turns the timeline's title into a safe file name.
+func Filename(title, extension string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(title) {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' {
+			b.WriteRune(r)
+			dash = false
+		} else if !dash && b.Len() > 0 {
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	name := strings.TrimSuffix(b.String(), "-")
+	if name == "" {
+		name = "timeline"
+	}
+	return name + "." + extension
+}
+
+// date is one end of an event's date range, as stored on the event.
+type date struct {
+	year        int64
+	month       int16
+	day         int16
+	minuteOfDay int16
+	precision   string
+}
+
+func startDate(e db.Event) (date, bool) {
+	return newDate(e.DatePrecision, e.StartYear, e.StartMonth, e.StartDay, e.StartMinuteOfDay)
+}
+
+func endDate(e db.Event) (date, bool) {
+	return newDate(e.DatePrecision, e.EndYear, e.EndMonth, e.EndDay, e.EndMinuteOfDay)
+}
+
+func newDate(precision pgtype.Text, year pgtype.Int8, month, day, minuteOfDay pgtype.Int2) (date, bool) {
+	if !year.Valid {
+		return date{}, false
+	}
+	return date{
+		year:        year.Int64,
+		month:       month.Int16,
+		day:         day.Int16,
+		minuteOfDay: minuteOfDay.Int16,
+		precision:   precision.String,
+	}, true
+}
+
+// String formats the date in ISO 8601, to the date's precision. Years
+// outside 0 to 9999 get a sign, as the standard's expanded form requires.
+func (d date) String() string {
+	var s string
+	if d.year < 0 || d.year > 9999 {
+		s = fmt.Sprintf("%+05d", d.year)
+	} else {
+		s = fmt.Sprintf("%04d", d.year)
+	}
+
+	switch d.precision {
+	case "month":
+		s += fmt.Sprintf("-%02d", d.month)
+	case "day":
+		s += fmt.Sprintf("-%02d-%02d", d.month, d.day)
+	case "minute":
+		s += fmt.Sprintf("-%02d-%02dT%02d:%02dZ", d.month, d.day, d.minuteOfDay/60, d.minuteOfDay%60)
+	}
+	return s
+}
+
+// dateRange formats the event's dates as an ISO 8601 interval, or "" if the
+// event is undated.
+func dateRange(e db.Event) string {
+	start, ok := startDate(e)
+	if !ok {
+		return ""
+	}
+	if end, ok := endDate(e); ok {
+		return start.String() + "/" + end.String()
+	}
+	return start.String()
+}
+
+func text(t pgtype.Text) string {
+	if !t.Valid {
+		return ""
+	}
+	return t.String
+}
@@ -0,0 +1,139 @@
+package export
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/nabsk911/chronify/internal/db"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+func int8(n int64) pgtype.Int8 { return pgtype.Int8{Int64: n, Valid: true} }
+func int2(n int16) pgtype.Int2 { return pgtype.Int2{Int16: n, Valid: true} }
+func str(s string) pgtype.Text { return pgtype.Text{String: s, Valid: true} }
+
+func testEvent(n byte, e db.Event) Event {
+	e.ID = pgtype.UUID{Bytes: [16]byte{0: 0xe0, 15: n}, Valid: true}
+	e.Position = string('a' + rune(n))
+	return Event{Event: e}
+}
+
+var testTimeline = db.Timeline{
+	Title:       "Space race; the 20th century, abridged",
+	Description: str("From Sputnik to Apollo"),
+}
+
+var testEvents = []Event{
+	testEvent(1, db.Event{Title: "1957", CardTitle: "Sputnik", StartYear: int8(1957), StartMonth: int2(10), StartDay: int2(4), DatePrecision: str("day")}),
+	testEvent(2, db.Event{Title: "1961", CardTitle: "Vostok and Mercury", StartYear: int8(1961), StartMonth: int2(4), EndYear: int8(1961), EndMonth: int2(5), DatePrecision: str("month")}),
+	testEvent(3, db.Event{Title: "1969", CardTitle: "Apollo 11; \"Eagle\", has landed \\o/", CardDetailedText: str("Armstrong:\nThat's one small step."), StartYear: int8(1969), DatePrecision: str("year")}),
+	testEvent(4, db.Event{
+		Title:            "Landing",
+		CardTitle:        "Eagle lands",
+		CardSubtitle:     str("静かの海に着陸。「ヒューストン、こちら静かの基地。イーグルは着陸した」"),
+		StartYear:        int8(1969),
+		StartMonth:       int2(7),
+		StartDay:         int2(20),
+		StartMinuteOfDay: int2(20*60 + 17),
+		EndYear:          int8(1969),
+		EndMonth:         int2(7),
+		EndDay:           int2(21),
+		EndMinuteOfDay:   int2(2*60 + 56),
+		DatePrecision:    str("minute"),
+	}),
+	testEvent(5, db.Event{Title: "Splashdown", CardTitle: "Splashdown", StartYear: int8(1969), StartMonth: int2(7), StartDay: int2(24), StartMinuteOfDay: int2(16*60 + 50), DatePrecision: str("minute")}),
+	testEvent(6, db.Event{Title: "776 BC", CardTitle: "First Olympics", StartYear: int8(-776), DatePrecision: str("year")}),
+	testEvent(7, db.Event{Title: "Someday", CardTitle: "Mars"}),
+	testEvent(8, db.Event{Title: `=HYPERLINK("http://example.com","Click")`, CardTitle: "+1", CardSubtitle: str("-1"), CardDetailedText: str("@SUM(A1:A2)")}),
+}
+
+// dtstamp is when the file was written, which changes on every run.
+var dtstamp = regexp.MustCompile(`DTSTAMP:\d{8}T\d{6}Z`)
+
+func TestFormatsGolden(t *testing.T) {
+	for name, format := range Formats {
+		if name == "json" {
+			continue
+		}
+		t.Run(name, func(t *testing.T) {
+			var b bytes.Buffer
+			if err := format.Write(&b, testTimeline, testEvents); err != nil {
+				t.Fatal(err)
+			}
+			got := dtstamp.ReplaceAll(b.Bytes(), []byte("DTSTAMP:20240101T000000Z"))
+
+			golden := filepath.Join("testdata", "events."+format.Extension)
+			if *update {
+				if err := os.WriteFile(golden, got, 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("%s differs from %s:\n%s", name, golden, got)
+			}
+		})
+	}
+}
+
+func TestICSLinesFold(t *testing.T) {
+	var b bytes.Buffer
+	if err := ICS(&b, testTimeline, testEvents); err != nil {
+		t.Fatal(err)
+	}
+	ics := b.String()
+	if !strings.HasSuffix(ics, "\r\n") || strings.Contains(strings.ReplaceAll(ics, "\r\n", ""), "\n") {
+		t.Fatal("lines don't all end with CRLF")
+	}
+
+	folded := 0
+	for _, line := range strings.Split(strings.TrimSuffix(ics, "\r\n"), "\r\n") {
+		if len(line) > 75 {
+			t.Errorf("line is %d octets: %q", len(line), line)
+		}
+		if !utf8.ValidString(line) {
+			t.Errorf("line splits a character: %q", line)
+		}
+		if strings.HasPrefix(line, " ") {
+			folded++
+		}
+	}
+	if folded == 0 {
+		t.Error("no line was folded")
+	}
+
+	unfolded := strings.ReplaceAll(ics, "\r\n ", "")
+	if !strings.Contains(unfolded, "SUMMARY:Eagle lands") || !strings.Contains(unfolded, "DESCRIPTION:"+testEvents[3].CardSubtitle.String+"\r\n") {
+		t.Error("unfolding doesn't give back the subtitle")
+	}
+}
+
+func TestSpreadsheetSafe(t *testing.T) {
+	tests := map[string]string{
+		"=1+1":         "'=1+1",
+		"+44 20":       "'+44 20",
+		"-1":           "'-1",
+		"@SUM(A1)":     "'@SUM(A1)",
+		"\t=1":         "'\t=1",
+		"Moon landing": "Moon landing",
+		"1+1=2":        "1+1=2",
+		"'quoted":      "''quoted",
+		"":             "",
+	}
+	for in, want := range tests {
+		if got := SpreadsheetSafe(in); got != want {
+			t.Errorf("SpreadsheetSafe(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
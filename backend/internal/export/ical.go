@@ -0,0 +1,127 @@
+package export
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/nabsk911/chronify/internal/db"
+)
+
+const icsDateTime = "20060102T150405Z"
+
+// ICS writes an RFC 5545 calendar with a VEVENT for each dated event.
+// Events without dates, or dated outside the years 1 to 9999 that
+// iCalendar can express, are left out.
+func ICS(w io.Writer, timeline db.Timeline, events []Event) error {
+	bw := bufio.NewWriter(w)
+	stamp := time.Now().UTC().Format(icsDateTime)
+
+	writeICSLine(bw, "BEGIN:VCALENDAR")
+	writeICSLine(bw, "VERSION:2.0")
+	writeICSLine(bw, "PRODID:-//Chronify//Timeline Export//EN")
+	writeICSLine(bw, "CALSCALE:GREGORIAN")
+	writeICSLine(bw, "X-WR-CALNAME:"+escapeICSText(timeline.Title))
+
+	for _, e := range events {
+		start, end, ok := icsDates(e.Event)
+		if !ok {
+			continue
+		}
+
+		writeICSLine(bw, "BEGIN:VEVENT")
+		writeICSLine(bw, "UID:"+e.ID.String()+"@chronify")
+		writeICSLine(bw, "DTSTAMP:"+stamp)
+		writeICSLine(bw, "DTSTART"+start)
+		if end != "" {
+			writeICSLine(bw, "DTEND"+end)
+		}
+		writeICSLine(bw, "SUMMARY:"+escapeICSText(e.CardTitle))
+
+		description := strings.TrimSpace(text(e.CardSubtitle) + "\n\n" + text(e.CardDetailedText))
+		if description != "" {
+			writeICSLine(bw, "DESCRIPTION:"+escapeICSText(description))
+		}
+		writeICSLine(bw, "END:VEVENT")
+	}
+
+	writeICSLine(bw, "END:VCALENDAR")
+	return bw.Flush()
+}
+
+// icsDates returns the DTSTART and DTEND values, including their parameters.
+// Whole dates get an exclusive DTEND one year, month or day past the last
+// one covered, so a year-precision event covers the whole year.
+func icsDates(e db.Event) (string, string, bool) {
+	start, ok := startDate(e)
+	if !ok {
+		return "", "", false
+	}
+	last, hasEnd := endDate(e)
+	if !hasEnd {
+		last = start
+	}
+	if start.year < 1 || last.year > 9999 {
+		return "", "", false
+	}
+
+	if start.precision == "minute" {
+		end := ""
+		if hasEnd {
+			end = ":" + last.time().Format(icsDateTime)
+		}
+		return ":" + start.time().Format(icsDateTime), end, true
+	}
+
+	var end time.Time
+	switch start.precision {
+	case "year":
+		end = last.time().AddDate(1, 0, 0)
+	case "month":
+		end = last.time().AddDate(0, 1, 0)
+	default:
+		end = last.time().AddDate(0, 0, 1)
+	}
+	if end.Year() > 9999 {
+		return "", "", false
+	}
+	return ";VALUE=DATE:" + start.time().Format("20060102"), ";VALUE=DATE:" + end.Format("20060102"), true
+}
+
+// time returns the start of the date, treating missing components as the
+// first month, day or minute.
+func (d date) time() time.Time {
+	month, day := time.Month(max(d.month, 1)), int(max(d.day, 1))
+	return time.Date(int(d.year), month, day, 0, int(d.minuteOfDay), 0, 0, time.UTC)
+}
+
+func escapeICSText(s string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+		"\r", "",
+	).Replace(s)
+}
+
+// writeICSLine folds lines longer than 75 octets as RFC 5545 requires,
+// without splitting a UTF-8 sequence, and ends them with CRLF.
+func writeICSLine(w *bufio.Writer, line string) {
+	limit := 75
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		w.WriteString(line[:cut])
+		w.WriteString("\r\n ")
+		line = line[cut:]
+		limit = 74
+	}
+	w.WriteString(line)
+	w.WriteString("\r\n")
+}
@@ -0,0 +1,122 @@
+package export
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/nabsk911/chronify/internal/db"
+)
+
+// Version is bumped whenever the JSON document changes shape. Version 2 added
+// the events' tags.
+const Version = 2
+
+type document struct {
+	Version    int          `json:"version"`
+	ExportedAt time.Time    `json:"exported_at"`
+	Timeline   timelineJSON `json:"timeline"`
+}
+
+// timelineJSON is the timeline as its members see it, without its owner or
+// the columns only the database needs.
+type timelineJSON struct {
+	ID          pgtype.UUID        `json:"id"`
+	Title       string             `json:"title"`
+	Description pgtype.Text        `json:"description"`
+	CreatedAt   pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt   pgtype.Timestamptz `json:"updated_at"`
+}
+
+// eventJSON is everything about an event that can be seen or edited.
+type eventJSON struct {
+	ID               pgtype.UUID        `json:"id"`
+	Title            string             `json:"title"`
+	CardTitle        string             `json:"card_title"`
+	CardSubtitle     pgtype.Text        `json:"card_subtitle"`
+	CardDetailedText pgtype.Text        `json:"card_detailed_text"`
+	StartYear        pgtype.Int8        `json:"start_year"`
+	StartMonth       pgtype.Int2        `json:"start_month"`
+	StartDay         pgtype.Int2        `json:"start_day"`
+	StartMinuteOfDay pgtype.Int2        `json:"start_minute_of_day"`
+	EndYear          pgtype.Int8        `json:"end_year"`
+	EndMonth         pgtype.Int2        `json:"end_month"`
+	EndDay           pgtype.Int2        `json:"end_day"`
+	EndMinuteOfDay   pgtype.Int2        `json:"end_minute_of_day"`
+	DatePrecision    pgtype.Text        `json:"date_precision"`
+	Position         string             `json:"position"`
+	CreatedAt        pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt        pgtype.Timestamptz `json:"updated_at"`
+	Tags             []Tag              `json:"tags"`
+}
+
+// JSON writes a lossless document holding the timeline and everything its
+// members can see of its events, including their tags. The events are
+// written one at a time, so large timelines don't have to be encoded in
+// memory.
+func JSON(w io.Writer, timeline db.Timeline, events []Event) error {
+	header, err := json.Marshal(document{
+		Version:    Version,
+		ExportedAt: time.Now().UTC(),
+		Timeline: timelineJSON{
+			ID:          timeline.ID,
+			Title:       timeline.Title,
+			Description: timeline.Description,
+			CreatedAt:   timeline.CreatedAt,
+			UpdatedAt:   timeline.UpdatedAt,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	// Reopen the header object to append the events to it.
+	if _, err := w.Write(header[:len(header)-1]); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, `,"events":[`); err != nil {
+		return err
+	}
+
+	for i, event := range events {
+		if i > 0 {
+			if _, err := io.WriteString(w, ","); err != nil {
+				return err
+			}
+		}
+		tags := event.Tags
+		if tags == nil {
+			tags = []Tag{}
+		}
+		b, err := json.Marshal(eventJSON{
+			ID:               event.ID,
+			Title:            event.Title,
+			CardTitle:        event.CardTitle,
+			CardSubtitle:     event.CardSubtitle,
+			CardDetailedText: event.CardDetailedText,
+			StartYear:        event.StartYear,
+			StartMonth:       event.StartMonth,
+			StartDay:         event.StartDay,
+			StartMinuteOfDay: event.StartMinuteOfDay,
+			EndYear:          event.EndYear,
+			EndMonth:         event.EndMonth,
+			EndDay:           event.EndDay,
+			EndMinuteOfDay:   event.EndMinuteOfDay,
+			DatePrecision:    event.DatePrecision,
+			Position:         event.Position,
+			CreatedAt:        event.CreatedAt,
+			UpdatedAt:        event.UpdatedAt,
+			Tags:             tags,
+		})
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(b); err != nil {
+			return err
+		}
+	}
+
+	_, err = io.WriteString(w, "]}\n")
+	return err
+}
@@ -0,0 +1,45 @@
+package export
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/nabsk911/chronify/internal/db"
+)
+
+// Markdown writes the timeline as a document with a heading per event.
+func Markdown(w io.Writer, timeline db.Timeline, events []Event) error {
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintf(bw, "# %s\n", oneLine(timeline.Title))
+	if description := text(timeline.Description); description != "" {
+		fmt.Fprintf(bw, "\n%s\n", description)
+	}
+
+	for _, e := range events {
+		fmt.Fprintf(bw, "\n## %s\n\n", oneLine(e.CardTitle))
+
+		meta := oneLine(e.Title)
+		if dates := dateRange(e.Event); dates != "" {
+			meta += " (" + dates + ")"
+		}
+		fmt.Fprintf(bw, "_%s_\n", meta)
+
+		if subtitle := text(e.CardSubtitle); subtitle != "" {
+			fmt.Fprintf(bw, "\n**%s**\n", oneLine(subtitle))
+		}
+		if detail := text(e.CardDetailedText); detail != "" {
+			fmt.Fprintf(bw, "\n%s\n", detail)
+		}
+	}
+
+	return bw.Flush()
+}
+
+// oneLine keeps a value from breaking out of the heading or emphasis it is
+// written in.
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,152 @@
+// Package fractional generates fractional index keys: strings that sort
+// bytewise and can always be split, so moving an item between two others only
+// rewrites the moved item.
+package fractional
+
+import (
+	"errors"
+	"strings"
+)
+
+const digits = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+var (
+	ErrInvalidKey   = errors.New("fractional: invalid key")
+	ErrInvalidRange = errors.New("fractional: lower key must sort before upper key")
+)
+
+// KeyBetween returns a key that sorts strictly between a and b. An empty a
+// means the start of the list and an empty b means the end.
+//
+// Adding to either end steps to the next digit instead of halving the open
+// range, so a long run of appends or prepends only lengthens the keys by one
+// digit every 60 or so keys.
+func KeyBetween(a, b string) (string, error) {
+	if !isValid(a) || !isValid(b) {
+		return "", ErrInvalidKey
+	}
+	if b != "" && a >= b {
+		return "", ErrInvalidRange
+	}
+	switch {
+	case a != "" && b == "":
+		return after(a), nil
+	case a == "" && b != "":
+		return before(b), nil
+	}
+	return midpoint(a, b), nil
+}
+
+// NKeysBetween returns n ascending keys that all sort strictly between a and
+// b, splitting the range evenly so the keys stay short.
+func NKeysBetween(a, b string, n int) ([]string, error) {
+	if n <= 0 {
+		return nil, nil
+	}
+	if b == "" {
+		// Bound the open end so the range can be bisected. A run of 'z'
+		// longer than a always sorts after it.
+		b = strings.Repeat(digits[len(digits)-1:], len(a)+2)
+	}
+	if !isValid(a) || !isValid(b) {
+		return nil, ErrInvalidKey
+	}
+	if a >= b {
+		return nil, ErrInvalidRange
+	}
+	return nKeysBetween(a, b, n), nil
+}
+
+func nKeysBetween(a, b string, n int) []string {
+	if n == 0 {
+		return nil
+	}
+	mid := midpoint(a, b)
+	keys := nKeysBetween(a, mid, n/2)
+	keys = append(keys, mid)
+	return append(keys, nKeysBetween(mid, b, n-n/2-1)...)
+}
+
+func isValid(key string) bool {
+	if strings.HasSuffix(key, digits[:1]) {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		if strings.IndexByte(digits, key[i]) < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// midpoint expects a < b (an empty b sorts last) and neither to end in the
+// zero digit.
+func midpoint(a, b string) string {
+	if b != "" {
+		n := 0
+		for n < len(b) && digitAt(a, n) == b[n] {
+			n++
+		}
+		if n > 0 {
+			return b[:n] + midpoint(suffix(a, n), b[n:])
+		}
+	}
+
+	digitA := 0
+	if a != "" {
+		digitA = strings.IndexByte(digits, a[0])
+	}
+	digitB := len(digits)
+	if b != "" {
+		digitB = strings.IndexByte(digits, b[0])
+	}
+
+	if digitB-digitA > 1 {
+		return digits[(digitA+digitB+1)/2 : (digitA+digitB+1)/2+1]
+	}
+	if len(b) > 1 {
+		return b[:1]
+	}
+	return digits[digitA:digitA+1] + midpoint(suffix(a, 1), "")
+}
+
+// after returns a short key that sorts after a, which may be empty.
+func after(a string) string {
+	if a == "" {
+		return digits[1:2]
+	}
+	d := strings.IndexByte(digits, a[0])
+	if d < len(digits)-1 {
+		return digits[d+1 : d+2]
+	}
+	return a[:1] + after(a[1:])
+}
+
+// before returns a short key that sorts before b, which must be valid and
+// not empty.
+func before(b string) string {
+	d := strings.IndexByte(digits, b[0])
+	switch {
+	case d > 1:
+		return digits[d-1 : d]
+	case d == 1 && len(b) > 1:
+		return b[:1]
+	case d == 1:
+		return digits[:1] + digits[len(digits)-1:]
+	}
+	return b[:1] + before(b[1:])
+}
+
+func digitAt(key string, i int) byte {
+	if i < len(key) {
+		return key[i]
+	}
+	return digits[0]
+}
+
+func suffix(key string, i int) string {
+	if i < len(key) {
+		return key[i:]
+	}
+	return ""
+}
@@ -0,0 +1,166 @@
+package fractional
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestKeyBetween(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+	}{
+		{"empty list", "", ""},
+		{"before first", "", "V"},
+		{"after last", "V", ""},
+		{"before the smallest digit", "", "1"},
+		{"after the largest digit", "z", ""},
+		{"wide gap", "1", "z"},
+		{"adjacent digits", "a", "b"},
+		{"key and its extension", "a", "a1"},
+		{"shared prefix", "aaa", "aab"},
+		{"long and short", "a1z", "a2"},
+		{"adjacent after carry", "Vzz", "W"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := KeyBetween(tt.a, tt.b)
+			if err != nil {
+				t.Fatalf("KeyBetween(%q, %q): %v", tt.a, tt.b, err)
+			}
+			if !isValid(key) || key == "" {
+				t.Fatalf("KeyBetween(%q, %q) = %q, not a valid key", tt.a, tt.b, key)
+			}
+			if key <= tt.a || (tt.b != "" && key >= tt.b) {
+				t.Fatalf("KeyBetween(%q, %q) = %q, not between them", tt.a, tt.b, key)
+			}
+		})
+	}
+}
+
+func TestKeyBetweenErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want error
+	}{
+		{"equal keys", "a", "a", ErrInvalidRange},
+		{"reversed keys", "b", "a", ErrInvalidRange},
+		{"trailing zero", "a0", "", ErrInvalidKey},
+		{"trailing zero upper", "", "a0", ErrInvalidKey},
+		{"bad digit", "a-", "", ErrInvalidKey},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := KeyBetween(tt.a, tt.b); !errors.Is(err, tt.want) {
+				t.Fatalf("KeyBetween(%q, %q) error = %v, want %v", tt.a, tt.b, err, tt.want)
+			}
+		})
+	}
+}
+
+// checkRun checks that keys is a strictly ascending run of valid keys no
+// longer than maxLen.
+func checkRun(t *testing.T, keys []string, maxLen int) {
+	t.Helper()
+	for i, key := range keys {
+		if !isValid(key) || key == "" {
+			t.Fatalf("key %d = %q, not a valid key", i, key)
+		}
+		if len(key) > maxLen {
+			t.Fatalf("key %d = %q, longer than %d", i, key, maxLen)
+		}
+		if i > 0 && keys[i-1] >= key {
+			t.Fatalf("keys %d and %d = %q, %q, not ascending", i-1, i, keys[i-1], key)
+		}
+	}
+}
+
+func TestKeyBetweenAppends(t *testing.T) {
+	keys := []string{}
+	last := ""
+	for range 1000 {
+		key, err := KeyBetween(last, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys = append(keys, key)
+		last = key
+	}
+	checkRun(t, keys, 20)
+}
+
+func TestKeyBetweenPrepends(t *testing.T) {
+	keys := []string{}
+	first := ""
+	for range 1000 {
+		key, err := KeyBetween("", first)
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys = append([]string{key}, keys...)
+		first = key
+	}
+	checkRun(t, keys, 20)
+}
+
+func TestKeyBetweenRepeatedSplits(t *testing.T) {
+	// Always inserting right after the same key is the worst case: each key
+	// lands between the last one and its neighbour.
+	a, b := "a", "b"
+	keys := []string{a}
+	for range 200 {
+		key, err := KeyBetween(a, b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys = append(keys, key)
+		a = key
+	}
+	keys = append(keys, b)
+	checkRun(t, keys, 60)
+}
+
+func TestNKeysBetween(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		n    int
+	}{
+		{"empty list", "", "", 100},
+		{"after last", "V", "", 50},
+		{"before first", "", "V", 50},
+		{"adjacent digits", "a", "b", 100},
+		{"one key", "a", "c", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys, err := NKeysBetween(tt.a, tt.b, tt.n)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(keys) != tt.n {
+				t.Fatalf("got %d keys, want %d", len(keys), tt.n)
+			}
+			run := append([]string{}, keys...)
+			if tt.a != "" {
+				run = append([]string{tt.a}, run...)
+			}
+			if tt.b != "" {
+				run = append(run, tt.b)
+			}
+			checkRun(t, run, 10)
+		})
+	}
+
+	if keys, err := NKeysBetween("a", "b", 0); err != nil || keys != nil {
+		t.Fatalf("NKeysBetween(n=0) = %q, %v, want no keys", keys, err)
+	}
+	if _, err := NKeysBetween("b", "a", 1); !errors.Is(err, ErrInvalidRange) {
+		t.Fatalf("NKeysBetween of a reversed range error = %v, want %v", err, ErrInvalidRange)
+	}
+	if keys, err := NKeysBetween(strings.Repeat("z", 5), "", 3); err != nil || len(keys) != 3 {
+		t.Fatalf("NKeysBetween after a run of z = %q, %v", keys, err)
+	}
+}
@@ -0,0 +1,275 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/nabsk911/chronify/internal/auth"
+	"github.com/nabsk911/chronify/internal/db"
+	"github.com/nabsk911/chronify/internal/mail"
+	"github.com/nabsk911/chronify/internal/utils"
+)
+
+type emailRequest struct {
+	Email string `json:"email"`
+}
+
+type userTokenRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password,omitempty"`
+}
+
+// sendUserToken expires the user's earlier tokens for the purpose, then
+// emails them a link to path carrying a fresh one.
+func (uh *UserHandler) sendUserToken(ctx context.Context, user db.User, purpose string, ttl time.Duration, path string, subject string, body string) error {
+	token, tokenHash, err := auth.GenerateUserToken()
+	if err != nil {
+		return err
+	}
+
+	err = uh.userStore.ExpireUserTokens(ctx, db.ExpireUserTokensParams{
+		UserID:  user.ID,
+		Purpose: purpose,
+	})
+	if err != nil {
+		return err
+	}
+
+	err = uh.userStore.CreateUserToken(ctx, db.CreateUserTokenParams{
+		UserID:    user.ID,
+		Purpose:   purpose,
+		TokenHash: tokenHash,
+		ExpiresAt: pgtype.Timestamptz{Time: time.Now().Add(ttl), Valid: true},
+	})
+	if err != nil {
+		return err
+	}
+
+	link := uh.appURL + path + "?token=" + url.QueryEscape(token)
+	return uh.mailer.Send(ctx, mail.Message{
+		To:      user.Email,
+		Subject: subject,
+		Body:    fmt.Sprintf(body, user.Username, link),
+	})
+}
+
+func (uh *UserHandler) sendVerificationEmail(ctx context.Context, user db.User) error {
+	return uh.sendUserToken(ctx, user, auth.PurposeVerifyEmail, auth.EmailVerificationTTL, "/verify-email",
+		"Verify your Chronify email",
+		"Hi %s,\n\nConfirm your email address by opening this link:\n\n%s\n\nThe link expires in 24 hours. If you didn't sign up for Chronify, you can ignore this email.\n")
+}
+
+func (uh *UserHandler) sendPasswordResetEmail(ctx context.Context, user db.User) error {
+	return uh.sendUserToken(ctx, user, auth.PurposeResetPassword, auth.PasswordResetTTL, "/reset-password",
+		"Reset your Chronify password",
+		"Hi %s,\n\nChoose a new password by opening this link:\n\n%s\n\nThe link expires in 1 hour. If you didn't ask to reset your password, you can ignore this email.\n")
+}
+
+// Verify email
+func (uh *UserHandler) HandleVerifyEmail(w http.ResponseWriter, r *http.Request) {
+	var req userTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		uh.logger.Printf("Failed to decode verify email request: %v", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Invalid request payload!"})
+		return
+	}
+
+	if req.Token == "" {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Token is required"})
+		return
+	}
+
+	tx, err := uh.dbConn.Begin(r.Context())
+	if err != nil {
+		uh.logger.Printf("Failed to begin transaction: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Internal server error!"})
+		return
+	}
+	defer tx.Rollback(r.Context())
+	qtx := uh.userStore.WithTx(tx)
+
+	userID, err := qtx.UseUserToken(r.Context(), db.UseUserTokenParams{
+		TokenHash: auth.HashUserToken(req.Token),
+		Purpose:   auth.PurposeVerifyEmail,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Invalid or expired token"})
+			return
+		}
+		uh.logger.Printf("Failed to use verification token: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Internal server error!"})
+		return
+	}
+
+	if err := qtx.MarkEmailVerified(r.Context(), userID); err != nil {
+		uh.logger.Printf("Failed to verify email for user %s: %v", userID.String(), err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Internal server error!"})
+		return
+	}
+
+	if err := tx.Commit(r.Context()); err != nil {
+		uh.logger.Printf("Failed to commit transaction: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Internal server error!"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"message": "Email verified successfully!"})
+}
+
+// HandleResendVerification answers the same whether or not the address has
+// an account, so it can't be used to find out who has signed up.
+func (uh *UserHandler) HandleResendVerification(w http.ResponseWriter, r *http.Request) {
+	var req emailRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		uh.logger.Printf("Failed to decode resend verification request: %v", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Invalid request payload!"})
+		return
+	}
+
+	if !utils.IsValidEmail(req.Email) {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Invalid email format"})
+		return
+	}
+
+	user, err := uh.userStore.GetUserByEmail(r.Context(), req.Email)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		uh.logger.Printf("Failed to retrieve user %s: %v", req.Email, err)
+	}
+	if err == nil && !user.EmailVerifiedAt.Valid {
+		if err := uh.sendVerificationEmail(r.Context(), user); err != nil {
+			uh.logger.Printf("Failed to send verification email to user %s: %v", user.ID.String(), err)
+		}
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"message": "If that account needs verifying, we've sent a new link"})
+}
+
+// HandleForgotPassword answers the same whether or not the address has an
+// account, so it can't be used to find out who has signed up.
+func (uh *UserHandler) HandleForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var req emailRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		uh.logger.Printf("Failed to decode forgot password request: %v", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Invalid request payload!"})
+		return
+	}
+
+	if !utils.IsValidEmail(req.Email) {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Invalid email format"})
+		return
+	}
+
+	user, err := uh.userStore.GetUserByEmail(r.Context(), req.Email)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		uh.logger.Printf("Failed to retrieve user %s: %v", req.Email, err)
+	}
+	if err == nil {
+		if err := uh.sendPasswordResetEmail(r.Context(), user); err != nil {
+			uh.logger.Printf("Failed to send password reset email to user %s: %v", user.ID.String(), err)
+		}
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"message": "If that email has an account, we've sent a link to reset its password"})
+}
+
+// HandleResetPassword sets a new password and signs the user out everywhere.
+// Following the emailed link also proves the address, so it counts as
+// verified from then on.
+func (uh *UserHandler) HandleResetPassword(w http.ResponseWriter, r *http.Request) {
+	var req userTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		uh.logger.Printf("Failed to decode reset password request: %v", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Invalid request payload!"})
+		return
+	}
+
+	if req.Token == "" || req.Password == "" {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Token and password are required"})
+		return
+	}
+
+	if len(req.Password) < 8 {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Password must be at least 8 characters"})
+		return
+	}
+
+	passwordHash, err := auth.SetPasswordHash(req.Password)
+	if err != nil {
+		uh.logger.Printf("Failed to hash password: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Internal server error!"})
+		return
+	}
+
+	tx, err := uh.dbConn.Begin(r.Context())
+	if err != nil {
+		uh.logger.Printf("Failed to begin transaction: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Internal server error!"})
+		return
+	}
+	defer tx.Rollback(r.Context())
+	qtx := uh.userStore.WithTx(tx)
+
+	userID, err := qtx.UseUserToken(r.Context(), db.UseUserTokenParams{
+		TokenHash: auth.HashUserToken(req.Token),
+		Purpose:   auth.PurposeResetPassword,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Invalid or expired token"})
+			return
+		}
+		uh.logger.Printf("Failed to use password reset token: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Internal server error!"})
+		return
+	}
+
+	err = qtx.UpdateUserPassword(r.Context(), db.UpdateUserPasswordParams{
+		ID:           userID,
+		PasswordHash: passwordHash,
+	})
+	if err != nil {
+		uh.logger.Printf("Failed to update password for user %s: %v", userID.String(), err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Internal server error!"})
+		return
+	}
+
+	// Any other reset links still out there stop working, and so does every
+	// session started with the old password.
+	err = qtx.ExpireUserTokens(r.Context(), db.ExpireUserTokensParams{
+		UserID:  userID,
+		Purpose: auth.PurposeResetPassword,
+	})
+	if err != nil {
+		uh.logger.Printf("Failed to expire password reset tokens for user %s: %v", userID.String(), err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Internal server error!"})
+		return
+	}
+
+	if err := qtx.RevokeUserSessions(r.Context(), userID); err != nil {
+		uh.logger.Printf("Failed to revoke sessions for user %s: %v", userID.String(), err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Internal server error!"})
+		return
+	}
+
+	if err := qtx.MarkEmailVerified(r.Context(), userID); err != nil {
+		uh.logger.Printf("Failed to verify email for user %s: %v", userID.String(), err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Internal server error!"})
+		return
+	}
+
+	if err := tx.Commit(r.Context()); err != nil {
+		uh.logger.Printf("Failed to commit transaction: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Internal server error!"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"message": "Password reset successfully!"})
+}
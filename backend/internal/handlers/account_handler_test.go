@@ -0,0 +1,256 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/nabsk911/chronify/internal/auth"
+	"github.com/nabsk911/chronify/internal/db"
+	"github.com/nabsk911/chronify/internal/mail"
+)
+
+const testAppURL = "https://chronify.example"
+
+// fakeAccounts is a database holding the users, user_tokens and sessions
+// tables.
+type fakeAccounts struct {
+	*fakeSessions
+
+	users  []db.User
+	tokens []db.UserToken
+}
+
+func newFakeAccounts() *fakeAccounts {
+	f := &fakeAccounts{fakeSessions: newFakeSessions()}
+	f.On("CreateUser", func(args []any) (any, error) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		u := db.User{
+			ID:           pgtype.UUID{Bytes: [16]byte{0: 0xa1, 15: byte(len(f.users))}, Valid: true},
+			Email:        args[0].(string),
+			Username:     args[1].(string),
+			PasswordHash: args[2].(string),
+		}
+		f.users = append(f.users, u)
+		return db.CreateUserRow{ID: u.ID, Email: u.Email, Username: u.Username}, nil
+	})
+	f.On("GetUserByEmail", func(args []any) (any, error) {
+		if u := f.user(func(u db.User) bool { return u.Email == args[0] }); u != nil {
+			return *u, nil
+		}
+		return nil, nil
+	})
+	f.On("UpdateUserPassword", func(args []any) (any, error) {
+		f.user(func(u db.User) bool { return u.ID == args[0] }).PasswordHash = args[1].(string)
+		return nil, nil
+	})
+	f.On("MarkEmailVerified", func(args []any) (any, error) {
+		if u := f.user(func(u db.User) bool { return u.ID == args[0] }); !u.EmailVerifiedAt.Valid {
+			u.EmailVerifiedAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+		}
+		return nil, nil
+	})
+	f.On("CreateUserToken", func(args []any) (any, error) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.tokens = append(f.tokens, db.UserToken{
+			UserID:    args[0].(pgtype.UUID),
+			Purpose:   args[1].(string),
+			TokenHash: args[2].(string),
+			ExpiresAt: args[3].(pgtype.Timestamptz),
+		})
+		return nil, nil
+	})
+	f.On("ExpireUserTokens", func(args []any) (any, error) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		for i, t := range f.tokens {
+			if t.UserID == args[0] && t.Purpose == args[1] && !t.UsedAt.Valid {
+				f.tokens[i].UsedAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+			}
+		}
+		return nil, nil
+	})
+	f.On("UseUserToken", func(args []any) (any, error) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		for i, t := range f.tokens {
+			if t.TokenHash == args[0] && t.Purpose == args[1] && !t.UsedAt.Valid && t.ExpiresAt.Time.After(time.Now()) {
+				f.tokens[i].UsedAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+				return t.UserID, nil
+			}
+		}
+		return nil, nil
+	})
+	return f
+}
+
+// user returns the first user that matches, to be read or changed in place.
+func (f *fakeAccounts) user(match func(db.User) bool) *db.User {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i := range f.users {
+		if match(f.users[i]) {
+			return &f.users[i]
+		}
+	}
+	return nil
+}
+
+// token returns the stored token, to be read or changed in place.
+func (f *fakeAccounts) token(token string) *db.UserToken {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i := range f.tokens {
+		if f.tokens[i].TokenHash == auth.HashUserToken(token) {
+			return &f.tokens[i]
+		}
+	}
+	return nil
+}
+
+// newAccountHandler returns a handler that mails through the file driver,
+// and the file it writes to.
+func newAccountHandler(t *testing.T, fake *fakeAccounts) (*UserHandler, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "mail.txt")
+	mailer, err := mail.NewFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewUserHandler(db.New(fake), fake, newTestKeySet(t), mailer, testAppURL, false, nil, testLogger), path
+}
+
+var mailedLinkPattern = regexp.MustCompile(`(?m)^To: (\S+)$|` + regexp.QuoteMeta(testAppURL) + `(/\S+)\?token=(\S+)`)
+
+// lastMailedToken returns the token in the latest link to path in the mail
+// file, checking it was sent to email.
+func lastMailedToken(t *testing.T, mailFile, email, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(mailFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var to, token string
+	for _, m := range mailedLinkPattern.FindAllStringSubmatch(string(data), -1) {
+		switch {
+		case m[1] != "":
+			to = m[1]
+		case m[2] == path:
+			if to != email {
+				t.Errorf("link to %s mailed to %s, want %s", path, to, email)
+			}
+			token, err = url.QueryUnescape(m[3])
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if token == "" {
+		t.Fatalf("no link to %s in the mail:\n%s", path, data)
+	}
+	return token
+}
+
+func post(handle http.HandlerFunc, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	handle(w, httptest.NewRequest("POST", "/", strings.NewReader(body)))
+	return w
+}
+
+func TestRegisterAndVerifyEmail(t *testing.T) {
+	fake := newFakeAccounts()
+	uh, mailFile := newAccountHandler(t, fake)
+
+	if w := post(uh.HandleRegister, `{"email":"ada@example.com","username":"ada","password":"analytical"}`); w.Code != http.StatusCreated {
+		t.Fatalf("register = %d, want 201: %s", w.Code, w.Body)
+	}
+	token := lastMailedToken(t, mailFile, "ada@example.com", "/verify-email")
+	if ttl := time.Until(fake.token(token).ExpiresAt.Time); ttl > auth.EmailVerificationTTL || ttl < auth.EmailVerificationTTL-time.Minute {
+		t.Errorf("the link expires in %v, want %v", ttl, auth.EmailVerificationTTL)
+	}
+
+	body := `{"token":"` + token + `"}`
+	if w := post(uh.HandleVerifyEmail, body); w.Code != http.StatusOK {
+		t.Fatalf("verify = %d, want 200: %s", w.Code, w.Body)
+	}
+	if !fake.users[0].EmailVerifiedAt.Valid {
+		t.Error("the email wasn't verified")
+	}
+	if w := post(uh.HandleVerifyEmail, body); w.Code != http.StatusBadRequest {
+		t.Errorf("verifying with the link again = %d, want 400", w.Code)
+	}
+}
+
+func TestForgotAndResetPassword(t *testing.T) {
+	fake := newFakeAccounts()
+	uh, mailFile := newAccountHandler(t, fake)
+	post(uh.HandleRegister, `{"email":"ada@example.com","username":"ada","password":"analytical"}`)
+	fake.login(t, uh, fake.users[0].ID)
+
+	forgot := func() string {
+		t.Helper()
+		if w := post(uh.HandleForgotPassword, `{"email":"ada@example.com"}`); w.Code != http.StatusOK {
+			t.Fatalf("forgot password = %d, want 200: %s", w.Code, w.Body)
+		}
+		return lastMailedToken(t, mailFile, "ada@example.com", "/reset-password")
+	}
+	reset := func(token, password string) int {
+		return post(uh.HandleResetPassword, `{"token":"`+token+`","password":"`+password+`"}`).Code
+	}
+
+	first := forgot()
+	latest := forgot()
+	if ttl := time.Until(fake.token(latest).ExpiresAt.Time); ttl > auth.PasswordResetTTL || ttl < auth.PasswordResetTTL-time.Minute {
+		t.Errorf("the link expires in %v, want %v", ttl, auth.PasswordResetTTL)
+	}
+	if code := reset(first, "difference"); code != http.StatusBadRequest {
+		t.Errorf("resetting with an earlier link = %d, want 400: only the latest works", code)
+	}
+	verifyToken := lastMailedToken(t, mailFile, "ada@example.com", "/verify-email")
+	if code := reset(verifyToken, "difference"); code != http.StatusBadRequest {
+		t.Errorf("resetting with the verification link = %d, want 400", code)
+	}
+
+	if code := reset(latest, "difference"); code != http.StatusOK {
+		t.Fatalf("reset = %d, want 200", code)
+	}
+	if ok, _ := auth.CheckPasswordHash("difference", fake.users[0].PasswordHash); !ok {
+		t.Error("the password wasn't changed")
+	}
+	if !fake.users[0].EmailVerifiedAt.Valid {
+		t.Error("following the link didn't verify the email")
+	}
+	if len(fake.active()) != 0 {
+		t.Error("a session started before the reset survived it")
+	}
+	if code := reset(latest, "third-one"); code != http.StatusBadRequest {
+		t.Errorf("resetting with the link again = %d, want 400", code)
+	}
+}
+
+func TestResetPasswordLinkExpires(t *testing.T) {
+	fake := newFakeAccounts()
+	uh, mailFile := newAccountHandler(t, fake)
+	post(uh.HandleRegister, `{"email":"ada@example.com","username":"ada","password":"analytical"}`)
+	post(uh.HandleForgotPassword, `{"email":"ada@example.com"}`)
+	token := lastMailedToken(t, mailFile, "ada@example.com", "/reset-password")
+
+	// An hour passes.
+	fake.token(token).ExpiresAt.Time = time.Now().Add(-time.Second)
+
+	if w := post(uh.HandleResetPassword, `{"token":"`+token+`","password":"difference"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("reset with an expired link = %d, want 400", w.Code)
+	}
+	if ok, _ := auth.CheckPasswordHash("analytical", fake.users[0].PasswordHash); !ok {
+		t.Error("the password was changed")
+	}
+}
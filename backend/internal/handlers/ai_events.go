@@ -1,20 +1,35 @@
-// TODO: Apply rate limiting
 package handlers
 
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
-	"os"
+	"time"
 
 	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/nabsk911/chronify/internal/ai"
+	"github.com/nabsk911/chronify/internal/auth"
 	"github.com/nabsk911/chronify/internal/db"
 	"github.com/nabsk911/chronify/internal/utils"
-	"google.golang.org/genai"
+)
+
+const (
+	aiModePreview = "preview"
+	aiModeAppend  = "append"
+	aiModeReplace = "replace"
 )
 
 type AIEventRequest struct {
 	Prompt string `json:"prompt"`
+	// Mode is preview, append (the default) or replace.
+	Mode string `json:"mode"`
+}
+
+// AIEventCommitRequest saves a previewed, possibly edited, set of events.
+type AIEventCommitRequest struct {
+	Mode   string                 `json:"mode"`
+	Events []TimelineEventRequest `json:"events"`
 }
 
 type TimelineEventRequest struct {
@@ -22,18 +37,31 @@ type TimelineEventRequest struct {
 	CardTitle        string      `json:"card_title"`
 	CardSubtitle     pgtype.Text `json:"card_subtitle"`
 	CardDetailedText pgtype.Text `json:"card_detailed_text"`
+	eventDates
 }
 
-func (eh *EventHandler) HandleCreateAIEvents(w http.ResponseWriter, r *http.Request) {
+// aiStreamEvent is an "event" message of the stream: a generated event, with
+// its ID once it has been saved.
+type aiStreamEvent struct {
+	ID *pgtype.UUID `json:"id,omitempty"`
+	TimelineEventRequest
+}
 
-	apiKey := os.Getenv("GEMINI_API_KEY")
+type AIEventStreamRequest struct {
+	Prompt string `json:"prompt"`
+	// PersistIncrementally saves each event as soon as it is generated, so
+	// the events streamed before a client cancels are kept. Otherwise the
+	// events are saved together once generation has finished.
+	PersistIncrementally bool `json:"persist_incrementally"`
+}
 
-	timelineID, err := utils.ReadIDParam(r, "timelineId")
-	if err != nil {
-		eh.logger.Printf("Invalid timeline ID: %v", err)
-		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Invalid timeline ID"})
+func (eh *EventHandler) HandleCreateAIEvents(w http.ResponseWriter, r *http.Request) {
+
+	timeline, ok := authorizeTimeline(w, r, eh.eventStore, eh.logger, roleEditor)
+	if !ok {
 		return
 	}
+	timelineID := timeline.ID
 
 	var req AIEventRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -47,95 +75,393 @@ func (eh *EventHandler) HandleCreateAIEvents(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	ctx := context.Background()
-	client, err := genai.NewClient(ctx, &genai.ClientConfig{
-		APIKey:  apiKey,
-		Backend: genai.BackendGeminiAPI,
-	})
+	if req.Mode == "" {
+		req.Mode = aiModeAppend
+	}
+	if req.Mode != aiModePreview && req.Mode != aiModeAppend && req.Mode != aiModeReplace {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Mode must be preview, append or replace"})
+		return
+	}
+	// The route only needs ai:generate, which is enough for a preview.
+	if req.Mode != aiModePreview && !utils.HasScope(r, auth.ScopeEventsWrite) {
+		utils.WriteJSON(w, http.StatusForbidden, utils.Envelope{"message": "Token is missing the " + auth.ScopeEventsWrite + " scope"})
+		return
+	}
+
+	userID, err := utils.ReadUserID(r)
 	if err != nil {
-		eh.logger.Printf("Failed to create GeminiAI client: %v", err)
-		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to create GeminiAI client"})
-		return
-	}
-
-	config := &genai.GenerateContentConfig{
-		ResponseMIMEType: "application/json",
-		ResponseSchema: &genai.Schema{
-			Type: genai.TypeArray,
-			Items: &genai.Schema{
-				Type: genai.TypeObject,
-				Properties: map[string]*genai.Schema{
-					"title": {
-						Type:        genai.TypeString,
-						Description: "The main date or time marker for the event, like 'January 2022' 'Week 1', 'Month 2-3' etc.",
-					},
-					"cardTitle": {
-						Type:        genai.TypeString,
-						Description: "A short, concise title for the timeline card.",
-					},
-					"cardSubtitle": {
-						Type:        genai.TypeString,
-						Description: "A brief, one-sentence subtitle for the event.",
-					},
-					"cardDetailedText": {
-						Type:        genai.TypeString,
-						Description: "A detailed, paragraph-length description of the event that occurred.",
-					},
-				},
-
-				PropertyOrdering: []string{"title", "cardTitle", "cardSubtitle", "cardDetailedText"},
-			},
-		},
-	}
-	response, err := client.Models.GenerateContent(
-		ctx,
-		"gemini-2.5-flash",
-		genai.Text(
-			req.Prompt,
-		),
-		config,
-	)
+		eh.logger.Printf("Invalid user ID format: %v", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Invalid user ID"})
+		return
+	}
 
+	usageID, maxOutputTokens, ok := eh.allowAIRequest(w, r, userID, timelineID, req.Prompt)
+	if !ok {
+		return
+	}
+
+	started := time.Now()
+	result, err := eh.aiProvider.GenerateEvents(r.Context(), req.Prompt, maxOutputTokens)
+	var usage ai.Usage
+	if result != nil {
+		usage = result.Usage
+	}
+	eh.recordAIUsage(r.Context(), usageID, started, usage, err)
 	if err != nil {
-		eh.logger.Printf("Failed to generate content: %v", err)
-		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to generate content"})
+		eh.logger.Printf("Failed to generate content with %s: %v", eh.aiProvider.Name(), err)
+		utils.WriteJSON(w, http.StatusBadGateway, utils.Envelope{"message": "Failed to generate content"})
 		return
 	}
 
-	var timelineEvents []TimelineEventRequest
+	timelineEvents := make([]TimelineEventRequest, len(result.Events))
+	for i, event := range result.Events {
+		timelineEvents[i] = timelineEventFromAI(event)
+	}
 
-	if err := json.Unmarshal([]byte(response.Candidates[0].Content.Parts[0].Text), &timelineEvents); err != nil {
+	if req.Mode == aiModePreview {
+		utils.WriteJSON(w, http.StatusOK, utils.Envelope{"events": timelineEvents})
+		return
+	}
+
+	eh.saveAIEvents(w, r, timelineID, userID, req.Mode, timelineEvents)
+}
+
+// HandleCommitAIEvents saves events from an earlier preview after the client
+// has reviewed them.
+func (eh *EventHandler) HandleCommitAIEvents(w http.ResponseWriter, r *http.Request) {
+
+	timeline, ok := authorizeTimeline(w, r, eh.eventStore, eh.logger, roleEditor)
+	if !ok {
+		return
+	}
+	timelineID := timeline.ID
+
+	var req AIEventCommitRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		eh.logger.Printf("Failed to decode request: %v", err)
 		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Invalid request payload"})
 		return
 	}
 
-	var createParams []db.BulkCreateEventsParams
-	for _, event := range timelineEvents {
+	if req.Mode == "" {
+		req.Mode = aiModeAppend
+	}
+	if req.Mode != aiModeAppend && req.Mode != aiModeReplace {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Mode must be append or replace"})
+		return
+	}
+
+	if len(req.Events) == 0 {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "No events provided"})
+		return
+	}
+
+	for i, event := range req.Events {
+		dates, err := event.eventDates.normalize()
+		if err != nil {
+			utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": fmt.Sprintf("Event %d: %v", i, err)})
+			return
+		}
+		req.Events[i].eventDates = dates
+	}
+
+	userID, err := utils.ReadUserID(r)
+	if err != nil {
+		eh.logger.Printf("Invalid user ID format: %v", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Invalid user ID"})
+		return
+	}
+
+	eh.saveAIEvents(w, r, timelineID, userID, req.Mode, req.Events)
+}
+
+// saveAIEvents adds the events after the timeline's existing ones, or in
+// replace mode swaps them for the existing ones, in a single transaction.
+func (eh *EventHandler) saveAIEvents(w http.ResponseWriter, r *http.Request, timelineID, userID pgtype.UUID, mode string, timelineEvents []TimelineEventRequest) {
+	ctx := r.Context()
+
+	tx, err := eh.dbConn.Begin(ctx)
+	if err != nil {
+		eh.logger.Printf("Failed to begin transaction: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to create events"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := eh.eventStore.WithTx(tx)
 
+	if err := qtx.LockTimeline(ctx, timelineID); err != nil {
+		eh.logger.Printf("Failed to lock timeline: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to create events"})
+		return
+	}
+
+	if mode == aiModeReplace {
+		// The replaced events go to the trash, so they can be recovered.
+		if err := qtx.TrashEventsByTimelineId(ctx, timelineID); err != nil {
+			eh.logger.Printf("Failed to trash events: %v", err)
+			utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to replace events"})
+			return
+		}
+	}
+
+	// Locks the timeline's events until the transaction ends.
+	current, err := qtx.GetEventPositions(ctx, timelineID)
+	if err != nil {
+		eh.logger.Printf("Failed to retrieve event positions: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to create events"})
+		return
+	}
+
+	positions, err := appendPositions(current, len(timelineEvents))
+	if err != nil {
+		eh.logger.Printf("Failed to plan event positions: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to create events"})
+		return
+	}
+
+	var createParams []db.BulkCreateEventsParams
+	for i, event := range timelineEvents {
 		createParams = append(createParams, db.BulkCreateEventsParams{
 			TimelineID:       timelineID,
 			Title:            event.Title,
 			CardTitle:        event.CardTitle,
 			CardSubtitle:     event.CardSubtitle,
 			CardDetailedText: event.CardDetailedText,
+			StartYear:        event.StartYear,
+			StartMonth:       event.StartMonth,
+			StartDay:         event.StartDay,
+			StartMinuteOfDay: event.StartMinuteOfDay,
+			EndYear:          event.EndYear,
+			EndMonth:         event.EndMonth,
+			EndDay:           event.EndDay,
+			EndMinuteOfDay:   event.EndMinuteOfDay,
+			DatePrecision:    event.DatePrecision,
+			Position:         positions[i],
 		})
+	}
 
+	if len(createParams) > 0 {
+		if _, err := qtx.BulkCreateEvents(ctx, createParams); err != nil {
+			eh.logger.Printf("Failed to create events: %v", err)
+			utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to create events"})
+			return
+		}
 	}
 
-	_, err = eh.eventStore.BulkCreateEvents(r.Context(), createParams)
-	if err != nil {
-		eh.logger.Printf("Failed to create events: %v", err)
+	if err := recordRevision(ctx, qtx, timelineID, userID, revisionAIEvents, pgtype.Int4{}); err != nil {
+		eh.logger.Printf("Failed to record revision: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to create events"})
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		eh.logger.Printf("Failed to commit transaction: %v", err)
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to create events"})
 		return
 	}
 
-	events, err := eh.eventStore.GetEventsByTimelineId(r.Context(), timelineID)
+	events, err := eh.listTaggedEvents(r, timelineID)
 	if err != nil {
 		eh.logger.Printf("Failed to retrieve events: %v", err)
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to retrieve events"})
 		return
 	}
 	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"events": events})
+}
+
+// HandleStreamAIEvents streams the generated events to the client as
+// Server-Sent Events: an "event" message for each one as it is read from the
+// model, then a "done" message with the saved events and the token usage, or
+// an "error" message.
+func (eh *EventHandler) HandleStreamAIEvents(w http.ResponseWriter, r *http.Request) {
+
+	timeline, ok := authorizeTimeline(w, r, eh.eventStore, eh.logger, roleEditor)
+	if !ok {
+		return
+	}
+	timelineID := timeline.ID
+
+	var req AIEventStreamRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		eh.logger.Printf("Failed to decode request: %v", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Invalid request payload"})
+		return
+	}
+
+	if req.Prompt == "" {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Prompt is required"})
+		return
+	}
+
+	userID, err := utils.ReadUserID(r)
+	if err != nil {
+		eh.logger.Printf("Invalid user ID format: %v", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Invalid user ID"})
+		return
+	}
 
+	usageID, maxOutputTokens, ok := eh.allowAIRequest(w, r, userID, timelineID, req.Prompt)
+	if !ok {
+		return
+	}
+
+	stream, err := utils.NewEventStream(w)
+	if err != nil {
+		eh.logger.Printf("Failed to start event stream: %v", err)
+		eh.recordAIUsage(r.Context(), usageID, time.Now(), ai.Usage{}, err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to start streaming events"})
+		return
+	}
+
+	created := []db.Event{}
+	var pending []TimelineEventRequest
+
+	started := time.Now()
+	usage, err := eh.aiProvider.StreamEvents(r.Context(), req.Prompt, maxOutputTokens, func(event ai.Event) error {
+		timelineEvent := timelineEventFromAI(event)
+		if !req.PersistIncrementally {
+			pending = append(pending, timelineEvent)
+			return stream.Send("event", aiStreamEvent{TimelineEventRequest: timelineEvent})
+		}
+
+		// Each event is saved on its own, so the events streamed before a
+		// client cancels are kept.
+		saved, err := eh.createEvents(r.Context(), timelineID, userID, []TimelineEventRequest{timelineEvent})
+		if err != nil {
+			return err
+		}
+		created = append(created, saved...)
+		return stream.Send("event", aiStreamEvent{ID: &saved[0].ID, TimelineEventRequest: timelineEvent})
+	})
+	eh.recordAIUsage(r.Context(), usageID, started, usage, err)
+	if err != nil {
+		if r.Context().Err() != nil {
+			eh.logger.Printf("AI event stream cancelled after %d events", len(created)+len(pending))
+			return
+		}
+		eh.logger.Printf("Failed to generate content with %s: %v", eh.aiProvider.Name(), err)
+		stream.Send("error", utils.Envelope{"message": "Failed to generate content"})
+		return
+	}
+
+	if len(pending) > 0 {
+		created, err = eh.createEvents(r.Context(), timelineID, userID, pending)
+		if err != nil {
+			eh.logger.Printf("Failed to create events: %v", err)
+			stream.Send("error", utils.Envelope{"message": "Failed to create events"})
+			return
+		}
+	}
+
+	stream.Send("done", utils.Envelope{"events": created, "usage": usage})
+}
+
+// createEvents adds the events after the timeline's existing ones and records
+// a revision in a single transaction. The positions are read once the
+// timeline is locked, so events saved in the meantime aren't collided with.
+func (eh *EventHandler) createEvents(ctx context.Context, timelineID, userID pgtype.UUID, timelineEvents []TimelineEventRequest) ([]db.Event, error) {
+	tx, err := eh.dbConn.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := eh.eventStore.WithTx(tx)
+	if err := qtx.LockTimeline(ctx, timelineID); err != nil {
+		return nil, err
+	}
+
+	current, err := qtx.GetEventPositions(ctx, timelineID)
+	if err != nil {
+		return nil, err
+	}
+	positions, err := appendPositions(current, len(timelineEvents))
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]db.Event, 0, len(timelineEvents))
+	for i, event := range timelineEvents {
+		saved, err := qtx.CreateEvent(ctx, createEventParams(timelineID, event, positions[i]))
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, saved)
+	}
+
+	if err := recordRevision(ctx, qtx, timelineID, userID, revisionAIEvents, pgtype.Int4{}); err != nil {
+		return nil, err
+	}
+	return events, tx.Commit(ctx)
+}
+
+func createEventParams(timelineID pgtype.UUID, event TimelineEventRequest, position string) db.CreateEventParams {
+	return db.CreateEventParams{
+		TimelineID:       timelineID,
+		Title:            event.Title,
+		CardTitle:        event.CardTitle,
+		CardSubtitle:     event.CardSubtitle,
+		CardDetailedText: event.CardDetailedText,
+		StartYear:        event.StartYear,
+		StartMonth:       event.StartMonth,
+		StartDay:         event.StartDay,
+		StartMinuteOfDay: event.StartMinuteOfDay,
+		EndYear:          event.EndYear,
+		EndMonth:         event.EndMonth,
+		EndDay:           event.EndDay,
+		EndMinuteOfDay:   event.EndMinuteOfDay,
+		DatePrecision:    event.DatePrecision,
+		Position:         position,
+	}
+}
+
+// timelineEventFromAI converts a generated event, keeping it even if the
+// model got its dates wrong.
+func timelineEventFromAI(event ai.Event) TimelineEventRequest {
+	dates, err := eventDatesFromAI(event).normalize()
+	if err != nil {
+		dates = eventDates{}
+	}
+	return TimelineEventRequest{
+		Title:            event.Title,
+		CardTitle:        event.CardTitle,
+		CardSubtitle:     pgtype.Text{String: event.CardSubtitle, Valid: true},
+		CardDetailedText: pgtype.Text{String: event.CardDetailedText, Valid: true},
+		eventDates:       dates,
+	}
+}
+
+// eventDatesFromAI converts the optional dates of a generated event into
+// their nullable column types.
+func eventDatesFromAI(event ai.Event) eventDates {
+	var dates eventDates
+	if event.StartYear != nil {
+		dates.StartYear = pgtype.Int8{Int64: *event.StartYear, Valid: true}
+	}
+	if event.StartMonth != nil {
+		dates.StartMonth = pgtype.Int2{Int16: *event.StartMonth, Valid: true}
+	}
+	if event.StartDay != nil {
+		dates.StartDay = pgtype.Int2{Int16: *event.StartDay, Valid: true}
+	}
+	if event.StartMinuteOfDay != nil {
+		dates.StartMinuteOfDay = pgtype.Int2{Int16: *event.StartMinuteOfDay, Valid: true}
+	}
+	if event.EndYear != nil {
+		dates.EndYear = pgtype.Int8{Int64: *event.EndYear, Valid: true}
+	}
+	if event.EndMonth != nil {
+		dates.EndMonth = pgtype.Int2{Int16: *event.EndMonth, Valid: true}
+	}
+	if event.EndDay != nil {
+		dates.EndDay = pgtype.Int2{Int16: *event.EndDay, Valid: true}
+	}
+	if event.EndMinuteOfDay != nil {
+		dates.EndMinuteOfDay = pgtype.Int2{Int16: *event.EndMinuteOfDay, Valid: true}
+	}
+	if event.DatePrecision != "" {
+		dates.DatePrecision = pgtype.Text{String: event.DatePrecision, Valid: true}
+	}
+	return dates
 }
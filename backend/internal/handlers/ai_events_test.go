@@ -0,0 +1,277 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/nabsk911/chronify/internal/ai"
+	"github.com/nabsk911/chronify/internal/db"
+)
+
+var testAIEvents = []ai.Event{
+	{Title: "1969", CardTitle: "Moon landing"},
+	{Title: "1989", CardTitle: "Fall of the Berlin Wall"},
+}
+
+var errTestProvider = errors.New("provider unavailable")
+
+var existingEvents = []db.Event{
+	{Title: "1914", CardTitle: "Start of the war", Position: "V"},
+	{Title: "1918", CardTitle: "End of the war", Position: "W"},
+}
+
+func newAIEventHandler(fake *fakeTimeline, provider ai.Provider) *EventHandler {
+	return NewEventHandler(db.New(fake), fake, provider, ai.Limits{}, testLogger)
+}
+
+func TestCreateAIEvents(t *testing.T) {
+	tests := []struct {
+		mode string
+		// want are the timeline's events afterwards.
+		want     []string
+		trashed  int
+		revision bool
+	}{
+		{"preview", []string{"1914", "1918"}, 0, false},
+		{"append", []string{"1914", "1918", "1969", "1989"}, 0, true},
+		{"", []string{"1914", "1918", "1969", "1989"}, 0, true},
+		{"replace", []string{"1969", "1989"}, 2, true},
+	}
+	for _, tt := range tests {
+		t.Run("mode "+tt.mode, func(t *testing.T) {
+			fake := newFakeTimeline(t, existingEvents...)
+			eh := newAIEventHandler(fake, ai.NewFake(testAIEvents))
+
+			body, _ := json.Marshal(AIEventRequest{Prompt: "The 20th century", Mode: tt.mode})
+			rec := httptest.NewRecorder()
+			eh.HandleCreateAIEvents(rec, newTimelineRequest("POST", "/timelines/x/aievents", string(body)))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+			}
+
+			var resp struct {
+				Events []struct {
+					Title     string `json:"title"`
+					CardTitle string `json:"card_title"`
+				} `json:"events"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			var returned []string
+			for _, e := range resp.Events {
+				returned = append(returned, e.Title)
+			}
+			wantReturned := tt.want
+			if tt.mode == "preview" {
+				wantReturned = []string{"1969", "1989"}
+			}
+			if !slices.Equal(returned, wantReturned) {
+				t.Errorf("returned events %q, want %q", returned, wantReturned)
+			}
+
+			if got := fake.titles(); !slices.Equal(got, tt.want) {
+				t.Errorf("timeline has events %q, want %q", got, tt.want)
+			}
+			if len(fake.trashed) != tt.trashed {
+				t.Errorf("%d events trashed, want %d", len(fake.trashed), tt.trashed)
+			}
+			if got := slices.Equal(fake.revisions, []string{revisionAIEvents}); got != tt.revision {
+				t.Errorf("revisions = %q, want a revision: %v", fake.revisions, tt.revision)
+			}
+			if got := fake.Called("SettleAIUsage"); got != 1 {
+				t.Errorf("usage recorded %d times, want once", got)
+			}
+			// One more commit reserved the tokens before generating.
+			if tt.mode != "preview" && fake.Commits() != 2 {
+				t.Errorf("%d commits, want the events saved in one transaction", fake.Commits()-1)
+			}
+		})
+	}
+}
+
+func TestCreateAIEventsRejectsBadRequests(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want int
+	}{
+		{"no prompt", `{"mode":"append"}`, http.StatusBadRequest},
+		{"unknown mode", `{"prompt":"x","mode":"merge"}`, http.StatusBadRequest},
+		{"invalid JSON", `{`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeTimeline(t, existingEvents...)
+			eh := newAIEventHandler(fake, ai.NewFake(testAIEvents))
+
+			rec := httptest.NewRecorder()
+			eh.HandleCreateAIEvents(rec, newTimelineRequest("POST", "/timelines/x/aievents", tt.body))
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+			if fake.Called("SettleAIUsage") != 0 {
+				t.Error("the model was called")
+			}
+		})
+	}
+}
+
+func TestCreateAIEventsProviderFailure(t *testing.T) {
+	fake := newFakeTimeline(t, existingEvents...)
+	provider := ai.NewFake(nil)
+	provider.Err = errTestProvider
+	eh := newAIEventHandler(fake, provider)
+
+	rec := httptest.NewRecorder()
+	eh.HandleCreateAIEvents(rec, newTimelineRequest("POST", "/timelines/x/aievents", `{"prompt":"x","mode":"replace"}`))
+	if rec.Code != http.StatusBadGateway {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusBadGateway)
+	}
+	if got := fake.titles(); !slices.Equal(got, []string{"1914", "1918"}) {
+		t.Errorf("timeline has events %q, want them untouched", got)
+	}
+	if fake.Called("SettleAIUsage") != 1 {
+		t.Error("the failed call wasn't recorded")
+	}
+}
+
+func TestCreateAIEventsStaysWithinQuota(t *testing.T) {
+	const prompt = "Every Olympic Games"
+	many := make([]ai.Event, 100)
+	for i := range many {
+		many[i] = ai.Event{Title: "Games", CardTitle: "Olympics"}
+	}
+
+	tests := []struct {
+		name       string
+		remaining  int64
+		wantStatus int
+		wantEvents int
+	}{
+		{"room for every event", int64(ai.EstimatePromptTokens(prompt)) + 5000, http.StatusOK, 100},
+		{"room for some events", int64(ai.EstimatePromptTokens(prompt)) + 530, http.StatusOK, 53},
+		{"too little room", int64(ai.EstimatePromptTokens(prompt)) + minAIOutputTokens - 1, http.StatusTooManyRequests, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			const quota = 100_000
+			fake := newFakeTimeline(t)
+			fake.On("GetAIUsageSince", func(args []any) (any, error) {
+				return db.GetAIUsageSinceRow{Requests: 1, Tokens: quota - tt.remaining}, nil
+			})
+			var spent int64
+			fake.On("SettleAIUsage", func(args []any) (any, error) {
+				spent = int64(args[1].(int32) + args[2].(int32))
+				return nil, nil
+			})
+			eh := NewEventHandler(db.New(fake), fake, ai.NewFake(many), ai.Limits{DailyTokens: quota}, testLogger)
+
+			body, _ := json.Marshal(AIEventRequest{Prompt: prompt, Mode: aiModePreview})
+			rec := httptest.NewRecorder()
+			eh.HandleCreateAIEvents(rec, newTimelineRequest("POST", "/timelines/x/aievents", string(body)))
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if tt.wantStatus != http.StatusOK {
+				if fake.Called("SettleAIUsage") != 0 {
+					t.Error("the model was called")
+				}
+				return
+			}
+
+			var resp struct {
+				Events []TimelineEventRequest `json:"events"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			if len(resp.Events) != tt.wantEvents {
+				t.Errorf("got %d events, want %d", len(resp.Events), tt.wantEvents)
+			}
+			if spent > tt.remaining {
+				t.Errorf("spent %d tokens with %d left", spent, tt.remaining)
+			}
+		})
+	}
+}
+
+// overlappingProvider is a fake provider that runs another request from the
+// same user while it generates.
+type overlappingProvider struct {
+	*ai.Fake
+	during func()
+}
+
+func (p overlappingProvider) GenerateEvents(ctx context.Context, prompt string, maxOutputTokens int) (*ai.Result, error) {
+	p.during()
+	return p.Fake.GenerateEvents(ctx, prompt, maxOutputTokens)
+}
+
+func TestCreateAIEventsReservesQuota(t *testing.T) {
+	const (
+		prompt = "Every Olympic Games"
+		quota  = 2000
+	)
+	fake := newFakeTimeline(t)
+	// usage is the user's ai_usage table: each row's reserved tokens, until
+	// it settles with what it used.
+	var usage []int64
+	fake.On("GetAIUsageSince", func(args []any) (any, error) {
+		var tokens int64
+		for _, used := range usage {
+			tokens += used
+		}
+		return db.GetAIUsageSinceRow{Requests: int64(len(usage)), Tokens: tokens}, nil
+	})
+	fake.On("ReserveAIUsage", func(args []any) (any, error) {
+		usage = append(usage, int64(args[4].(int32)))
+		return pgtype.UUID{Bytes: [16]byte{0: byte(len(usage))}, Valid: true}, nil
+	})
+	fake.On("SettleAIUsage", func(args []any) (any, error) {
+		usage[args[0].(pgtype.UUID).Bytes[0]-1] = int64(args[1].(int32) + args[2].(int32))
+		return nil, nil
+	})
+
+	many := make([]ai.Event, 200)
+	for i := range many {
+		many[i] = ai.Event{Title: "Games", CardTitle: "Olympics"}
+	}
+	body, _ := json.Marshal(AIEventRequest{Prompt: prompt, Mode: aiModePreview})
+	var overlapping *httptest.ResponseRecorder
+	var eh *EventHandler
+	provider := overlappingProvider{Fake: ai.NewFake(many), during: func() {
+		if overlapping != nil {
+			return
+		}
+		overlapping = httptest.NewRecorder()
+		eh.HandleCreateAIEvents(overlapping, newTimelineRequest("POST", "/timelines/x/aievents", string(body)))
+	}}
+	eh = NewEventHandler(db.New(fake), fake, provider, ai.Limits{DailyTokens: quota}, testLogger)
+
+	rec := httptest.NewRecorder()
+	eh.HandleCreateAIEvents(rec, newTimelineRequest("POST", "/timelines/x/aievents", string(body)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+	}
+	if overlapping.Code != http.StatusTooManyRequests {
+		t.Errorf("request during the first one's generation = %d, want 429: the quota is reserved", overlapping.Code)
+	}
+
+	var spent int64
+	for _, used := range usage {
+		spent += used
+	}
+	if spent > quota {
+		t.Errorf("spent %d tokens of a %d quota", spent, quota)
+	}
+	if fake.Called("LockAIUsage") != 2 {
+		t.Errorf("quota checked under the lock %d times, want 2", fake.Called("LockAIUsage"))
+	}
+}
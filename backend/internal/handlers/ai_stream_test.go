@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/nabsk911/chronify/internal/ai"
+	"github.com/nabsk911/chronify/internal/db"
+	"github.com/nabsk911/chronify/internal/fractional"
+)
+
+// busyProvider is a fake provider whose timeline gets a new event from
+// someone else before each generated one.
+type busyProvider struct {
+	*ai.Fake
+	timeline *fakeTimeline
+}
+
+func (p busyProvider) StreamEvents(ctx context.Context, prompt string, maxOutputTokens int, emit func(ai.Event) error) (ai.Usage, error) {
+	return p.Fake.StreamEvents(ctx, prompt, maxOutputTokens, func(event ai.Event) error {
+		events := p.timeline.list()
+		position, err := fractional.KeyBetween(events[len(events)-1].Position, "")
+		if err != nil {
+			return err
+		}
+		p.timeline.add(db.Event{Title: "concurrent", Position: position})
+		return emit(event)
+	})
+}
+
+type sseMessage struct {
+	event string
+	data  string
+}
+
+// streamAIEvents runs a stream request and returns its messages.
+func streamAIEvents(t *testing.T, eh *EventHandler, body string) []sseMessage {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.SetPathValue("timelineId", testTimelineID.String())
+		eh.HandleStreamAIEvents(w, r.WithContext(context.WithValue(r.Context(), "userID", testUserID.String())))
+	}))
+	defer server.Close()
+
+	resp, err := http.Post(server.URL+"/stream", "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var messages []sseMessage
+	var msg sseMessage
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "event: "):
+			msg.event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			msg.data = strings.TrimPrefix(line, "data: ")
+		case line == "":
+			messages = append(messages, msg)
+			msg = sseMessage{}
+		}
+	}
+	return messages
+}
+
+func TestStreamAIEvents(t *testing.T) {
+	for _, persist := range []bool{false, true} {
+		name := "save at the end"
+		if persist {
+			name = "persist incrementally"
+		}
+		t.Run(name, func(t *testing.T) {
+			fake := newFakeTimeline(t, existingEvents...)
+			provider := busyProvider{Fake: ai.NewFake(testAIEvents), timeline: fake}
+			eh := newAIEventHandler(fake, provider)
+
+			body, _ := json.Marshal(AIEventStreamRequest{Prompt: "The 20th century", PersistIncrementally: persist})
+			messages := streamAIEvents(t, eh, string(body))
+
+			var streamed []map[string]any
+			for _, msg := range messages {
+				if msg.event != "event" {
+					continue
+				}
+				var event map[string]any
+				if err := json.Unmarshal([]byte(msg.data), &event); err != nil {
+					t.Fatal(err)
+				}
+				streamed = append(streamed, event)
+			}
+			if len(streamed) != len(testAIEvents) {
+				t.Fatalf("streamed %d events, want %d: %v", len(streamed), len(testAIEvents), messages)
+			}
+			for i, event := range streamed {
+				if event["title"] != testAIEvents[i].Title || event["card_title"] != testAIEvents[i].CardTitle {
+					t.Errorf("event %d = %v, want %q", i, event, testAIEvents[i].Title)
+				}
+				if _, ok := event["id"]; ok != persist {
+					t.Errorf("event %d has an id: %v, want %v", i, ok, persist)
+				}
+				if _, ok := event["timeline_id"]; ok {
+					t.Errorf("event %d isn't shaped like a request event: %v", i, event)
+				}
+			}
+			if last := messages[len(messages)-1]; last.event != "done" {
+				t.Fatalf("last message = %q %s, want done", last.event, last.data)
+			}
+
+			// The generated events land after everything saved before them,
+			// including the concurrent events, without sharing a position.
+			var positions []string
+			for _, e := range fake.list() {
+				positions = append(positions, e.Position)
+			}
+			if len(slices.Compact(slices.Clone(positions))) != len(positions) {
+				t.Errorf("positions collide: %q", positions)
+			}
+			want := []string{"1914", "1918", "concurrent", "1969", "concurrent", "1989"}
+			if !persist {
+				want = []string{"1914", "1918", "concurrent", "concurrent", "1969", "1989"}
+			}
+			if got := fake.titles(); !slices.Equal(got, want) {
+				t.Errorf("timeline has events %q, want %q", got, want)
+			}
+
+			wantRevisions := 1
+			if persist {
+				wantRevisions = len(testAIEvents)
+			}
+			if len(fake.revisions) != wantRevisions {
+				t.Errorf("%d revisions recorded, want %d", len(fake.revisions), wantRevisions)
+			}
+			// One more commit reserved the tokens before generating.
+			if fake.Commits()-1 != wantRevisions {
+				t.Errorf("%d transactions committed, want %d", fake.Commits()-1, wantRevisions)
+			}
+		})
+	}
+}
+
+func TestStreamAIEventsWithoutStreaming(t *testing.T) {
+	fake := newFakeTimeline(t, existingEvents...)
+	provider := ai.NewFake(testAIEvents)
+	eh := newAIEventHandler(fake, provider)
+
+	// A recorder can't lift the write deadline, like a writer that can't
+	// stream.
+	body, _ := json.Marshal(AIEventStreamRequest{Prompt: "The 20th century"})
+	w := httptest.NewRecorder()
+	eh.HandleStreamAIEvents(w, newTimelineRequest("POST", "/stream", string(body)))
+
+	if w.Code != http.StatusInternalServerError || w.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("response = %d %s, want a 500 JSON error", w.Code, w.Header().Get("Content-Type"))
+	}
+	if got := fake.titles(); len(got) != len(existingEvents) {
+		t.Errorf("timeline has events %q, want none added", got)
+	}
+}
@@ -0,0 +1,211 @@
+package handlers
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/nabsk911/chronify/internal/ai"
+	"github.com/nabsk911/chronify/internal/db"
+	"github.com/nabsk911/chronify/internal/utils"
+)
+
+// aiUsageWindow is a user's usage over the current UTC day or month.
+type aiUsageWindow struct {
+	Requests        int64     `json:"requests"`
+	TokensUsed      int64     `json:"tokens_used"`
+	TokenLimit      *int64    `json:"token_limit"`
+	TokensRemaining *int64    `json:"tokens_remaining"`
+	ResetsAt        time.Time `json:"resets_at"`
+}
+
+type aiRateLimit struct {
+	RequestsPerMinute float64 `json:"requests_per_minute"`
+	Burst             int     `json:"burst"`
+	Available         int     `json:"available"`
+}
+
+func aiUsageWindowSince(ctx context.Context, store *db.Queries, userID pgtype.UUID, start, end time.Time, limit int64) (aiUsageWindow, error) {
+	usage, err := store.GetAIUsageSince(ctx, db.GetAIUsageSinceParams{
+		UserID:    userID,
+		CreatedAt: pgtype.Timestamptz{Time: start, Valid: true},
+	})
+	if err != nil {
+		return aiUsageWindow{}, err
+	}
+
+	window := aiUsageWindow{
+		Requests:   usage.Requests,
+		TokensUsed: usage.Tokens,
+		ResetsAt:   end,
+	}
+	if limit > 0 {
+		remaining := max(limit-usage.Tokens, 0)
+		window.TokenLimit = &limit
+		window.TokensRemaining = &remaining
+	}
+	return window, nil
+}
+
+// aiUsageWindows returns the user's usage for the current UTC day and month,
+// counting the tokens reserved by generations still running.
+func (eh *EventHandler) aiUsageWindows(ctx context.Context, store *db.Queries, userID pgtype.UUID) (daily, monthly aiUsageWindow, err error) {
+	now := time.Now().UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	daily, err = aiUsageWindowSince(ctx, store, userID, today, today.AddDate(0, 0, 1), eh.aiLimits.DailyTokens)
+	if err != nil {
+		return daily, monthly, err
+	}
+	monthly, err = aiUsageWindowSince(ctx, store, userID, month, month.AddDate(0, 1, 0), eh.aiLimits.MonthlyTokens)
+	return daily, monthly, err
+}
+
+// minAIOutputTokens is the least room for an answer worth asking the model
+// for. A request that would have less before a quota runs out is turned away
+// rather than cut off part way through.
+const minAIOutputTokens = 500
+
+// allowAIRequest checks the user's quotas and request rate, and writes a 429
+// with Retry-After when either has run out. Otherwise it returns how many
+// tokens the model may generate: what is left of the tightest quota, less
+// what the prompt is estimated to cost, so a single request can't overshoot
+// a quota. Zero means there is no quota.
+//
+// Those tokens are reserved in the usage log before it returns, under a lock
+// on the user, so requests running at the same time share the quota rather
+// than each being allowed all of it. The caller settles the reservation with
+// recordAIUsage, which it must call once it has a usageID.
+//
+// The request rate is limited in this server's memory only; see ai.Limits.
+func (eh *EventHandler) allowAIRequest(w http.ResponseWriter, r *http.Request, userID, timelineID pgtype.UUID, prompt string) (usageID pgtype.UUID, maxOutputTokens int, ok bool) {
+	ctx := r.Context()
+	tx, err := eh.dbConn.Begin(ctx)
+	if err != nil {
+		eh.logger.Printf("Failed to begin transaction: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to check AI usage"})
+		return usageID, 0, false
+	}
+	defer tx.Rollback(ctx)
+	qtx := eh.eventStore.WithTx(tx)
+
+	if err := qtx.LockAIUsage(ctx, userID); err != nil {
+		eh.logger.Printf("Failed to lock AI usage: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to check AI usage"})
+		return usageID, 0, false
+	}
+
+	daily, monthly, err := eh.aiUsageWindows(ctx, qtx, userID)
+	if err != nil {
+		eh.logger.Printf("Failed to retrieve AI usage: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to check AI usage"})
+		return usageID, 0, false
+	}
+
+	promptTokens := ai.EstimatePromptTokens(prompt)
+	quotas := []struct {
+		window  aiUsageWindow
+		message string
+	}{
+		{monthly, "Monthly AI token quota exceeded"},
+		{daily, "Daily AI token quota exceeded"},
+	}
+	for _, quota := range quotas {
+		if quota.window.TokensRemaining == nil {
+			continue
+		}
+		budget := *quota.window.TokensRemaining - int64(promptTokens)
+		if budget < minAIOutputTokens {
+			writeTooManyRequests(w, time.Until(quota.window.ResetsAt), quota.message)
+			return usageID, 0, false
+		}
+		if maxOutputTokens == 0 || budget < int64(maxOutputTokens) {
+			maxOutputTokens = int(budget)
+		}
+	}
+
+	if eh.aiLimiter != nil {
+		if ok, wait := eh.aiLimiter.Allow(userID.String()); !ok {
+			writeTooManyRequests(w, wait, "Too many AI requests")
+			return usageID, 0, false
+		}
+	}
+
+	// Without a quota there is nothing to hold back, but the row is still
+	// made now so the request is logged however it ends.
+	reserved := 0
+	if maxOutputTokens > 0 {
+		reserved = promptTokens + maxOutputTokens
+	}
+	usageID, err = qtx.ReserveAIUsage(ctx, db.ReserveAIUsageParams{
+		UserID:         userID,
+		TimelineID:     timelineID,
+		Provider:       eh.aiProvider.Name(),
+		Model:          eh.aiProvider.Model(),
+		ReservedTokens: int32(reserved),
+	})
+	if err != nil {
+		eh.logger.Printf("Failed to reserve AI usage: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to check AI usage"})
+		return usageID, 0, false
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		eh.logger.Printf("Failed to commit transaction: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to check AI usage"})
+		return usageID, 0, false
+	}
+	return usageID, maxOutputTokens, true
+}
+
+func writeTooManyRequests(w http.ResponseWriter, retryAfter time.Duration, message string) {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	w.Header().Set("Retry-After", strconv.Itoa(max(seconds, 1)))
+	utils.WriteJSON(w, http.StatusTooManyRequests, utils.Envelope{"message": message})
+}
+
+// recordAIUsage settles a generation call's reservation with what it used.
+// It still runs when the client has gone away, since the tokens were spent
+// regardless.
+func (eh *EventHandler) recordAIUsage(ctx context.Context, usageID pgtype.UUID, started time.Time, usage ai.Usage, genErr error) {
+	err := eh.eventStore.SettleAIUsage(context.WithoutCancel(ctx), db.SettleAIUsageParams{
+		ID:           usageID,
+		PromptTokens: int32(usage.PromptTokens),
+		OutputTokens: int32(usage.OutputTokens),
+		LatencyMs:    int32(time.Since(started).Milliseconds()),
+		Success:      genErr == nil,
+	})
+	if err != nil {
+		eh.logger.Printf("Failed to record AI usage: %v", err)
+	}
+}
+
+func (eh *EventHandler) HandleGetAIUsage(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.ReadUserID(r)
+	if err != nil {
+		eh.logger.Printf("Invalid user ID format: %v", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Invalid user ID"})
+		return
+	}
+
+	daily, monthly, err := eh.aiUsageWindows(r.Context(), eh.eventStore, userID)
+	if err != nil {
+		eh.logger.Printf("Failed to retrieve AI usage: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to retrieve AI usage"})
+		return
+	}
+
+	envelope := utils.Envelope{"daily": daily, "monthly": monthly, "rate": nil}
+	if eh.aiLimiter != nil {
+		envelope["rate"] = aiRateLimit{
+			RequestsPerMinute: eh.aiLimits.RequestsPerMinute,
+			Burst:             eh.aiLimits.Burst,
+			Available:         eh.aiLimiter.Available(userID.String()),
+		}
+	}
+	utils.WriteJSON(w, http.StatusOK, envelope)
+}
@@ -0,0 +1,408 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/nabsk911/chronify/internal/db"
+	"github.com/nabsk911/chronify/internal/storage"
+	"github.com/nabsk911/chronify/internal/utils"
+)
+
+const maxFilenameLength = 255
+
+// multipartOverhead is room in the request body for the multipart framing
+// around the file.
+const multipartOverhead = 1 << 20
+
+// attachmentTypes are the content types that can be uploaded, as sniffed from
+// the file itself. The type the client claims is ignored.
+var attachmentTypes = map[string]bool{
+	"image/jpeg":      true,
+	"image/png":       true,
+	"image/gif":       true,
+	"image/webp":      true,
+	"application/pdf": true,
+	"text/plain":      true,
+}
+
+type AttachmentHandler struct {
+	attachmentStore *db.Queries
+	files           storage.Store
+	maxBytes        int64
+	logger          *log.Logger
+}
+
+func NewAttachmentHandler(attachmentStore *db.Queries, files storage.Store, maxBytes int64, logger *log.Logger) *AttachmentHandler {
+	return &AttachmentHandler{
+		attachmentStore: attachmentStore,
+		files:           files,
+		maxBytes:        maxBytes,
+		logger:          logger,
+	}
+}
+
+// sniffContentType returns the media type of data, without parameters such
+// as the charset of text.
+func sniffContentType(data []byte) string {
+	mediaType, _, err := mime.ParseMediaType(http.DetectContentType(data))
+	if err != nil {
+		return "application/octet-stream"
+	}
+	return mediaType
+}
+
+// imageSize returns the width and height of an image without decoding it.
+func imageSize(contentType string, data []byte) (width, height int, ok bool) {
+	if contentType == "image/webp" {
+		return webpSize(data)
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return 0, 0, false
+	}
+	return cfg.Width, cfg.Height, true
+}
+
+// webpSize reads the size from the header of the first chunk of a WebP file,
+// which is lossy (VP8), lossless (VP8L) or extended (VP8X).
+func webpSize(data []byte) (width, height int, ok bool) {
+	if len(data) < 30 || string(data[0:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return 0, 0, false
+	}
+	switch string(data[12:16]) {
+	case "VP8 ":
+		if data[23] != 0x9d || data[24] != 0x01 || data[25] != 0x2a {
+			return 0, 0, false
+		}
+		width = int(binary.LittleEndian.Uint16(data[26:28]) & 0x3fff)
+		height = int(binary.LittleEndian.Uint16(data[28:30]) & 0x3fff)
+	case "VP8L":
+		if data[20] != 0x2f {
+			return 0, 0, false
+		}
+		bits := binary.LittleEndian.Uint32(data[21:25])
+		width = int(bits&0x3fff) + 1
+		height = int(bits>>14&0x3fff) + 1
+	case "VP8X":
+		width = int(uint32(data[24])|uint32(data[25])<<8|uint32(data[26])<<16) + 1
+		height = int(uint32(data[27])|uint32(data[28])<<8|uint32(data[29])<<16) + 1
+	default:
+		return 0, 0, false
+	}
+	return width, height, true
+}
+
+// cleanFilename keeps the name the file was uploaded with, short enough to
+// store and without control characters.
+func cleanFilename(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f {
+			return -1
+		}
+		return r
+	}, strings.TrimSpace(name))
+	if utf8.RuneCountInString(name) > maxFilenameLength {
+		name = string([]rune(name)[:maxFilenameLength])
+	}
+	if name == "" {
+		name = "attachment"
+	}
+	return name
+}
+
+func newStorageKey() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "attachments/" + hex.EncodeToString(b), nil
+}
+
+// authorizeEvent checks the caller's role on the timeline as
+// authorizeTimeline does, then loads the {eventId} path parameter from it.
+// When ok is false the response has already been written.
+func (ah *AttachmentHandler) authorizeEvent(w http.ResponseWriter, r *http.Request, minRole string) (event db.Event, ok bool) {
+	timeline, ok := authorizeTimeline(w, r, ah.attachmentStore, ah.logger, minRole)
+	if !ok {
+		return event, false
+	}
+
+	eventID, err := utils.ReadIDParam(r, "eventId")
+	if err != nil {
+		ah.logger.Printf("Invalid event ID: %v", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Invalid event ID"})
+		return event, false
+	}
+
+	event, err = ah.attachmentStore.GetEventById(r.Context(), db.GetEventByIdParams{
+		ID:         eventID,
+		TimelineID: timeline.ID,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"message": "Event not found"})
+		return event, false
+	}
+	if err != nil {
+		ah.logger.Printf("Failed to retrieve event: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to retrieve event"})
+		return event, false
+	}
+	return event, true
+}
+
+func (ah *AttachmentHandler) HandleGetAttachments(w http.ResponseWriter, r *http.Request) {
+	event, ok := ah.authorizeEvent(w, r, roleViewer)
+	if !ok {
+		return
+	}
+
+	attachments, err := ah.attachmentStore.GetAttachmentsByEventId(r.Context(), event.ID)
+	if err != nil {
+		ah.logger.Printf("Failed to retrieve attachments: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to retrieve attachments"})
+		return
+	}
+	if attachments == nil {
+		attachments = []db.Attachment{}
+	}
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"data": attachments})
+}
+
+// HandleUploadAttachment takes the file in the "file" field of a multipart
+// form. Its type is sniffed from its content and must be an image, a PDF or
+// plain text; images also get their width and height recorded.
+func (ah *AttachmentHandler) HandleUploadAttachment(w http.ResponseWriter, r *http.Request) {
+	event, ok := ah.authorizeEvent(w, r, roleEditor)
+	if !ok {
+		return
+	}
+
+	userID, err := utils.ReadUserID(r)
+	if err != nil {
+		ah.logger.Printf("Invalid user ID format: %v", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Invalid user ID"})
+		return
+	}
+
+	tooLarge := "File must be at most " + strconv.FormatInt(ah.maxBytes, 10) + " bytes"
+	r.Body = http.MaxBytesReader(w, r.Body, ah.maxBytes+multipartOverhead)
+
+	mr, err := r.MultipartReader()
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Request must be a multipart form"})
+		return
+	}
+
+	var data []byte
+	var filename string
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			utils.WriteJSON(w, http.StatusRequestEntityTooLarge, utils.Envelope{"message": tooLarge})
+			return
+		}
+		if err != nil {
+			ah.logger.Printf("Failed to read multipart form: %v", err)
+			utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Invalid multipart form"})
+			return
+		}
+		if part.FormName() != "file" {
+			continue
+		}
+
+		data, err = io.ReadAll(io.LimitReader(part, ah.maxBytes+1))
+		if errors.As(err, &maxErr) {
+			utils.WriteJSON(w, http.StatusRequestEntityTooLarge, utils.Envelope{"message": tooLarge})
+			return
+		}
+		if err != nil {
+			ah.logger.Printf("Failed to read uploaded file: %v", err)
+			utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Invalid multipart form"})
+			return
+		}
+		filename = cleanFilename(part.FileName())
+		break
+	}
+
+	if data == nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "A file is required"})
+		return
+	}
+	if int64(len(data)) > ah.maxBytes {
+		utils.WriteJSON(w, http.StatusRequestEntityTooLarge, utils.Envelope{"message": tooLarge})
+		return
+	}
+	if len(data) == 0 {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "File is empty"})
+		return
+	}
+
+	contentType := sniffContentType(data)
+	if !attachmentTypes[contentType] {
+		utils.WriteJSON(w, http.StatusUnsupportedMediaType, utils.Envelope{"message": "Unsupported file type " + contentType})
+		return
+	}
+
+	var width, height pgtype.Int4
+	if strings.HasPrefix(contentType, "image/") {
+		wd, ht, ok := imageSize(contentType, data)
+		if !ok {
+			utils.WriteJSON(w, http.StatusUnsupportedMediaType, utils.Envelope{"message": "Image could not be read"})
+			return
+		}
+		width = pgtype.Int4{Int32: int32(wd), Valid: true}
+		height = pgtype.Int4{Int32: int32(ht), Valid: true}
+	}
+
+	ctx := r.Context()
+
+	key, err := newStorageKey()
+	if err != nil {
+		ah.logger.Printf("Failed to generate storage key: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to upload attachment"})
+		return
+	}
+
+	if err := ah.files.Put(ctx, key, bytes.NewReader(data), int64(len(data)), contentType); err != nil {
+		ah.logger.Printf("Failed to store attachment: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to upload attachment"})
+		return
+	}
+
+	attachment, err := ah.attachmentStore.CreateAttachment(ctx, db.CreateAttachmentParams{
+		EventID:     event.ID,
+		UploadedBy:  userID,
+		Filename:    filename,
+		ContentType: contentType,
+		SizeBytes:   int64(len(data)),
+		Width:       width,
+		Height:      height,
+		StorageKey:  key,
+	})
+	if err != nil {
+		ah.logger.Printf("Failed to create attachment: %v", err)
+		if err := ah.files.Delete(ctx, key); err != nil {
+			ah.logger.Printf("Failed to delete stored attachment %s: %v", key, err)
+		}
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to upload attachment"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, utils.Envelope{"data": attachment, "message": "Attachment uploaded successfully"})
+}
+
+// HandleDownloadAttachment sends the file. Images are shown inline; anything
+// else is sent as a download.
+func (ah *AttachmentHandler) HandleDownloadAttachment(w http.ResponseWriter, r *http.Request) {
+	event, ok := ah.authorizeEvent(w, r, roleViewer)
+	if !ok {
+		return
+	}
+
+	attachmentID, err := utils.ReadIDParam(r, "attachmentId")
+	if err != nil {
+		ah.logger.Printf("Invalid attachment ID: %v", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Invalid attachment ID"})
+		return
+	}
+
+	attachment, err := ah.attachmentStore.GetAttachment(r.Context(), db.GetAttachmentParams{
+		ID:         attachmentID,
+		EventID:    event.ID,
+		TimelineID: event.TimelineID,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"message": "Attachment not found"})
+		return
+	}
+	if err != nil {
+		ah.logger.Printf("Failed to retrieve attachment: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to retrieve attachment"})
+		return
+	}
+
+	body, err := ah.files.Get(r.Context(), attachment.StorageKey)
+	if errors.Is(err, storage.ErrNotFound) {
+		ah.logger.Printf("Attachment %s has no stored file", attachment.ID.String())
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"message": "Attachment not found"})
+		return
+	}
+	if err != nil {
+		ah.logger.Printf("Failed to read stored attachment: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to retrieve attachment"})
+		return
+	}
+	defer body.Close()
+
+	disposition := "attachment"
+	if strings.HasPrefix(attachment.ContentType, "image/") {
+		disposition = "inline"
+	}
+
+	w.Header().Set("Content-Type", attachment.ContentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(attachment.SizeBytes, 10))
+	w.Header().Set("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": attachment.Filename}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+	if _, err := io.Copy(w, body); err != nil {
+		ah.logger.Printf("Failed to send attachment: %v", err)
+	}
+}
+
+func (ah *AttachmentHandler) HandleDeleteAttachment(w http.ResponseWriter, r *http.Request) {
+	event, ok := ah.authorizeEvent(w, r, roleEditor)
+	if !ok {
+		return
+	}
+
+	attachmentID, err := utils.ReadIDParam(r, "attachmentId")
+	if err != nil {
+		ah.logger.Printf("Invalid attachment ID: %v", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Invalid attachment ID"})
+		return
+	}
+
+	key, err := ah.attachmentStore.DeleteAttachment(r.Context(), db.DeleteAttachmentParams{
+		ID:         attachmentID,
+		EventID:    event.ID,
+		TimelineID: event.TimelineID,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"message": "Attachment not found"})
+		return
+	}
+	if err != nil {
+		ah.logger.Printf("Failed to delete attachment: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to delete attachment"})
+		return
+	}
+
+	// The attachment is gone either way; a file left behind only takes up
+	// space.
+	if err := ah.files.Delete(r.Context(), key); err != nil {
+		ah.logger.Printf("Failed to delete stored attachment %s: %v", key, err)
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"message": "Attachment deleted successfully"})
+}
@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+// testWebP returns the start of a WebP file whose first chunk is of the
+// given kind, holding a width by height image.
+func testWebP(kind string, width, height int) []byte {
+	data := make([]byte, 30)
+	copy(data, "RIFF")
+	binary.LittleEndian.PutUint32(data[4:], 1000)
+	copy(data[8:], "WEBP")
+	copy(data[12:], kind)
+	binary.LittleEndian.PutUint32(data[16:], 100)
+	switch kind {
+	case "VP8 ":
+		copy(data[23:], []byte{0x9d, 0x01, 0x2a})
+		binary.LittleEndian.PutUint16(data[26:], uint16(width))
+		binary.LittleEndian.PutUint16(data[28:], uint16(height))
+	case "VP8L":
+		data[20] = 0x2f
+		binary.LittleEndian.PutUint32(data[21:], uint32(width-1)|uint32(height-1)<<14)
+	case "VP8X":
+		w, h := width-1, height-1
+		copy(data[24:], []byte{byte(w), byte(w >> 8), byte(w >> 16), byte(h), byte(h >> 8), byte(h >> 16)})
+	}
+	return data
+}
+
+func encodeImage(t *testing.T, encode func(*bytes.Buffer, image.Image) error) []byte {
+	t.Helper()
+	var b bytes.Buffer
+	if err := encode(&b, image.NewRGBA(image.Rect(0, 0, 3, 2))); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+func TestWebPSize(t *testing.T) {
+	tests := []struct {
+		name       string
+		data       []byte
+		wantWidth  int
+		wantHeight int
+		wantOK     bool
+	}{
+		{"lossy", testWebP("VP8 ", 640, 480), 640, 480, true},
+		{"lossy at its largest", testWebP("VP8 ", 16383, 16383), 16383, 16383, true},
+		{"lossless", testWebP("VP8L", 1024, 768), 1024, 768, true},
+		{"lossless at its largest", testWebP("VP8L", 16384, 16384), 16384, 16384, true},
+		{"extended", testWebP("VP8X", 1, 1), 1, 1, true},
+		{"extended and large", testWebP("VP8X", 20000, 100000), 20000, 100000, true},
+		{"unknown chunk", testWebP("ALPH", 1, 1), 0, 0, false},
+		{"lossy without its start code", func() []byte { d := testWebP("VP8 ", 1, 1); d[23] = 0; return d }(), 0, 0, false},
+		{"lossless without its signature", func() []byte { d := testWebP("VP8L", 1, 1); d[20] = 0; return d }(), 0, 0, false},
+		{"truncated", testWebP("VP8 ", 640, 480)[:29], 0, 0, false},
+		{"not RIFF", append([]byte("RIFX"), testWebP("VP8 ", 1, 1)[4:]...), 0, 0, false},
+		{"RIFF but not WebP", append([]byte("RIFF\x00\x00\x00\x00WAVE"), make([]byte, 18)...), 0, 0, false},
+		{"empty", nil, 0, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			width, height, ok := webpSize(tt.data)
+			if width != tt.wantWidth || height != tt.wantHeight || ok != tt.wantOK {
+				t.Errorf("webpSize = %d, %d, %v, want %d, %d, %v", width, height, ok, tt.wantWidth, tt.wantHeight, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestSniffAttachment(t *testing.T) {
+	pngData := encodeImage(t, func(b *bytes.Buffer, m image.Image) error { return png.Encode(b, m) })
+	jpegData := encodeImage(t, func(b *bytes.Buffer, m image.Image) error { return jpeg.Encode(b, m, nil) })
+	gifData := encodeImage(t, func(b *bytes.Buffer, m image.Image) error { return gif.Encode(b, m, nil) })
+
+	tests := []struct {
+		name        string
+		data        []byte
+		wantType    string
+		allowed     bool
+		wantWidth   int
+		wantHeight  int
+		wantImageOK bool
+	}{
+		{"png", pngData, "image/png", true, 3, 2, true},
+		{"jpeg", jpegData, "image/jpeg", true, 3, 2, true},
+		{"gif", gifData, "image/gif", true, 3, 2, true},
+		{"webp", testWebP("VP8L", 800, 600), "image/webp", true, 800, 600, true},
+		{"pdf", []byte("%PDF-1.7\n1 0 obj\n"), "application/pdf", true, 0, 0, false},
+		{"text", []byte("Notes about the Apollo program\n"), "text/plain", true, 0, 0, false},
+		{"html", []byte("<!DOCTYPE html><html><script>alert(1)</script>"), "text/html", false, 0, 0, false},
+		{"svg", []byte(`<?xml version="1.0"?><svg xmlns="http://www.w3.org/2000/svg"></svg>`), "text/xml", false, 0, 0, false},
+		{"zip", []byte("PK\x03\x04rest of the archive"), "application/zip", false, 0, 0, false},
+		{"binary", []byte{0x00, 0x01, 0x02, 0xff}, "application/octet-stream", false, 0, 0, false},
+		{"png with a broken header", pngData[:12], "image/png", true, 0, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			contentType := sniffContentType(tt.data)
+			if contentType != tt.wantType {
+				t.Fatalf("sniffContentType = %q, want %q", contentType, tt.wantType)
+			}
+			if attachmentTypes[contentType] != tt.allowed {
+				t.Errorf("%s allowed: %v, want %v", contentType, attachmentTypes[contentType], tt.allowed)
+			}
+			width, height, ok := imageSize(contentType, tt.data)
+			if width != tt.wantWidth || height != tt.wantHeight || ok != tt.wantImageOK {
+				t.Errorf("imageSize = %d, %d, %v, want %d, %d, %v", width, height, ok, tt.wantWidth, tt.wantHeight, tt.wantImageOK)
+			}
+		})
+	}
+}
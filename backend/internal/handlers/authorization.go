@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/nabsk911/chronify/internal/db"
+	"github.com/nabsk911/chronify/internal/utils"
+)
+
+const (
+	roleViewer = "viewer"
+	roleEditor = "editor"
+	roleOwner  = "owner"
+)
+
+var roleRanks = map[string]int{
+	roleViewer: 1,
+	roleEditor: 2,
+	roleOwner:  3,
+}
+
+func isValidRole(role string) bool {
+	_, ok := roleRanks[role]
+	return ok
+}
+
+func hasRole(role, minRole string) bool {
+	return roleRanks[role] >= roleRanks[minRole]
+}
+
+// authorizeTimeline loads the {timelineId} path parameter on behalf of the
+// authenticated user and checks that they hold at least minRole on it.
+// Timelines the user isn't a member of are reported as not found so their IDs
+// don't leak. When ok is false the response has already been written.
+func authorizeTimeline(w http.ResponseWriter, r *http.Request, store *db.Queries, logger *log.Logger, minRole string) (timeline db.GetTimeLineByIdRow, ok bool) {
+	userID, err := utils.ReadUserID(r)
+	if err != nil {
+		logger.Printf("Invalid user ID format: %v", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Invalid user ID"})
+		return timeline, false
+	}
+
+	timelineID, err := utils.ReadIDParam(r, "timelineId")
+	if err != nil {
+		logger.Printf("Invalid timeline ID: %v", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Invalid timeline ID"})
+		return timeline, false
+	}
+
+	timeline, err = store.GetTimeLineById(r.Context(), db.GetTimeLineByIdParams{
+		ID:     timelineID,
+		UserID: userID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"message": "Timeline not found"})
+			return timeline, false
+		}
+		logger.Printf("Failed to retrieve timeline: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to retrieve timeline"})
+		return timeline, false
+	}
+
+	if !hasRole(timeline.Role, minRole) {
+		utils.WriteJSON(w, http.StatusForbidden, utils.Envelope{"message": "You don't have permission to do this"})
+		return timeline, false
+	}
+
+	return timeline, true
+}
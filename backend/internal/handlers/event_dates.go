@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const (
+	precisionYear   = "year"
+	precisionMonth  = "month"
+	precisionDay    = "day"
+	precisionMinute = "minute"
+)
+
+// eventDates holds the structured start and optional end of an event. Years
+// are astronomical (1 BCE is 0, 44 BCE is -43) and times of day are minutes
+// after midnight UTC.
+type eventDates struct {
+	StartYear        pgtype.Int8 `json:"start_year"`
+	StartMonth       pgtype.Int2 `json:"start_month"`
+	StartDay         pgtype.Int2 `json:"start_day"`
+	StartMinuteOfDay pgtype.Int2 `json:"start_minute_of_day"`
+	EndYear          pgtype.Int8 `json:"end_year"`
+	EndMonth         pgtype.Int2 `json:"end_month"`
+	EndDay           pgtype.Int2 `json:"end_day"`
+	EndMinuteOfDay   pgtype.Int2 `json:"end_minute_of_day"`
+	DatePrecision    pgtype.Text `json:"date_precision"`
+}
+
+// normalize validates the dates and fills in the precision from the start
+// date when it wasn't given.
+func (d eventDates) normalize() (eventDates, error) {
+	if !d.StartYear.Valid {
+		if d.StartMonth.Valid || d.StartDay.Valid || d.StartMinuteOfDay.Valid ||
+			d.EndYear.Valid || d.EndMonth.Valid || d.EndDay.Valid || d.EndMinuteOfDay.Valid ||
+			d.DatePrecision.Valid {
+			return d, errors.New("start_year is required when other date fields are set")
+		}
+		return d, nil
+	}
+
+	if !d.DatePrecision.Valid || d.DatePrecision.String == "" {
+		d.DatePrecision = pgtype.Text{String: inferPrecision(d.StartMonth, d.StartDay, d.StartMinuteOfDay), Valid: true}
+	}
+	precision := d.DatePrecision.String
+
+	if err := checkDate(precision, d.StartYear, d.StartMonth, d.StartDay, d.StartMinuteOfDay); err != nil {
+		return d, err
+	}
+
+	if !d.EndYear.Valid {
+		if d.EndMonth.Valid || d.EndDay.Valid || d.EndMinuteOfDay.Valid {
+			return d, errors.New("end_year is required when other end date fields are set")
+		}
+		return d, nil
+	}
+
+	if err := checkDate(precision, d.EndYear, d.EndMonth, d.EndDay, d.EndMinuteOfDay); err != nil {
+		return d, err
+	}
+
+	start := []int64{d.StartYear.Int64, int64(d.StartMonth.Int16), int64(d.StartDay.Int16), int64(d.StartMinuteOfDay.Int16)}
+	end := []int64{d.EndYear.Int64, int64(d.EndMonth.Int16), int64(d.EndDay.Int16), int64(d.EndMinuteOfDay.Int16)}
+	for i := range start {
+		if end[i] > start[i] {
+			break
+		}
+		if end[i] < start[i] {
+			return d, errors.New("end date must not be before start date")
+		}
+	}
+
+	return d, nil
+}
+
+func inferPrecision(month, day, minuteOfDay pgtype.Int2) string {
+	switch {
+	case minuteOfDay.Valid:
+		return precisionMinute
+	case day.Valid:
+		return precisionDay
+	case month.Valid:
+		return precisionMonth
+	default:
+		return precisionYear
+	}
+}
+
+// checkDate makes sure exactly the components required by precision are set
+// and that they form a real calendar date.
+func checkDate(precision string, year pgtype.Int8, month, day, minuteOfDay pgtype.Int2) error {
+	var wantMonth, wantDay, wantMinute bool
+	switch precision {
+	case precisionYear:
+	case precisionMonth:
+		wantMonth = true
+	case precisionDay:
+		wantMonth, wantDay = true, true
+	case precisionMinute:
+		wantMonth, wantDay, wantMinute = true, true, true
+	default:
+		return errors.New("date_precision must be one of year, month, day or minute")
+	}
+
+	if month.Valid != wantMonth || day.Valid != wantDay || minuteOfDay.Valid != wantMinute {
+		return errors.New("date fields don't match date_precision " + precision)
+	}
+
+	if month.Valid && (month.Int16 < 1 || month.Int16 > 12) {
+		return errors.New("month must be between 1 and 12")
+	}
+	if day.Valid && (day.Int16 < 1 || int(day.Int16) > daysInMonth(year.Int64, month.Int16)) {
+		return errors.New("day is out of range for the month")
+	}
+	if minuteOfDay.Valid && (minuteOfDay.Int16 < 0 || minuteOfDay.Int16 > 1439) {
+		return errors.New("minute of day must be between 0 and 1439")
+	}
+	return nil
+}
+
+// daysInMonth uses the proleptic Gregorian calendar for every year.
+func daysInMonth(year int64, month int16) int {
+	switch month {
+	case 2:
+		if year%4 == 0 && (year%100 != 0 || year%400 == 0) {
+			return 29
+		}
+		return 28
+	case 4, 6, 9, 11:
+		return 30
+	default:
+		return 31
+	}
+}
@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"sort"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/nabsk911/chronify/internal/db"
+	"github.com/nabsk911/chronify/internal/fractional"
+)
+
+// planPositions returns a position for every entry of ids so that they sort
+// in the given order. Invalid IDs stand for new events. Existing events keep
+// their position whenever it is already in order, so moving one event only
+// rewrites that event. Events that aren't listed stay where they are.
+func planPositions(ids []pgtype.UUID, current []db.GetEventPositionsRow) ([]string, error) {
+	currentPositions := make(map[pgtype.UUID]string, len(current))
+	for _, e := range current {
+		currentPositions[e.ID] = e.Position
+	}
+
+	// Keep the longest run of listed events that is already in order and move
+	// the rest around it.
+	var known []int
+	for i, id := range ids {
+		if _, ok := currentPositions[id]; ok && id.Valid {
+			known = append(known, i)
+		}
+	}
+	keptIdx := longestIncreasing(known, func(i int) string { return currentPositions[ids[i]] })
+
+	positions := make([]string, len(ids))
+	kept := make([]bool, len(ids))
+	for _, i := range keptIdx {
+		positions[i] = currentPositions[ids[i]]
+		kept[i] = true
+	}
+
+	moved := make(map[pgtype.UUID]bool)
+	for _, i := range known {
+		if !kept[i] {
+			moved[ids[i]] = true
+		}
+	}
+
+	// Positions of the events that stay put, used to bound runs at either end.
+	var stationary []string
+	for _, e := range current {
+		if !moved[e.ID] {
+			stationary = append(stationary, e.Position)
+		}
+	}
+
+	for start := 0; start < len(ids); {
+		if kept[start] {
+			start++
+			continue
+		}
+		end := start
+		for end < len(ids) && !kept[end] {
+			end++
+		}
+
+		var lower, upper string
+		switch {
+		case start > 0 && end < len(ids):
+			lower, upper = positions[start-1], positions[end]
+		case start > 0:
+			lower = positions[start-1]
+			upper = successor(stationary, lower)
+		case end < len(ids):
+			upper = positions[end]
+			lower = predecessor(stationary, upper)
+		default:
+			if len(stationary) > 0 {
+				lower = stationary[len(stationary)-1]
+			}
+		}
+
+		keys, err := fractional.NKeysBetween(lower, upper, end-start)
+		if err != nil {
+			return nil, err
+		}
+		copy(positions[start:end], keys)
+		start = end
+	}
+
+	return positions, nil
+}
+
+// longestIncreasing returns the longest subsequence of items whose keys
+// strictly increase, in patience-sorting O(n log n) time.
+func longestIncreasing(items []int, key func(int) string) []int {
+	var tails []int // tails[k] indexes items: the smallest tail of a run of length k+1
+	prev := make([]int, len(items))
+	for i, item := range items {
+		k := sort.Search(len(tails), func(j int) bool {
+			return key(items[tails[j]]) >= key(item)
+		})
+		if k > 0 {
+			prev[i] = tails[k-1]
+		} else {
+			prev[i] = -1
+		}
+		if k == len(tails) {
+			tails = append(tails, i)
+		} else {
+			tails[k] = i
+		}
+	}
+
+	run := make([]int, len(tails))
+	if len(tails) == 0 {
+		return run
+	}
+	for k, i := len(tails)-1, tails[len(tails)-1]; k >= 0; k, i = k-1, prev[i] {
+		run[k] = items[i]
+	}
+	return run
+}
+
+// appendPositions returns n positions after every existing event.
+func appendPositions(current []db.GetEventPositionsRow, n int) ([]string, error) {
+	last := ""
+	if len(current) > 0 {
+		last = current[len(current)-1].Position
+	}
+	return fractional.NKeysBetween(last, "", n)
+}
+
+// successor returns the first of the sorted positions after position, or ""
+// if there is none.
+func successor(sorted []string, position string) string {
+	i := sort.SearchStrings(sorted, position)
+	for i < len(sorted) && sorted[i] == position {
+		i++
+	}
+	if i < len(sorted) {
+		return sorted[i]
+	}
+	return ""
+}
+
+// predecessor returns the last of the sorted positions before position, or ""
+// if there is none.
+func predecessor(sorted []string, position string) string {
+	i := sort.SearchStrings(sorted, position)
+	if i > 0 {
+		return sorted[i-1]
+	}
+	return ""
+}
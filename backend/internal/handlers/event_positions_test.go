@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/nabsk911/chronify/internal/db"
+)
+
+func testEventID(n byte) pgtype.UUID {
+	return pgtype.UUID{Bytes: [16]byte{15: n}, Valid: true}
+}
+
+// testPositions returns events 1 to n at the positions given.
+func testPositions(positions ...string) []db.GetEventPositionsRow {
+	current := make([]db.GetEventPositionsRow, len(positions))
+	for i, position := range positions {
+		current[i] = db.GetEventPositionsRow{ID: testEventID(byte(i + 1)), Position: position}
+	}
+	return current
+}
+
+func TestLongestIncreasing(t *testing.T) {
+	tests := []struct {
+		name string
+		keys []string
+		want []int
+	}{
+		{"empty", nil, []int{}},
+		{"sorted", []string{"a", "b", "c", "d"}, []int{0, 1, 2, 3}},
+		{"reversed", []string{"d", "c", "b", "a"}, []int{3}},
+		{"one moved to the front", []string{"d", "a", "b", "c"}, []int{1, 2, 3}},
+		{"one moved to the back", []string{"b", "c", "d", "a"}, []int{0, 1, 2}},
+		{"two swapped", []string{"a", "c", "b", "d"}, []int{0, 2, 3}},
+		{"interleaved", []string{"c", "a", "d", "b", "e"}, []int{1, 3, 4}},
+		{"equal keys aren't increasing", []string{"a", "a", "b"}, []int{1, 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items := make([]int, len(tt.keys))
+			for i := range items {
+				items[i] = i
+			}
+			got := longestIncreasing(items, func(i int) string { return tt.keys[i] })
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("longestIncreasing(%q) = %v, want %v", tt.keys, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlanPositions(t *testing.T) {
+	newEvent := pgtype.UUID{}
+	tests := []struct {
+		name    string
+		current []db.GetEventPositionsRow
+		order   []pgtype.UUID
+		// kept are the indexes into order whose events keep their position.
+		kept []int
+	}{
+		{
+			name:    "unchanged order",
+			current: testPositions("a", "b", "c"),
+			order:   []pgtype.UUID{testEventID(1), testEventID(2), testEventID(3)},
+			kept:    []int{0, 1, 2},
+		},
+		{
+			name:    "last moved to the front",
+			current: testPositions("a", "b", "c", "d"),
+			order:   []pgtype.UUID{testEventID(4), testEventID(1), testEventID(2), testEventID(3)},
+			kept:    []int{1, 2, 3},
+		},
+		{
+			name:    "first moved to the back",
+			current: testPositions("a", "b", "c", "d"),
+			order:   []pgtype.UUID{testEventID(2), testEventID(3), testEventID(4), testEventID(1)},
+			kept:    []int{0, 1, 2},
+		},
+		{
+			name:    "one moved to the middle",
+			current: testPositions("a", "b", "c", "d", "e"),
+			order:   []pgtype.UUID{testEventID(1), testEventID(2), testEventID(5), testEventID(3), testEventID(4)},
+			kept:    []int{0, 1, 3, 4},
+		},
+		{
+			name:    "adjacent positions",
+			current: testPositions("a", "a1", "a2"),
+			order:   []pgtype.UUID{testEventID(1), testEventID(3), testEventID(2)},
+			kept:    []int{0, 2},
+		},
+		{
+			name:    "reversed",
+			current: testPositions("a", "b", "c"),
+			order:   []pgtype.UUID{testEventID(3), testEventID(2), testEventID(1)},
+			kept:    []int{2},
+		},
+		{
+			name:    "new events around existing ones",
+			current: testPositions("b", "c"),
+			order:   []pgtype.UUID{newEvent, testEventID(1), newEvent, testEventID(2), newEvent},
+			kept:    []int{1, 3},
+		},
+		{
+			name:    "new events only",
+			current: nil,
+			order:   []pgtype.UUID{newEvent, newEvent, newEvent},
+			kept:    []int{},
+		},
+		{
+			name:    "unlisted events stay put",
+			current: testPositions("a", "b", "c", "d"),
+			order:   []pgtype.UUID{testEventID(3), testEventID(2)},
+			kept:    []int{1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			positions, err := planPositions(tt.order, tt.current)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(positions) != len(tt.order) {
+				t.Fatalf("got %d positions for %d events", len(positions), len(tt.order))
+			}
+
+			currentPositions := make(map[pgtype.UUID]string)
+			for _, e := range tt.current {
+				currentPositions[e.ID] = e.Position
+			}
+			for i, id := range tt.order {
+				keep := slices.Contains(tt.kept, i)
+				if keep && positions[i] != currentPositions[id] {
+					t.Errorf("event %d moved from %q to %q, want it kept", i, currentPositions[id], positions[i])
+				}
+				if !keep && positions[i] == currentPositions[id] {
+					t.Errorf("event %d kept %q, want it moved", i, positions[i])
+				}
+			}
+
+			// The listed events sort in the requested order, and the unlisted
+			// ones keep positions that don't collide with them.
+			for i := 1; i < len(positions); i++ {
+				if positions[i-1] >= positions[i] {
+					t.Errorf("positions %d and %d = %q, %q, not ascending", i-1, i, positions[i-1], positions[i])
+				}
+			}
+			all := slices.Clone(positions)
+			for _, e := range tt.current {
+				if !slices.Contains(tt.order, e.ID) {
+					all = append(all, e.Position)
+				}
+			}
+			slices.Sort(all)
+			if len(slices.Compact(slices.Clone(all))) != len(all) {
+				t.Errorf("positions collide: %q", all)
+			}
+		})
+	}
+}
@@ -1,12 +1,17 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
 	"log"
 	"net/http"
+	"slices"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/nabsk911/chronify/internal/ai"
 	"github.com/nabsk911/chronify/internal/db"
+	"github.com/nabsk911/chronify/internal/ratelimit"
 	"github.com/nabsk911/chronify/internal/utils"
 )
 
@@ -16,42 +21,218 @@ type UpsertEventRequest struct {
 	CardTitle        string       `json:"card_title"`
 	CardSubtitle     pgtype.Text  `json:"card_subtitle"`
 	CardDetailedText pgtype.Text  `json:"card_detailed_text"`
+	// Tags replaces the caller's own tags on the event when given. Tags
+	// other members put on it are kept.
+	Tags *[]pgtype.UUID `json:"tags,omitempty"`
+	eventDates
+}
+
+const (
+	upsertCreated  = "created"
+	upsertUpdated  = "updated"
+	upsertNotFound = "not_found"
+	upsertError    = "error"
+	upsertSkipped  = "skipped"
+)
+
+// upsertEventResult reports what happened to one item of an upsert request.
+// The whole batch is saved or none of it is, so when any item fails the
+// others are reported as skipped.
+type upsertEventResult struct {
+	Index  int          `json:"index"`
+	ID     *pgtype.UUID `json:"id,omitempty"`
+	Status string       `json:"status"`
+	Error  string       `json:"error,omitempty"`
+}
+
+type reorderEventsRequest struct {
+	EventIDs []pgtype.UUID `json:"event_ids"`
+}
+
+// TxStarter begins transactions. *pgxpool.Pool satisfies it.
+type TxStarter interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
 }
 
 type EventHandler struct {
 	eventStore *db.Queries
+	dbConn     TxStarter
+	aiProvider ai.Provider
+	aiLimits   ai.Limits
+	aiLimiter  *ratelimit.Limiter
 	logger     *log.Logger
 }
 
-func NewEventHandler(eventStore *db.Queries, logger *log.Logger) *EventHandler {
+func NewEventHandler(eventStore *db.Queries, dbConn TxStarter, aiProvider ai.Provider, aiLimits ai.Limits, logger *log.Logger) *EventHandler {
+	var aiLimiter *ratelimit.Limiter
+	if aiLimits.RequestsPerMinute > 0 {
+		aiLimiter = ratelimit.New(aiLimits.RequestsPerMinute, aiLimits.Burst)
+	}
 	return &EventHandler{
 		eventStore: eventStore,
+		dbConn:     dbConn,
+		aiProvider: aiProvider,
+		aiLimits:   aiLimits,
+		aiLimiter:  aiLimiter,
 		logger:     logger,
 	}
 }
 
+// listEvents returns the timeline's events chronologically, or in their
+// user-chosen order with ?order=position.
+func (eh *EventHandler) listEvents(r *http.Request, timelineID pgtype.UUID) ([]db.Event, error) {
+	if r.URL.Query().Get("order") == "position" {
+		return eh.eventStore.GetEventsByTimelineIdByPosition(r.Context(), timelineID)
+	}
+	return eh.eventStore.GetEventsByTimelineId(r.Context(), timelineID)
+}
+
+// listTaggedEvents is listEvents with each event's tags.
+func (eh *EventHandler) listTaggedEvents(r *http.Request, timelineID pgtype.UUID) ([]taggedEvent, error) {
+	events, err := eh.listEvents(r, timelineID)
+	if err != nil {
+		return nil, err
+	}
+	return tagEvents(r.Context(), eh.eventStore, events)
+}
+
 func (eh *EventHandler) HandleGetEventsByTimelineId(w http.ResponseWriter, r *http.Request) {
-	timelineID, err := utils.ReadIDParam(r, "timelineId")
+	timeline, ok := authorizeTimeline(w, r, eh.eventStore, eh.logger, roleViewer)
+	if !ok {
+		return
+	}
+	timelineID := timeline.ID
+
+	userID, err := utils.ReadUserID(r)
 	if err != nil {
-		eh.logger.Printf("Invalid timeline ID: %v", err)
-		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Invalid timeline ID"})
+		eh.logger.Printf("Invalid user ID format: %v", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Invalid user ID"})
 		return
 	}
 
-	events, err := eh.eventStore.GetEventsByTimelineId(r.Context(), timelineID)
+	// ?order=position still picks the user-chosen order, as it does for
+	// listEvents.
+	sorts := []string{"date", "position"}
+	if r.URL.Query().Get("order") == "position" {
+		sorts = []string{"position", "date"}
+	}
+	p, err := readPage(r, sorts...)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Invalid pagination: " + err.Error()})
+		return
+	}
+
+	tagIDs, err := readTagFilter(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Invalid tag ID"})
+		return
+	}
+
+	var events []db.Event
+	switch p.Sort {
+	case "date":
+		var after eventDateKey
+		if err := p.readAfter(&after); err != nil {
+			utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Invalid pagination: " + err.Error()})
+			return
+		}
+		events, err = eh.eventStore.GetEventsPageByDate(r.Context(), db.GetEventsPageByDateParams{
+			TimelineID:             timelineID,
+			CursorID:               after.ID,
+			CursorStartYear:        after.StartYear,
+			CursorStartMonth:       after.StartMonth,
+			CursorStartDay:         after.StartDay,
+			CursorStartMinuteOfDay: after.StartMinuteOfDay,
+			CursorCreatedAt:        after.CreatedAt,
+			TagIds:                 tagIDs,
+			UserID:                 userID,
+			MaxRows:                int32(p.Limit + 1),
+		})
+	case "position":
+		var after eventPositionKey
+		if err := p.readAfter(&after); err != nil {
+			utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Invalid pagination: " + err.Error()})
+			return
+		}
+		events, err = eh.eventStore.GetEventsPageByPosition(r.Context(), db.GetEventsPageByPositionParams{
+			TimelineID:      timelineID,
+			CursorID:        after.ID,
+			CursorPosition:  pgtype.Text{String: after.Position, Valid: after.ID.Valid},
+			CursorCreatedAt: after.CreatedAt,
+			TagIds:          tagIDs,
+			UserID:          userID,
+			MaxRows:         int32(p.Limit + 1),
+		})
+	}
 	if err != nil {
 		eh.logger.Printf("Failed to retrieve events: %v", err)
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to retrieve events"})
 		return
 	}
-	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"events": events})
+
+	var total *int64
+	if p.Count {
+		count, err := eh.eventStore.CountEventsByTimelineId(r.Context(), db.CountEventsByTimelineIdParams{
+			TimelineID: timelineID,
+			TagIds:     tagIDs,
+			UserID:     userID,
+		})
+		if err != nil {
+			eh.logger.Printf("Failed to count events: %v", err)
+			utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to retrieve events"})
+			return
+		}
+		total = &count
+	}
+
+	tagged, err := tagEvents(r.Context(), eh.eventStore, events)
+	if err != nil {
+		eh.logger.Printf("Failed to retrieve event tags: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to retrieve events"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, pageEnvelope(p, "events", tagged, func(e taggedEvent) any {
+		if p.Sort == "position" {
+			return eventPositionKey{Position: e.Position, CreatedAt: e.CreatedAt, ID: e.ID}
+		}
+		return eventDateKey{
+			StartYear:        e.StartYear,
+			StartMonth:       e.StartMonth,
+			StartDay:         e.StartDay,
+			StartMinuteOfDay: e.StartMinuteOfDay,
+			CreatedAt:        e.CreatedAt,
+			ID:               e.ID,
+		}
+	}, total))
+}
+
+type eventDateKey struct {
+	StartYear        pgtype.Int8        `json:"start_year"`
+	StartMonth       pgtype.Int2        `json:"start_month"`
+	StartDay         pgtype.Int2        `json:"start_day"`
+	StartMinuteOfDay pgtype.Int2        `json:"start_minute_of_day"`
+	CreatedAt        pgtype.Timestamptz `json:"created_at"`
+	ID               pgtype.UUID        `json:"id"`
+}
+
+type eventPositionKey struct {
+	Position  string             `json:"position"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+	ID        pgtype.UUID        `json:"id"`
 }
 
 func (eh *EventHandler) HandleUpsertEvents(w http.ResponseWriter, r *http.Request) {
-	timelineID, err := utils.ReadIDParam(r, "timelineId")
+	timeline, ok := authorizeTimeline(w, r, eh.eventStore, eh.logger, roleEditor)
+	if !ok {
+		return
+	}
+	timelineID := timeline.ID
+
+	userID, err := utils.ReadUserID(r)
 	if err != nil {
-		eh.logger.Printf("Invalid timeline ID: %v", err)
-		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Invalid timeline ID"})
+		eh.logger.Printf("Invalid user ID format: %v", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Invalid user ID"})
 		return
 	}
 
@@ -67,10 +248,116 @@ func (eh *EventHandler) HandleUpsertEvents(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	ctx := r.Context()
+
+	tx, err := eh.dbConn.Begin(ctx)
+	if err != nil {
+		eh.logger.Printf("Failed to begin transaction: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to save events"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := eh.eventStore.WithTx(tx)
+
+	if err := qtx.LockTimeline(ctx, timelineID); err != nil {
+		eh.logger.Printf("Failed to lock timeline: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to save events"})
+		return
+	}
+
+	// Locks the timeline's events until the transaction ends.
+	current, err := qtx.GetEventPositions(ctx, timelineID)
+	if err != nil {
+		eh.logger.Printf("Failed to retrieve event positions: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to save events"})
+		return
+	}
+
+	currentPositions := make(map[pgtype.UUID]string, len(current))
+	for _, e := range current {
+		currentPositions[e.ID] = e.Position
+	}
+
+	var tagIDs []pgtype.UUID
+	for _, e := range req {
+		if e.Tags != nil {
+			tagIDs = append(tagIDs, *e.Tags...)
+		}
+	}
+	unknown, err := unknownTags(ctx, qtx, userID, tagIDs)
+	if err != nil {
+		eh.logger.Printf("Failed to check tags: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to save events"})
+		return
+	}
+
+	// Validate every item before writing anything.
+	results := make([]upsertEventResult, len(req))
+	dates := make([]eventDates, len(req))
+	ids := make([]pgtype.UUID, len(req))
+	seen := make(map[pgtype.UUID]bool)
+	var invalid, notFound bool
+	for i, e := range req {
+		results[i] = upsertEventResult{Index: i, ID: e.ID}
+
+		if e.ID != nil && e.ID.Valid {
+			if _, ok := currentPositions[*e.ID]; !ok {
+				results[i].Status = upsertNotFound
+				results[i].Error = "Event not found in this timeline"
+				notFound = true
+				continue
+			}
+			if seen[*e.ID] {
+				results[i].Status = upsertError
+				results[i].Error = "Duplicate event ID"
+				invalid = true
+				continue
+			}
+			seen[*e.ID] = true
+			ids[i] = *e.ID
+		}
+
+		dates[i], err = e.eventDates.normalize()
+		if err != nil {
+			results[i].Status = upsertError
+			results[i].Error = err.Error()
+			invalid = true
+			continue
+		}
+
+		if e.Tags != nil && slices.ContainsFunc(*e.Tags, func(id pgtype.UUID) bool { return unknown[id] }) {
+			results[i].Status = upsertError
+			results[i].Error = "Tag not found"
+			invalid = true
+		}
+	}
+
+	if invalid || notFound {
+		status := http.StatusNotFound
+		if invalid {
+			status = http.StatusBadRequest
+		}
+		markSkipped(results)
+		utils.WriteJSON(w, status, utils.Envelope{"message": "No events were saved", "results": results})
+		return
+	}
+
+	// Events are placed in the order they were submitted.
+	positions, err := planPositions(ids, current)
+	if err != nil {
+		eh.logger.Printf("Failed to plan event positions: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to save events"})
+		return
+	}
+
 	var createParams []db.BulkCreateEventsParams
 	var updateParams []db.BulkUpdateEventsParams
+	var positionParams []db.UpdateEventPositionsParams
+	var createIdx, updateIdx, positionIdx []int
 
-	for _, e := range req {
+	for i, e := range req {
+		d := dates[i]
 		if e.ID == nil || !e.ID.Valid {
 			// New event → create
 			createParams = append(createParams, db.BulkCreateEventsParams{
@@ -79,7 +366,18 @@ func (eh *EventHandler) HandleUpsertEvents(w http.ResponseWriter, r *http.Reques
 				CardTitle:        e.CardTitle,
 				CardSubtitle:     e.CardSubtitle,
 				CardDetailedText: e.CardDetailedText,
+				StartYear:        d.StartYear,
+				StartMonth:       d.StartMonth,
+				StartDay:         d.StartDay,
+				StartMinuteOfDay: d.StartMinuteOfDay,
+				EndYear:          d.EndYear,
+				EndMonth:         d.EndMonth,
+				EndDay:           d.EndDay,
+				EndMinuteOfDay:   d.EndMinuteOfDay,
+				DatePrecision:    d.DatePrecision,
+				Position:         positions[i],
 			})
+			createIdx = append(createIdx, i)
 		} else {
 			// Existing event → update
 			updateParams = append(updateParams, db.BulkUpdateEventsParams{
@@ -88,51 +386,177 @@ func (eh *EventHandler) HandleUpsertEvents(w http.ResponseWriter, r *http.Reques
 				CardTitle:        e.CardTitle,
 				CardSubtitle:     e.CardSubtitle,
 				CardDetailedText: e.CardDetailedText,
+				StartYear:        d.StartYear,
+				StartMonth:       d.StartMonth,
+				StartDay:         d.StartDay,
+				StartMinuteOfDay: d.StartMinuteOfDay,
+				EndYear:          d.EndYear,
+				EndMonth:         d.EndMonth,
+				EndDay:           d.EndDay,
+				EndMinuteOfDay:   d.EndMinuteOfDay,
+				DatePrecision:    d.DatePrecision,
+				TimelineID:       timelineID,
 			})
+			updateIdx = append(updateIdx, i)
+			if currentPositions[*e.ID] != positions[i] {
+				positionParams = append(positionParams, db.UpdateEventPositionsParams{
+					ID:         *e.ID,
+					Position:   positions[i],
+					TimelineID: timelineID,
+				})
+				positionIdx = append(positionIdx, i)
+			}
 		}
 	}
 
-	ctx := r.Context()
-
 	// Bulk create new events
 	if len(createParams) > 0 {
-		_, err := eh.eventStore.BulkCreateEvents(ctx, createParams)
+		_, err := qtx.BulkCreateEvents(ctx, createParams)
 		if err != nil {
 			eh.logger.Printf("Failed to create events: %v", err)
-			utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to create events"})
+			for _, i := range createIdx {
+				results[i].Status = upsertError
+				results[i].Error = "Failed to create event"
+			}
+			markSkipped(results)
+			utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "No events were saved", "results": results})
 			return
 		}
 	}
 
 	// Bulk update existing events
+	failed := false
 	if len(updateParams) > 0 {
-		results := eh.eventStore.BulkUpdateEvents(ctx, updateParams)
-		defer results.Close()
-		results.Exec(func(i int, err error) {
+		qtx.BulkUpdateEvents(ctx, updateParams).Exec(func(j int, err error) {
 			if err != nil {
 				eh.logger.Printf("Failed to update event: %v", err)
-				utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to update one or more events"})
-				return
+				results[updateIdx[j]].Status = upsertError
+				results[updateIdx[j]].Error = "Failed to update event"
+				failed = true
 			}
 		})
 	}
 
+	// Move existing events into the submitted order
+	if !failed && len(positionParams) > 0 {
+		qtx.UpdateEventPositions(ctx, positionParams).Exec(func(j int, err error) {
+			if err != nil {
+				eh.logger.Printf("Failed to update event position: %v", err)
+				results[positionIdx[j]].Status = upsertError
+				results[positionIdx[j]].Error = "Failed to move event"
+				failed = true
+			}
+		})
+	}
+
+	if failed {
+		markSkipped(results)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "No events were saved", "results": results})
+		return
+	}
+
+	// COPY doesn't return IDs, so find the new events by their positions.
+	if len(createIdx) > 0 {
+		saved, err := qtx.GetEventPositions(ctx, timelineID)
+		if err != nil {
+			eh.logger.Printf("Failed to retrieve event positions: %v", err)
+			utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to save events"})
+			return
+		}
+		savedIDs := make(map[string]pgtype.UUID, len(saved))
+		for _, e := range saved {
+			savedIDs[e.Position] = e.ID
+		}
+		for _, i := range createIdx {
+			id := savedIDs[positions[i]]
+			results[i].ID = &id
+		}
+	}
+
+	// An item's tags replace the caller's own tags on its event.
+	var taggedIDs, eventTagEventIDs, eventTagTagIDs []pgtype.UUID
+	for i, e := range req {
+		if e.Tags == nil {
+			continue
+		}
+		taggedIDs = append(taggedIDs, *results[i].ID)
+		for _, tagID := range *e.Tags {
+			eventTagEventIDs = append(eventTagEventIDs, *results[i].ID)
+			eventTagTagIDs = append(eventTagTagIDs, tagID)
+		}
+	}
+	if len(taggedIDs) > 0 {
+		if err := qtx.DeleteEventTagsByUserId(ctx, db.DeleteEventTagsByUserIdParams{
+			EventIds: taggedIDs,
+			UserID:   userID,
+		}); err != nil {
+			eh.logger.Printf("Failed to remove event tags: %v", err)
+			utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to save events"})
+			return
+		}
+	}
+	if len(eventTagEventIDs) > 0 {
+		if err := qtx.AddEventTags(ctx, db.AddEventTagsParams{
+			EventIds: eventTagEventIDs,
+			TagIds:   eventTagTagIDs,
+		}); err != nil {
+			eh.logger.Printf("Failed to add event tags: %v", err)
+			utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to save events"})
+			return
+		}
+	}
+
+	if err := recordRevision(ctx, qtx, timelineID, userID, revisionUpsertEvents, pgtype.Int4{}); err != nil {
+		eh.logger.Printf("Failed to record revision: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to save events"})
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		eh.logger.Printf("Failed to commit transaction: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to save events"})
+		return
+	}
+
+	for _, i := range createIdx {
+		results[i].Status = upsertCreated
+	}
+	for _, i := range updateIdx {
+		results[i].Status = upsertUpdated
+	}
+
 	// Return full list for the timeline
-	events, err := eh.eventStore.GetEventsByTimelineId(ctx, timelineID)
+	events, err := eh.listTaggedEvents(r, timelineID)
 	if err != nil {
 		eh.logger.Printf("Failed to retrieve events: %v", err)
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to retrieve events"})
 		return
 	}
 
-	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"events": events})
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"events": events, "results": results})
+}
+
+// markSkipped flags every item without a result as not saved because another
+// item in the same batch failed.
+func markSkipped(results []upsertEventResult) {
+	for i := range results {
+		if results[i].Status == "" {
+			results[i].Status = upsertSkipped
+		}
+	}
 }
 
 func (eh *EventHandler) HandleDeleteEvent(w http.ResponseWriter, r *http.Request) {
-	timelineID, err := utils.ReadIDParam(r, "timelineId")
+	timeline, ok := authorizeTimeline(w, r, eh.eventStore, eh.logger, roleEditor)
+	if !ok {
+		return
+	}
+	timelineID := timeline.ID
+
+	userID, err := utils.ReadUserID(r)
 	if err != nil {
-		eh.logger.Printf("Invalid timeline ID: %v", err)
-		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Invalid timeline ID"})
+		eh.logger.Printf("Invalid user ID format: %v", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Invalid user ID"})
 		return
 	}
 
@@ -143,19 +567,186 @@ func (eh *EventHandler) HandleDeleteEvent(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	err = eh.eventStore.DeleteEvent(r.Context(), eventID)
+	ctx := r.Context()
+
+	tx, err := eh.dbConn.Begin(ctx)
+	if err != nil {
+		eh.logger.Printf("Failed to begin transaction: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to delete event"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := eh.eventStore.WithTx(tx)
+
+	if err := qtx.LockTimeline(ctx, timelineID); err != nil {
+		eh.logger.Printf("Failed to lock timeline: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to delete event"})
+		return
+	}
+
+	deleted, err := qtx.TrashEvent(ctx, db.TrashEventParams{
+		ID:         eventID,
+		TimelineID: timelineID,
+	})
 	if err != nil {
 		eh.logger.Printf("Failed to delete event: %v", err)
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to delete event"})
 		return
 	}
+	if deleted == 0 {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"message": "Event not found"})
+		return
+	}
+
+	if err := recordRevision(ctx, qtx, timelineID, userID, revisionDeleteEvent, pgtype.Int4{}); err != nil {
+		eh.logger.Printf("Failed to record revision: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to delete event"})
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		eh.logger.Printf("Failed to commit transaction: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to delete event"})
+		return
+	}
 
-	events, err := eh.eventStore.GetEventsByTimelineId(r.Context(), timelineID)
+	events, err := eh.listTaggedEvents(r, timelineID)
 	if err != nil {
 		eh.logger.Printf("Failed to retrieve events: %v", err)
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to retrieve events"})
 		return
 	}
 
-	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"message": "Event deleted successfully", "events": events})
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"message": "Event moved to trash", "events": events})
+}
+
+func (eh *EventHandler) HandleReorderEvents(w http.ResponseWriter, r *http.Request) {
+	timeline, ok := authorizeTimeline(w, r, eh.eventStore, eh.logger, roleEditor)
+	if !ok {
+		return
+	}
+	timelineID := timeline.ID
+
+	userID, err := utils.ReadUserID(r)
+	if err != nil {
+		eh.logger.Printf("Invalid user ID format: %v", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Invalid user ID"})
+		return
+	}
+
+	var req reorderEventsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		eh.logger.Printf("Failed to decode request: %v", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Invalid request payload"})
+		return
+	}
+
+	if len(req.EventIDs) == 0 {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "No events provided"})
+		return
+	}
+
+	ctx := r.Context()
+
+	tx, err := eh.dbConn.Begin(ctx)
+	if err != nil {
+		eh.logger.Printf("Failed to begin transaction: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to reorder events"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := eh.eventStore.WithTx(tx)
+
+	if err := qtx.LockTimeline(ctx, timelineID); err != nil {
+		eh.logger.Printf("Failed to lock timeline: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to reorder events"})
+		return
+	}
+
+	// Locks the timeline's events until the transaction ends.
+	current, err := qtx.GetEventPositions(ctx, timelineID)
+	if err != nil {
+		eh.logger.Printf("Failed to retrieve event positions: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to reorder events"})
+		return
+	}
+
+	currentPositions := make(map[pgtype.UUID]string, len(current))
+	for _, e := range current {
+		currentPositions[e.ID] = e.Position
+	}
+
+	seen := make(map[pgtype.UUID]bool)
+	for i, id := range req.EventIDs {
+		if _, ok := currentPositions[id]; !ok {
+			utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Event not found in this timeline", "index": i})
+			return
+		}
+		if seen[id] {
+			utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Duplicate event ID", "index": i})
+			return
+		}
+		seen[id] = true
+	}
+
+	positions, err := planPositions(req.EventIDs, current)
+	if err != nil {
+		eh.logger.Printf("Failed to plan event positions: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to reorder events"})
+		return
+	}
+
+	var positionParams []db.UpdateEventPositionsParams
+	for i, id := range req.EventIDs {
+		if positions[i] != currentPositions[id] {
+			positionParams = append(positionParams, db.UpdateEventPositionsParams{
+				ID:         id,
+				Position:   positions[i],
+				TimelineID: timelineID,
+			})
+		}
+	}
+
+	if len(positionParams) > 0 {
+		var batchErr error
+		qtx.UpdateEventPositions(ctx, positionParams).Exec(func(i int, err error) {
+			if err != nil && batchErr == nil {
+				batchErr = err
+			}
+		})
+		if batchErr != nil {
+			eh.logger.Printf("Failed to update event position: %v", batchErr)
+			utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to reorder events"})
+			return
+		}
+	}
+
+	if err := recordRevision(ctx, qtx, timelineID, userID, revisionReorderEvents, pgtype.Int4{}); err != nil {
+		eh.logger.Printf("Failed to record revision: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to reorder events"})
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		eh.logger.Printf("Failed to commit transaction: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to reorder events"})
+		return
+	}
+
+	events, err := eh.eventStore.GetEventsByTimelineIdByPosition(ctx, timelineID)
+	if err != nil {
+		eh.logger.Printf("Failed to retrieve events: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to retrieve events"})
+		return
+	}
+	tagged, err := tagEvents(ctx, eh.eventStore, events)
+	if err != nil {
+		eh.logger.Printf("Failed to retrieve event tags: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to retrieve events"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"events": tagged})
 }
@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/nabsk911/chronify/internal/ai"
+	"github.com/nabsk911/chronify/internal/db"
+)
+
+func TestGetEventsFiltersByTheCallersTags(t *testing.T) {
+	tagID := pgtype.UUID{Bytes: [16]byte{0: 0x7a}, Valid: true}
+	for _, sort := range []string{"date", "position"} {
+		t.Run(sort, func(t *testing.T) {
+			fake := newFakeTimeline(t, existingEvents...)
+			// The tag filter's arguments, by query.
+			filters := make(map[string][]any)
+			page := func(name string, tags, user int) {
+				fake.On(name, func(args []any) (any, error) {
+					filters[name] = []any{args[tags], args[user]}
+					return fake.list(), nil
+				})
+			}
+			page("GetEventsPageByDate", 7, 8)
+			page("GetEventsPageByPosition", 4, 5)
+			fake.On("CountEventsByTimelineId", func(args []any) (any, error) {
+				filters["CountEventsByTimelineId"] = []any{args[1], args[2]}
+				return int64(len(existingEvents)), nil
+			})
+			eh := NewEventHandler(db.New(fake), fake, ai.NewFake(nil), ai.Limits{}, testLogger)
+
+			rec := httptest.NewRecorder()
+			eh.HandleGetEventsByTimelineId(rec, newTimelineRequest("GET", "/timelines/x/events?sort="+sort+"&tag="+tagID.String(), ""))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+			}
+
+			pageQuery := "GetEventsPageByDate"
+			if sort == "position" {
+				pageQuery = "GetEventsPageByPosition"
+			}
+			for _, name := range []string{pageQuery, "CountEventsByTimelineId"} {
+				filter, ok := filters[name]
+				if !ok {
+					t.Fatalf("%s didn't run", name)
+				}
+				if tags, _ := filter[0].([]pgtype.UUID); len(tags) != 1 || tags[0] != tagID {
+					t.Errorf("%s filtered by tags %v, want %v", name, filter[0], tagID)
+				}
+				if filter[1] != testUserID {
+					t.Errorf("%s matched the tags of %v, want the caller's", name, filter[1])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"mime"
+	"net/http"
+
+	"github.com/nabsk911/chronify/internal/db"
+	"github.com/nabsk911/chronify/internal/export"
+	"github.com/nabsk911/chronify/internal/utils"
+)
+
+// HandleExportTimeline downloads the timeline in the format given by
+// ?format=, which is one of json (the default), csv, markdown or ics.
+func (eh *EventHandler) HandleExportTimeline(w http.ResponseWriter, r *http.Request) {
+	timeline, ok := authorizeTimeline(w, r, eh.eventStore, eh.logger, roleViewer)
+	if !ok {
+		return
+	}
+
+	name := r.URL.Query().Get("format")
+	if name == "" {
+		name = "json"
+	}
+	format, ok := export.Formats[name]
+	if !ok {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Format must be json, csv, markdown or ics"})
+		return
+	}
+
+	tagged, err := eh.listTaggedEvents(r, timeline.ID)
+	if err != nil {
+		eh.logger.Printf("Failed to retrieve events: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to retrieve events"})
+		return
+	}
+
+	events := make([]export.Event, len(tagged))
+	for i, e := range tagged {
+		events[i] = export.Event{Event: e.Event, Tags: make([]export.Tag, len(e.Tags))}
+		for j, tag := range e.Tags {
+			events[i].Tags[j] = export.Tag{Name: tag.Name, Color: tag.Color}
+		}
+	}
+
+	filename := export.Filename(timeline.Title, format.Extension)
+	w.Header().Set("Content-Type", format.ContentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	w.WriteHeader(http.StatusOK)
+
+	err = format.Write(w, db.Timeline{
+		ID:          timeline.ID,
+		UserID:      timeline.UserID,
+		Title:       timeline.Title,
+		Description: timeline.Description,
+		CreatedAt:   timeline.CreatedAt,
+		UpdatedAt:   timeline.UpdatedAt,
+	}, events)
+	if err != nil {
+		eh.logger.Printf("Failed to export timeline: %v", err)
+	}
+}
@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/nabsk911/chronify/internal/db"
+	"github.com/nabsk911/chronify/internal/dbtest"
+)
+
+var (
+	testUserID     = pgtype.UUID{Bytes: [16]byte{0: 0xa1}, Valid: true}
+	testTimelineID = pgtype.UUID{Bytes: [16]byte{0: 0xf1}, Valid: true}
+	testUsageID    = pgtype.UUID{Bytes: [16]byte{0: 0xa5}, Valid: true}
+)
+
+var testLogger = log.New(io.Discard, "", 0)
+
+// fakeTimeline is a database holding one timeline, owned by testUserID, and
+// its events.
+type fakeTimeline struct {
+	*dbtest.DB
+
+	mu        sync.Mutex
+	events    []db.Event
+	trashed   []db.Event
+	revisions []string
+	nextID    byte
+}
+
+func newFakeTimeline(t *testing.T, events ...db.Event) *fakeTimeline {
+	t.Helper()
+	f := &fakeTimeline{DB: dbtest.New(), nextID: 0x10}
+	for _, e := range events {
+		f.add(e)
+	}
+
+	f.On("IsSessionActive", func(args []any) (any, error) { return true, nil })
+	f.On("GetTimeLineById", func(args []any) (any, error) {
+		if args[0] != testTimelineID || args[1] != testUserID {
+			return nil, nil
+		}
+		return db.GetTimeLineByIdRow{ID: testTimelineID, UserID: testUserID, Title: "Test timeline", Role: roleOwner}, nil
+	})
+	f.On("LockTimeline", func(args []any) (any, error) { return nil, nil })
+	f.On("GetAIUsageSince", func(args []any) (any, error) { return db.GetAIUsageSinceRow{}, nil })
+	f.On("LockAIUsage", func(args []any) (any, error) { return nil, nil })
+	f.On("ReserveAIUsage", func(args []any) (any, error) { return testUsageID, nil })
+	f.On("SettleAIUsage", func(args []any) (any, error) { return nil, nil })
+	f.On("CreateTimelineRevision", func(args []any) (any, error) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.revisions = append(f.revisions, args[2].(string))
+		return nil, nil
+	})
+	f.On("GetEventPositions", func(args []any) (any, error) {
+		var rows []db.GetEventPositionsRow
+		for _, e := range f.list() {
+			rows = append(rows, db.GetEventPositionsRow{ID: e.ID, Position: e.Position})
+		}
+		return rows, nil
+	})
+	f.On("GetEventsByTimelineId", func(args []any) (any, error) { return f.list(), nil })
+	f.On("GetEventTagsByEventIds", func(args []any) (any, error) { return nil, nil })
+	f.On("TrashEventsByTimelineId", func(args []any) (any, error) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.trashed = append(f.trashed, f.events...)
+		f.events = nil
+		return nil, nil
+	})
+	f.On("CreateEvent", func(args []any) (any, error) {
+		return f.add(db.Event{
+			TimelineID:       args[0].(pgtype.UUID),
+			Title:            args[1].(string),
+			CardTitle:        args[2].(string),
+			CardSubtitle:     args[3].(pgtype.Text),
+			CardDetailedText: args[4].(pgtype.Text),
+			StartYear:        args[5].(pgtype.Int8),
+			DatePrecision:    args[13].(pgtype.Text),
+			Position:         args[14].(string),
+		}), nil
+	})
+	f.OnCopyFrom("events", func(columns []string, rows [][]any) (int64, error) {
+		for _, row := range rows {
+			values := make(map[string]any, len(columns))
+			for i, column := range columns {
+				values[column] = row[i]
+			}
+			f.add(db.Event{
+				TimelineID:       values["timeline_id"].(pgtype.UUID),
+				Title:            values["title"].(string),
+				CardTitle:        values["card_title"].(string),
+				CardSubtitle:     values["card_subtitle"].(pgtype.Text),
+				CardDetailedText: values["card_detailed_text"].(pgtype.Text),
+				StartYear:        values["start_year"].(pgtype.Int8),
+				DatePrecision:    values["date_precision"].(pgtype.Text),
+				Position:         values["position"].(string),
+			})
+		}
+		return int64(len(rows)), nil
+	})
+	return f
+}
+
+// add saves the event, giving it an ID if it has none.
+func (f *fakeTimeline) add(e db.Event) db.Event {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !e.ID.Valid {
+		f.nextID++
+		e.ID = pgtype.UUID{Bytes: [16]byte{0: 0xe0, 15: f.nextID}, Valid: true}
+	}
+	e.TimelineID = testTimelineID
+	f.events = append(f.events, e)
+	return e
+}
+
+// list returns the events in position order.
+func (f *fakeTimeline) list() []db.Event {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	events := slices.Clone(f.events)
+	slices.SortFunc(events, func(a, b db.Event) int { return strings.Compare(a.Position, b.Position) })
+	return events
+}
+
+// titles returns the events' titles in position order.
+func (f *fakeTimeline) titles() []string {
+	var titles []string
+	for _, e := range f.list() {
+		titles = append(titles, e.Title)
+	}
+	return titles
+}
+
+// newTimelineRequest is a request from testUserID on the test timeline, as
+// the authentication middleware passes it on.
+func newTimelineRequest(method, target, body string) *http.Request {
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	r.SetPathValue("timelineId", testTimelineID.String())
+	return r.WithContext(context.WithValue(r.Context(), "userID", testUserID.String()))
+}
@@ -0,0 +1,347 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/nabsk911/chronify/internal/db"
+	"github.com/nabsk911/chronify/internal/fractional"
+	"github.com/nabsk911/chronify/internal/importer"
+	"github.com/nabsk911/chronify/internal/utils"
+)
+
+const (
+	maxImportBytes  = 10 << 20
+	maxImportEvents = 5000
+
+	// maxTimelineTitleLength is the length of timelines.title.
+	maxTimelineTitleLength = 255
+)
+
+type importRowError struct {
+	Row   int    `json:"row"`
+	Error string `json:"error"`
+}
+
+// HandleImportTimeline creates a timeline from an uploaded file. The
+// multipart form takes:
+//
+//   - file: a CSV, iCalendar or exported JSON file
+//   - format: csv, ics or json, if the file's extension doesn't say
+//   - mapping: for CSV, a JSON object naming the column each field is read from
+//   - title and description: override those read from the file
+//   - dry_run: validate the file without saving anything
+//
+// The tags on a JSON file's events become the user's own: a tag the user
+// already has by that name is reused, and the rest are created.
+func (eh *EventHandler) HandleImportTimeline(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.ReadUserID(r)
+	if err != nil {
+		eh.logger.Printf("Invalid user ID format: %v", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Invalid user ID"})
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportBytes)
+	if err := r.ParseMultipartForm(1 << 20); err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			utils.WriteJSON(w, http.StatusRequestEntityTooLarge, utils.Envelope{"message": fmt.Sprintf("File must be smaller than %d MB", maxImportBytes>>20)})
+			return
+		}
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Invalid multipart form"})
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "File is required"})
+		return
+	}
+	defer file.Close()
+
+	format := r.FormValue("format")
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(header.Filename)), ".")
+	}
+
+	var mapping map[string]string
+	if m := r.FormValue("mapping"); m != "" {
+		if err := json.Unmarshal([]byte(m), &mapping); err != nil {
+			utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Mapping must be a JSON object of field names to column names"})
+			return
+		}
+	}
+
+	dryRun := false
+	if v := r.FormValue("dry_run"); v != "" {
+		dryRun, err = strconv.ParseBool(v)
+		if err != nil {
+			utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "dry_run must be true or false"})
+			return
+		}
+	}
+
+	var doc *importer.Document
+	switch format {
+	case "csv":
+		doc, err = importer.ReadCSV(file, mapping, maxImportEvents)
+	case "ics", "ical":
+		doc, err = importer.ReadICS(file, maxImportEvents)
+	case "json":
+		doc, err = importer.ReadJSON(file, maxImportEvents)
+	default:
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Format must be csv, ics or json"})
+		return
+	}
+	if errors.Is(err, importer.ErrTooManyRows) {
+		utils.WriteJSON(w, http.StatusRequestEntityTooLarge, utils.Envelope{"message": fmt.Sprintf("Files may hold at most %d events", maxImportEvents)})
+		return
+	}
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Failed to read file: " + err.Error()})
+		return
+	}
+
+	title := r.FormValue("title")
+	if title == "" {
+		title = doc.Title
+	}
+	if title == "" {
+		title = strings.TrimSuffix(filepath.Base(header.Filename), filepath.Ext(header.Filename))
+	}
+	if utf8.RuneCountInString(title) > maxTimelineTitleLength {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": fmt.Sprintf("Title must be at most %d characters; pass a shorter one as title", maxTimelineTitleLength)})
+		return
+	}
+	description := r.FormValue("description")
+	if description == "" {
+		description = doc.Description
+	}
+
+	// Validate every row before writing anything.
+	rowErrors := []importRowError{}
+	createParams := make([]db.BulkCreateEventsParams, 0, len(doc.Rows))
+	// eventTags[i] are the tags of createParams[i].
+	eventTags := make([][]tagRequest, 0, len(doc.Rows))
+	for _, row := range doc.Rows {
+		if row.Err != nil {
+			rowErrors = append(rowErrors, importRowError{Row: row.Row, Error: row.Err.Error()})
+			continue
+		}
+
+		e := row.Event
+		if e.CardTitle == "" {
+			rowErrors = append(rowErrors, importRowError{Row: row.Row, Error: "card_title is required"})
+			continue
+		}
+
+		dates, err := eventDates{
+			StartYear:        e.StartYear,
+			StartMonth:       e.StartMonth,
+			StartDay:         e.StartDay,
+			StartMinuteOfDay: e.StartMinuteOfDay,
+			EndYear:          e.EndYear,
+			EndMonth:         e.EndMonth,
+			EndDay:           e.EndDay,
+			EndMinuteOfDay:   e.EndMinuteOfDay,
+			DatePrecision:    e.DatePrecision,
+		}.normalize()
+		if err != nil {
+			rowErrors = append(rowErrors, importRowError{Row: row.Row, Error: err.Error()})
+			continue
+		}
+
+		tags := make([]tagRequest, len(e.Tags))
+		for i, t := range e.Tags {
+			tags[i] = tagRequest{Name: t.Name, Color: t.Color}
+			if err = tags[i].normalize(); err != nil {
+				break
+			}
+		}
+		if err != nil {
+			rowErrors = append(rowErrors, importRowError{Row: row.Row, Error: "tag " + err.Error()})
+			continue
+		}
+		eventTags = append(eventTags, tags)
+
+		createParams = append(createParams, db.BulkCreateEventsParams{
+			Title:            e.Title,
+			CardTitle:        e.CardTitle,
+			CardSubtitle:     e.CardSubtitle,
+			CardDetailedText: e.CardDetailedText,
+			StartYear:        dates.StartYear,
+			StartMonth:       dates.StartMonth,
+			StartDay:         dates.StartDay,
+			StartMinuteOfDay: dates.StartMinuteOfDay,
+			EndYear:          dates.EndYear,
+			EndMonth:         dates.EndMonth,
+			EndDay:           dates.EndDay,
+			EndMinuteOfDay:   dates.EndMinuteOfDay,
+			DatePrecision:    dates.DatePrecision,
+		})
+	}
+
+	if dryRun {
+		// The import itself relies on the unique index, but a dry run
+		// doesn't insert anything, so it looks for the title instead.
+		exists, err := eh.eventStore.TimelineTitleExists(r.Context(), db.TimelineTitleExistsParams{UserID: userID, Title: title})
+		if err != nil {
+			eh.logger.Printf("Failed to check timeline title: %v", err)
+			utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to import timeline"})
+			return
+		}
+		if exists {
+			utils.WriteJSON(w, http.StatusConflict, utils.Envelope{"message": "You already have a timeline with this title"})
+			return
+		}
+
+		utils.WriteJSON(w, http.StatusOK, utils.Envelope{
+			"valid":  len(rowErrors) == 0,
+			"title":  title,
+			"events": len(createParams),
+			"errors": rowErrors,
+		})
+		return
+	}
+
+	if len(rowErrors) > 0 {
+		utils.WriteJSON(w, http.StatusUnprocessableEntity, utils.Envelope{"message": "Nothing was imported", "errors": rowErrors})
+		return
+	}
+
+	ctx := r.Context()
+
+	tx, err := eh.dbConn.Begin(ctx)
+	if err != nil {
+		eh.logger.Printf("Failed to begin transaction: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to import timeline"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := eh.eventStore.WithTx(tx)
+
+	timeline, err := qtx.CreateTimeline(ctx, db.CreateTimelineParams{
+		UserID:      userID,
+		Title:       title,
+		Description: pgtype.Text{String: description, Valid: true},
+	})
+	if err != nil {
+		eh.logger.Printf("Failed to create timeline: %v", err)
+		if isTitleConflict(err) {
+			utils.WriteJSON(w, http.StatusConflict, utils.Envelope{"message": "You already have a timeline with this title"})
+			return
+		}
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to import timeline"})
+		return
+	}
+
+	if len(createParams) > 0 {
+		positions, err := fractional.NKeysBetween("", "", len(createParams))
+		if err != nil {
+			eh.logger.Printf("Failed to plan event positions: %v", err)
+			utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to import timeline"})
+			return
+		}
+		for i := range createParams {
+			createParams[i].TimelineID = timeline.ID
+			createParams[i].Position = positions[i]
+		}
+
+		if _, err := qtx.BulkCreateEvents(ctx, createParams); err != nil {
+			eh.logger.Printf("Failed to create events: %v", err)
+			utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to import timeline"})
+			return
+		}
+
+		if err := importTags(ctx, qtx, userID, timeline.ID, positions, eventTags); err != nil {
+			eh.logger.Printf("Failed to import tags: %v", err)
+			utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to import timeline"})
+			return
+		}
+	}
+
+	if err := recordRevision(ctx, qtx, timeline.ID, userID, revisionImport, pgtype.Int4{}); err != nil {
+		eh.logger.Printf("Failed to record revision: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to import timeline"})
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		eh.logger.Printf("Failed to commit transaction: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to import timeline"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, utils.Envelope{"data": timeline, "events": len(createParams), "message": "Timeline imported successfully"})
+}
+
+// importTags tags the imported events with the user's tags of the same names,
+// creating those the user doesn't have yet. Events are found by their
+// position: positions[i] is that of the event tagged tags[i].
+func importTags(ctx context.Context, qtx *db.Queries, userID, timelineID pgtype.UUID, positions []string, tags [][]tagRequest) error {
+	var names, colors []string
+	seen := make(map[string]bool)
+	for _, eventTags := range tags {
+		for _, t := range eventTags {
+			if key := strings.ToLower(t.Name); !seen[key] {
+				seen[key] = true
+				names = append(names, t.Name)
+				colors = append(colors, t.Color)
+			}
+		}
+	}
+	if len(names) == 0 {
+		return nil
+	}
+
+	if err := qtx.CreateTags(ctx, db.CreateTagsParams{
+		UserID: userID,
+		Names:  names,
+		Colors: colors,
+	}); err != nil {
+		return err
+	}
+	userTags, err := qtx.GetTagsByNames(ctx, db.GetTagsByNamesParams{
+		UserID: userID,
+		Names:  names,
+	})
+	if err != nil {
+		return err
+	}
+	tagIDs := make(map[string]pgtype.UUID, len(userTags))
+	for _, t := range userTags {
+		tagIDs[strings.ToLower(t.Name)] = t.ID
+	}
+
+	events, err := qtx.GetEventPositions(ctx, timelineID)
+	if err != nil {
+		return err
+	}
+	eventIDs := make(map[string]pgtype.UUID, len(events))
+	for _, e := range events {
+		eventIDs[e.Position] = e.ID
+	}
+
+	var pairEvents, pairTags []pgtype.UUID
+	for i, eventTags := range tags {
+		for _, t := range eventTags {
+			pairEvents = append(pairEvents, eventIDs[positions[i]])
+			pairTags = append(pairTags, tagIDs[strings.ToLower(t.Name)])
+		}
+	}
+	return qtx.AddEventTags(ctx, db.AddEventTagsParams{
+		EventIds: pairEvents,
+		TagIds:   pairTags,
+	})
+}
@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/nabsk911/chronify/internal/ai"
+	"github.com/nabsk911/chronify/internal/db"
+)
+
+const testImportCSV = "title,card_title,start\n1969,Moon landing,1969-07-20\n"
+
+// newImportRequest uploads content as filename with the form values given.
+func newImportRequest(t *testing.T, filename, content string, values map[string]string) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fw, err := mw.CreateFormFile("file", filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fw.Write([]byte(content))
+	for k, v := range values {
+		mw.WriteField(k, v)
+	}
+	mw.Close()
+
+	r := newTimelineRequest("POST", "/timelines/import", body.String())
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+	return r
+}
+
+// newImportHandler returns a handler whose user already has a timeline
+// called taken.
+func newImportHandler(t *testing.T, taken string) (*EventHandler, *fakeTimeline) {
+	fake := newFakeTimeline(t)
+	fake.On("TimelineTitleExists", func(args []any) (any, error) {
+		return strings.EqualFold(args[1].(string), taken), nil
+	})
+	fake.On("CreateTimeline", func(args []any) (any, error) {
+		if strings.EqualFold(args[1].(string), taken) {
+			return nil, &pgconn.PgError{Code: "23505", ConstraintName: "timelines_user_id_title_key"}
+		}
+		return db.CreateTimelineRow{ID: testTimelineID, UserID: args[0].(pgtype.UUID), Title: args[1].(string)}, nil
+	})
+	return NewEventHandler(db.New(fake), fake, ai.NewFake(nil), ai.Limits{}, testLogger), fake
+}
+
+func TestImportTimelineTitle(t *testing.T) {
+	long := strings.Repeat("x", maxTimelineTitleLength+1)
+	tests := []struct {
+		name     string
+		filename string
+		values   map[string]string
+		want     int
+	}{
+		{"dry run", "history.csv", map[string]string{"dry_run": "true"}, http.StatusOK},
+		{"dry run with a taken title", "Taken.csv", map[string]string{"dry_run": "true"}, http.StatusConflict},
+		{"dry run with a taken title ignoring case", "history.csv", map[string]string{"dry_run": "true", "title": "TAKEN"}, http.StatusConflict},
+		{"import", "history.csv", nil, http.StatusCreated},
+		{"import with a taken title", "history.csv", map[string]string{"title": "taken"}, http.StatusConflict},
+		{"long filename", long + ".csv", nil, http.StatusBadRequest},
+		{"long filename on a dry run", long + ".csv", map[string]string{"dry_run": "true"}, http.StatusBadRequest},
+		{"long filename with a title", long + ".csv", map[string]string{"title": "History"}, http.StatusCreated},
+		{"long title", "history.csv", map[string]string{"title": long}, http.StatusBadRequest},
+		{"longest title", "history.csv", map[string]string{"title": long[1:]}, http.StatusCreated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			eh, fake := newImportHandler(t, "taken")
+
+			rec := httptest.NewRecorder()
+			eh.HandleImportTimeline(rec, newImportRequest(t, tt.filename, testImportCSV, tt.values))
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+			if rec.Code != http.StatusCreated && fake.Commits() != 0 {
+				t.Error("the import was committed")
+			}
+			if rec.Code == http.StatusBadRequest && len(fake.Calls()) != 0 {
+				t.Errorf("queried the database: %v", fake.Calls())
+			}
+		})
+	}
+}
+
+func TestImportJSONTags(t *testing.T) {
+	// Apollo is the user's already, under another case; Space isn't.
+	apollo := pgtype.UUID{Bytes: [16]byte{0: 0x7a, 15: 1}, Valid: true}
+	space := pgtype.UUID{Bytes: [16]byte{0: 0x7a, 15: 2}, Valid: true}
+	doc := `{"version": 2, "timeline": {"title": "Space race"}, "events": [
+		{"card_title": "Sputnik", "start_year": 1957, "position": "a", "tags": [{"name": "Space", "color": "#00FF00"}]},
+		{"card_title": "Moon landing", "start_year": 1969, "position": "b", "tags": [{"name": "apollo", "color": "#3366ff"}, {"name": "space"}]}
+	]}`
+
+	eh, fake := newImportHandler(t, "taken")
+	var created []string
+	fake.On("CreateTags", func(args []any) (any, error) {
+		if args[0] != testUserID {
+			t.Errorf("tags created for %v", args[0])
+		}
+		for i, name := range args[1].([]string) {
+			created = append(created, name+" "+args[2].([]string)[i])
+		}
+		return nil, nil
+	})
+	fake.On("GetTagsByNames", func(args []any) (any, error) {
+		return []db.GetTagsByNamesRow{{ID: apollo, Name: "Apollo"}, {ID: space, Name: "Space"}}, nil
+	})
+	var tagged []string
+	fake.On("AddEventTags", func(args []any) (any, error) {
+		events, tags := args[0].([]pgtype.UUID), args[1].([]pgtype.UUID)
+		for i := range events {
+			for _, e := range fake.list() {
+				if e.ID == events[i] {
+					tagged = append(tagged, fmt.Sprintf("%s %x", e.CardTitle, tags[i].Bytes[15]))
+				}
+			}
+		}
+		return nil, nil
+	})
+
+	rec := httptest.NewRecorder()
+	eh.HandleImportTimeline(rec, newImportRequest(t, "space.json", doc, nil))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want 201: %s", rec.Code, rec.Body)
+	}
+
+	if want := []string{"Space #00ff00", "apollo #3366ff"}; !slices.Equal(created, want) {
+		t.Errorf("created tags %q, want %q: each name once", created, want)
+	}
+	if want := []string{"Sputnik 2", "Moon landing 1", "Moon landing 2"}; !slices.Equal(tagged, want) {
+		t.Errorf("tagged %q, want %q", tagged, want)
+	}
+	if fake.Commits() != 1 {
+		t.Errorf("%d commits, want the tags saved with the import", fake.Commits())
+	}
+}
+
+func TestImportJSONInvalidTag(t *testing.T) {
+	doc := `{"version": 2, "timeline": {"title": "Space race"}, "events": [
+		{"card_title": "Sputnik", "start_year": 1957, "position": "a", "tags": [{"name": " "}]}
+	]}`
+	eh, fake := newImportHandler(t, "taken")
+
+	rec := httptest.NewRecorder()
+	eh.HandleImportTimeline(rec, newImportRequest(t, "space.json", doc, nil))
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("status = %d, want 422: %s", rec.Code, rec.Body)
+	}
+	if fake.Called("CreateTags") != 0 || fake.Commits() != 0 {
+		t.Error("the import was saved")
+	}
+}
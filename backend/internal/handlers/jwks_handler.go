@@ -0,0 +1,24 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/nabsk911/chronify/internal/auth"
+	"github.com/nabsk911/chronify/internal/utils"
+)
+
+// JWKSHandler publishes the public keys access tokens are signed with.
+type JWKSHandler struct {
+	keys *auth.KeySet
+}
+
+func NewJWKSHandler(keys *auth.KeySet) *JWKSHandler {
+	return &JWKSHandler{keys: keys}
+}
+
+// HandleGetJWKS lets the response be cached briefly, so a key added for a
+// rotation reaches other services well before it starts signing.
+func (jh *JWKSHandler) HandleGetJWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	utils.WriteJSON(w, http.StatusOK, jh.keys.JWKS())
+}
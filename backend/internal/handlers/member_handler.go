@@ -0,0 +1,219 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/nabsk911/chronify/internal/db"
+	"github.com/nabsk911/chronify/internal/utils"
+)
+
+type memberRequest struct {
+	Username string `json:"username,omitempty"`
+	Email    string `json:"email,omitempty"`
+	Role     string `json:"role"`
+}
+
+// timelineMember is a member as other members see them. Only the owner, who
+// invites members by email, sees their email addresses.
+type timelineMember struct {
+	UserID    pgtype.UUID        `json:"user_id"`
+	Username  string             `json:"username"`
+	Role      string             `json:"role"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+}
+
+func (th *TimelineHandler) HandleGetTimelineMembers(w http.ResponseWriter, r *http.Request) {
+	timeline, ok := authorizeTimeline(w, r, th.timelineStore, th.logger, roleViewer)
+	if !ok {
+		return
+	}
+
+	members, err := th.timelineStore.GetTimelineMembers(r.Context(), timeline.ID)
+	if err != nil {
+		th.logger.Printf("Failed to retrieve members: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to retrieve members"})
+		return
+	}
+	if hasRole(timeline.Role, roleOwner) {
+		utils.WriteJSON(w, http.StatusOK, utils.Envelope{"data": members})
+		return
+	}
+
+	visible := make([]timelineMember, len(members))
+	for i, member := range members {
+		visible[i] = timelineMember{
+			UserID:    member.UserID,
+			Username:  member.Username,
+			Role:      member.Role,
+			CreatedAt: member.CreatedAt,
+		}
+	}
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"data": visible})
+}
+
+// Invite by username or email
+func (th *TimelineHandler) HandleAddTimelineMember(w http.ResponseWriter, r *http.Request) {
+	timeline, ok := authorizeTimeline(w, r, th.timelineStore, th.logger, roleOwner)
+	if !ok {
+		return
+	}
+
+	var req memberRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		th.logger.Printf("Failed to decode member request: %v", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Invalid request payload!"})
+		return
+	}
+
+	identifier := req.Username
+	if identifier == "" {
+		identifier = req.Email
+	}
+	if identifier == "" {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Username or email is required"})
+		return
+	}
+
+	if req.Role == "" {
+		req.Role = roleViewer
+	}
+	if !isValidRole(req.Role) {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Role must be one of viewer, editor or owner"})
+		return
+	}
+
+	user, err := th.timelineStore.GetUserByUsernameOrEmail(r.Context(), identifier)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"message": "User not found"})
+			return
+		}
+		th.logger.Printf("Failed to retrieve user %s: %v", identifier, err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to add member"})
+		return
+	}
+
+	member, err := th.timelineStore.AddTimelineMember(r.Context(), db.AddTimelineMemberParams{
+		TimelineID: timeline.ID,
+		UserID:     user.ID,
+		Role:       req.Role,
+	})
+	if err != nil {
+		th.logger.Printf("Failed to add member: %v", err)
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			if pgErr.Code == "23505" {
+				switch pgErr.ConstraintName {
+				case "timeline_members_pkey":
+					utils.WriteJSON(w, http.StatusConflict, utils.Envelope{"message": "User is already a member of this timeline"})
+					return
+				}
+			}
+		}
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to add member"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, utils.Envelope{"data": member, "message": "Member added successfully"})
+}
+
+func (th *TimelineHandler) HandleUpdateTimelineMember(w http.ResponseWriter, r *http.Request) {
+	timeline, ok := authorizeTimeline(w, r, th.timelineStore, th.logger, roleOwner)
+	if !ok {
+		return
+	}
+
+	memberID, err := utils.ReadIDParam(r, "userId")
+	if err != nil {
+		th.logger.Printf("Invalid user ID: %v", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Invalid user ID"})
+		return
+	}
+
+	var req memberRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		th.logger.Printf("Failed to decode member request: %v", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Invalid request payload!"})
+		return
+	}
+
+	if !isValidRole(req.Role) {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Role must be one of viewer, editor or owner"})
+		return
+	}
+
+	if memberID == timeline.UserID && req.Role != roleOwner {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "The timeline creator must remain an owner"})
+		return
+	}
+
+	member, err := th.timelineStore.UpdateTimelineMemberRole(r.Context(), db.UpdateTimelineMemberRoleParams{
+		TimelineID: timeline.ID,
+		UserID:     memberID,
+		Role:       req.Role,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"message": "Member not found"})
+			return
+		}
+		th.logger.Printf("Failed to update member: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to update member"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"data": member})
+}
+
+// Owners can remove anyone but the creator; other members can only leave.
+func (th *TimelineHandler) HandleRemoveTimelineMember(w http.ResponseWriter, r *http.Request) {
+	timeline, ok := authorizeTimeline(w, r, th.timelineStore, th.logger, roleViewer)
+	if !ok {
+		return
+	}
+
+	userID, err := utils.ReadUserID(r)
+	if err != nil {
+		th.logger.Printf("Invalid user ID format: %v", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Invalid user ID"})
+		return
+	}
+
+	memberID, err := utils.ReadIDParam(r, "userId")
+	if err != nil {
+		th.logger.Printf("Invalid user ID: %v", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Invalid user ID"})
+		return
+	}
+
+	if memberID != userID && !hasRole(timeline.Role, roleOwner) {
+		utils.WriteJSON(w, http.StatusForbidden, utils.Envelope{"message": "You don't have permission to do this"})
+		return
+	}
+
+	if memberID == timeline.UserID {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "The timeline creator can't be removed"})
+		return
+	}
+
+	removed, err := th.timelineStore.RemoveTimelineMember(r.Context(), db.RemoveTimelineMemberParams{
+		TimelineID: timeline.ID,
+		UserID:     memberID,
+	})
+	if err != nil {
+		th.logger.Printf("Failed to remove member: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to remove member"})
+		return
+	}
+	if removed == 0 {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"message": "Member not found"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"message": "Member removed successfully"})
+}
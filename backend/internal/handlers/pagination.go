@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/nabsk911/chronify/internal/utils"
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 200
+)
+
+// page is what a listing was asked for with ?limit=, ?cursor=, ?sort= and
+// ?count=false, which skips counting the total.
+type page struct {
+	Limit int
+	Sort  string
+	Count bool
+	// after is the sort key of the last row of the previous page, or nil on
+	// the first page.
+	after json.RawMessage
+}
+
+// cursor is what next_cursor encodes. It is tied to a sort order, because
+// the key means nothing in another one.
+type cursor struct {
+	Sort string          `json:"s"`
+	Key  json.RawMessage `json:"k"`
+}
+
+// readPage reads the paging parameters. sorts lists the sort orders the
+// listing accepts; the first is the default.
+func readPage(r *http.Request, sorts ...string) (page, error) {
+	query := r.URL.Query()
+	p := page{Limit: defaultPageSize, Sort: sorts[0], Count: true}
+
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxPageSize {
+			return p, fmt.Errorf("limit must be between 1 and %d", maxPageSize)
+		}
+		p.Limit = n
+	}
+
+	if v := query.Get("sort"); v != "" {
+		if !slices.Contains(sorts, v) {
+			return p, fmt.Errorf("sort must be one of %s", strings.Join(sorts, ", "))
+		}
+		p.Sort = v
+	}
+
+	if v := query.Get("count"); v != "" {
+		count, err := strconv.ParseBool(v)
+		if err != nil {
+			return p, errors.New("count must be true or false")
+		}
+		p.Count = count
+	}
+
+	if v := query.Get("cursor"); v != "" {
+		var c cursor
+		raw, err := base64.RawURLEncoding.DecodeString(v)
+		if err != nil || json.Unmarshal(raw, &c) != nil || len(c.Key) == 0 {
+			return p, errors.New("invalid cursor")
+		}
+		if c.Sort != p.Sort {
+			return p, errors.New("cursor is for a different sort order")
+		}
+		p.after = c.Key
+	}
+
+	return p, nil
+}
+
+// readAfter decodes the key of the cursor into key. It leaves key alone on
+// the first page.
+func (p page) readAfter(key any) error {
+	if p.after == nil {
+		return nil
+	}
+	if err := json.Unmarshal(p.after, key); err != nil {
+		return errors.New("invalid cursor")
+	}
+	return nil
+}
+
+// pageEnvelope builds the response for a page. rows holds up to Limit+1
+// rows; the extra one only tells that there is a next page, which starts
+// after key of the last row shown.
+func pageEnvelope[T any](p page, name string, rows []T, key func(T) any, total *int64) utils.Envelope {
+	var next any
+	if len(rows) > p.Limit {
+		rows = rows[:p.Limit]
+		k, _ := json.Marshal(key(rows[len(rows)-1]))
+		c, _ := json.Marshal(cursor{Sort: p.Sort, Key: k})
+		next = base64.RawURLEncoding.EncodeToString(c)
+	}
+	if rows == nil {
+		rows = []T{}
+	}
+
+	env := utils.Envelope{name: rows, "next_cursor": next}
+	if total != nil {
+		env["total"] = *total
+	}
+	return env
+}
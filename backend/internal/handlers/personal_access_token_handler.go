@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/nabsk911/chronify/internal/auth"
+	"github.com/nabsk911/chronify/internal/db"
+	"github.com/nabsk911/chronify/internal/utils"
+)
+
+const maxTokenNameLength = 100
+
+type personalAccessTokenRequest struct {
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// normalize trims the name and drops repeated scopes, then checks the
+// request.
+func (req *personalAccessTokenRequest) normalize() error {
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		return errors.New("name is required")
+	}
+	if utf8.RuneCountInString(req.Name) > maxTokenNameLength {
+		return fmt.Errorf("name must be at most %d characters", maxTokenNameLength)
+	}
+
+	if len(req.Scopes) == 0 {
+		return errors.New("at least one scope is required")
+	}
+	for _, scope := range req.Scopes {
+		if !auth.IsValidScope(scope) {
+			return fmt.Errorf("unknown scope %q, expected one of %s", scope, strings.Join(auth.Scopes, ", "))
+		}
+	}
+	slices.Sort(req.Scopes)
+	req.Scopes = slices.Compact(req.Scopes)
+
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return errors.New("expiry must be in the future")
+	}
+	return nil
+}
+
+func (uh *UserHandler) HandleGetPersonalAccessTokens(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.ReadUserID(r)
+	if err != nil {
+		uh.logger.Printf("Invalid user ID format: %v", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Invalid user ID"})
+		return
+	}
+
+	tokens, err := uh.userStore.GetPersonalAccessTokensByUserId(r.Context(), userID)
+	if err != nil {
+		uh.logger.Printf("Failed to retrieve personal access tokens: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to retrieve personal access tokens"})
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"data": tokens})
+}
+
+// HandleCreatePersonalAccessToken returns the token once; only its hash is
+// stored.
+func (uh *UserHandler) HandleCreatePersonalAccessToken(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.ReadUserID(r)
+	if err != nil {
+		uh.logger.Printf("Invalid user ID format: %v", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Invalid user ID"})
+		return
+	}
+
+	var req personalAccessTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		uh.logger.Printf("Failed to decode request: %v", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Invalid request payload"})
+		return
+	}
+	if err := req.normalize(); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Invalid personal access token: " + err.Error()})
+		return
+	}
+
+	var expiresAt pgtype.Timestamptz
+	if req.ExpiresAt != nil {
+		expiresAt = pgtype.Timestamptz{Time: *req.ExpiresAt, Valid: true}
+	}
+
+	token, tokenHash, err := auth.GeneratePersonalAccessToken()
+	if err != nil {
+		uh.logger.Printf("Failed to generate personal access token: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to create personal access token"})
+		return
+	}
+
+	created, err := uh.userStore.CreatePersonalAccessToken(r.Context(), db.CreatePersonalAccessTokenParams{
+		UserID:    userID,
+		Name:      req.Name,
+		TokenHash: tokenHash,
+		Scopes:    req.Scopes,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		uh.logger.Printf("Failed to create personal access token: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to create personal access token"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, utils.Envelope{
+		"data":    created,
+		"token":   token,
+		"message": "Personal access token created successfully",
+	})
+}
+
+func (uh *UserHandler) HandleRevokePersonalAccessToken(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.ReadUserID(r)
+	if err != nil {
+		uh.logger.Printf("Invalid user ID format: %v", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Invalid user ID"})
+		return
+	}
+
+	tokenID, err := utils.ReadIDParam(r, "tokenId")
+	if err != nil {
+		uh.logger.Printf("Invalid personal access token ID: %v", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Invalid personal access token ID"})
+		return
+	}
+
+	revoked, err := uh.userStore.RevokePersonalAccessToken(r.Context(), db.RevokePersonalAccessTokenParams{
+		ID:     tokenID,
+		UserID: userID,
+	})
+	if err != nil {
+		uh.logger.Printf("Failed to revoke personal access token: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to revoke personal access token"})
+		return
+	}
+	if revoked == 0 {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"message": "Personal access token not found"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"message": "Personal access token revoked successfully"})
+}
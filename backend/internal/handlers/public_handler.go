@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/nabsk911/chronify/internal/auth"
+	"github.com/nabsk911/chronify/internal/db"
+	"github.com/nabsk911/chronify/internal/ratelimit"
+	"github.com/nabsk911/chronify/internal/utils"
+)
+
+// publicTimeline and publicEvent leave out IDs, owners and positions, which
+// mean nothing to someone without an account.
+type publicTimeline struct {
+	Title       string             `json:"title"`
+	Description pgtype.Text        `json:"description"`
+	CreatedAt   pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt   pgtype.Timestamptz `json:"updated_at"`
+}
+
+type publicEvent struct {
+	Title            string      `json:"title"`
+	CardTitle        string      `json:"card_title"`
+	CardSubtitle     pgtype.Text `json:"card_subtitle"`
+	CardDetailedText pgtype.Text `json:"card_detailed_text"`
+	eventDates
+}
+
+type PublicHandler struct {
+	store *db.Queries
+	// passwordLimiter slows down guessing the password of a share link.
+	passwordLimiter *ratelimit.Limiter
+	logger          *log.Logger
+}
+
+func NewPublicHandler(store *db.Queries, logger *log.Logger) *PublicHandler {
+	return &PublicHandler{
+		store:           store,
+		passwordLimiter: ratelimit.New(10, 5),
+		logger:          logger,
+	}
+}
+
+// HandleGetSharedTimeline serves a timeline through a share link. Links
+// with a password need it in the X-Share-Password header.
+func (ph *PublicHandler) HandleGetSharedTimeline(w http.ResponseWriter, r *http.Request) {
+	token := r.PathValue("shareToken")
+
+	link, err := ph.store.GetActiveShareLinkByTokenHash(r.Context(), auth.HashShareToken(token))
+	if errors.Is(err, pgx.ErrNoRows) {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"message": "Share link not found"})
+		return
+	}
+	if err != nil {
+		ph.logger.Printf("Failed to retrieve share link: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to retrieve timeline"})
+		return
+	}
+
+	if link.PasswordHash.Valid {
+		password := r.Header.Get("X-Share-Password")
+		if password == "" {
+			utils.WriteJSON(w, http.StatusUnauthorized, utils.Envelope{"message": "Password required"})
+			return
+		}
+		if ok, wait := ph.passwordLimiter.Allow(link.ID.String()); !ok {
+			writeTooManyRequests(w, wait, "Too many password attempts")
+			return
+		}
+		matches, err := auth.CheckPasswordHash(password, link.PasswordHash.String)
+		if err != nil {
+			ph.logger.Printf("Failed to check share link password: %v", err)
+			utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to retrieve timeline"})
+			return
+		}
+		if !matches {
+			utils.WriteJSON(w, http.StatusUnauthorized, utils.Envelope{"message": "Incorrect password"})
+			return
+		}
+	}
+
+	timeline, err := ph.store.GetSharedTimeline(r.Context(), link.TimelineID)
+	if err != nil {
+		ph.logger.Printf("Failed to retrieve shared timeline: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to retrieve timeline"})
+		return
+	}
+
+	var events []db.Event
+	if r.URL.Query().Get("order") == "position" {
+		events, err = ph.store.GetEventsByTimelineIdByPosition(r.Context(), link.TimelineID)
+	} else {
+		events, err = ph.store.GetEventsByTimelineId(r.Context(), link.TimelineID)
+	}
+	if err != nil {
+		ph.logger.Printf("Failed to retrieve events: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to retrieve events"})
+		return
+	}
+
+	publicEvents := make([]publicEvent, len(events))
+	for i, e := range events {
+		publicEvents[i] = publicEvent{
+			Title:            e.Title,
+			CardTitle:        e.CardTitle,
+			CardSubtitle:     e.CardSubtitle,
+			CardDetailedText: e.CardDetailedText,
+			eventDates: eventDates{
+				StartYear:        e.StartYear,
+				StartMonth:       e.StartMonth,
+				StartDay:         e.StartDay,
+				StartMinuteOfDay: e.StartMinuteOfDay,
+				EndYear:          e.EndYear,
+				EndMonth:         e.EndMonth,
+				EndDay:           e.EndDay,
+				EndMinuteOfDay:   e.EndMinuteOfDay,
+				DatePrecision:    e.DatePrecision,
+			},
+		}
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{
+		"data": publicTimeline{
+			Title:       timeline.Title,
+			Description: timeline.Description,
+			CreatedAt:   timeline.CreatedAt,
+			UpdatedAt:   timeline.UpdatedAt,
+		},
+		"events": publicEvents,
+	})
+}
@@ -0,0 +1,334 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/nabsk911/chronify/internal/db"
+	"github.com/nabsk911/chronify/internal/utils"
+)
+
+// What a revision was made by.
+const (
+	revisionInitial         = "initial"
+	revisionCreate          = "create"
+	revisionUpdate          = "update"
+	revisionUpsertEvents    = "upsert_events"
+	revisionDeleteEvent     = "delete_event"
+	revisionReorderEvents   = "reorder_events"
+	revisionAIEvents        = "ai_events"
+	revisionImport          = "import"
+	revisionRestore         = "restore"
+	revisionRestoreEvent    = "restore_event"
+	revisionRestoreTimeline = "restore_timeline"
+	revisionTrashTimeline   = "trash_timeline"
+)
+
+// timelineSnapshot is the timeline_snapshot() a revision stores.
+type timelineSnapshot struct {
+	Title       string          `json:"title"`
+	Description pgtype.Text     `json:"description"`
+	Events      []snapshotEvent `json:"events"`
+}
+
+// snapshotEvent is an event in a snapshot. Revisions made before events had
+// tags have no tag_ids.
+type snapshotEvent struct {
+	db.Event
+	TagIDs []pgtype.UUID `json:"tag_ids"`
+}
+
+// recordRevision saves the timeline as q sees it as a new revision. Call it
+// at the end of the transaction that made the change, which must hold
+// LockTimeline, so the change and its revision are saved together.
+func recordRevision(ctx context.Context, q *db.Queries, timelineID, userID pgtype.UUID, action string, restoredFrom pgtype.Int4) error {
+	return q.CreateTimelineRevision(ctx, db.CreateTimelineRevisionParams{
+		TimelineID:   timelineID,
+		UserID:       userID,
+		Action:       action,
+		RestoredFrom: restoredFrom,
+	})
+}
+
+// tagSnapshotEvents gives the snapshot's events the shape the event list
+// has, looking up each event's tags by ID. Tags deleted since are left out,
+// as restoring the revision would leave them out.
+func tagSnapshotEvents(ctx context.Context, store *db.Queries, events []snapshotEvent) ([]taggedEvent, error) {
+	tagged := make([]taggedEvent, len(events))
+	var tagIDs []pgtype.UUID
+	for i, e := range events {
+		tagged[i] = taggedEvent{Event: e.Event, Tags: []tagLabel{}}
+		tagIDs = append(tagIDs, e.TagIDs...)
+	}
+	if len(tagIDs) == 0 {
+		return tagged, nil
+	}
+
+	tags, err := store.GetTagsByIds(ctx, tagIDs)
+	if err != nil {
+		return nil, err
+	}
+	// The tags come sorted by name; keep that order on each event.
+	for i, e := range events {
+		for _, t := range tags {
+			if slices.Contains(e.TagIDs, t.ID) {
+				tagged[i].Tags = append(tagged[i].Tags, tagLabel{ID: t.ID, Name: t.Name, Color: t.Color})
+			}
+		}
+	}
+	return tagged, nil
+}
+
+func readRevisionParam(r *http.Request) (int32, error) {
+	n, err := strconv.ParseInt(r.PathValue("revision"), 10, 32)
+	if err != nil || n < 1 {
+		return 0, errors.New("revision must be a positive number")
+	}
+	return int32(n), nil
+}
+
+func (eh *EventHandler) HandleGetRevisions(w http.ResponseWriter, r *http.Request) {
+	timeline, ok := authorizeTimeline(w, r, eh.eventStore, eh.logger, roleViewer)
+	if !ok {
+		return
+	}
+
+	revisions, err := eh.eventStore.GetTimelineRevisions(r.Context(), timeline.ID)
+	if err != nil {
+		eh.logger.Printf("Failed to retrieve revisions: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to retrieve revisions"})
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"data": revisions})
+}
+
+// HandleGetRevision returns the timeline and its events as they were after
+// the revision.
+func (eh *EventHandler) HandleGetRevision(w http.ResponseWriter, r *http.Request) {
+	timeline, ok := authorizeTimeline(w, r, eh.eventStore, eh.logger, roleViewer)
+	if !ok {
+		return
+	}
+
+	revision, err := readRevisionParam(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Invalid revision"})
+		return
+	}
+
+	rev, err := eh.eventStore.GetTimelineRevision(r.Context(), db.GetTimelineRevisionParams{
+		TimelineID: timeline.ID,
+		Revision:   revision,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"message": "Revision not found"})
+		return
+	}
+	if err != nil {
+		eh.logger.Printf("Failed to retrieve revision: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to retrieve revision"})
+		return
+	}
+
+	var snapshot timelineSnapshot
+	if err := json.Unmarshal(rev.Snapshot, &snapshot); err != nil {
+		eh.logger.Printf("Failed to decode revision snapshot: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to retrieve revision"})
+		return
+	}
+
+	events, err := tagSnapshotEvents(r.Context(), eh.eventStore, snapshot.Events)
+	if err != nil {
+		eh.logger.Printf("Failed to retrieve revision tags: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to retrieve revision"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"data": utils.Envelope{
+		"revision":      rev.Revision,
+		"user_id":       rev.UserID,
+		"username":      rev.Username,
+		"action":        rev.Action,
+		"restored_from": rev.RestoredFrom,
+		"created_at":    rev.CreatedAt,
+		"timeline": utils.Envelope{
+			"title":       snapshot.Title,
+			"description": snapshot.Description,
+			"events":      events,
+		},
+	}})
+}
+
+// HandleRestoreRevision puts the timeline back the way it was after the
+// revision. The restore is itself recorded as a new revision, so it can be
+// undone too.
+func (eh *EventHandler) HandleRestoreRevision(w http.ResponseWriter, r *http.Request) {
+	timeline, ok := authorizeTimeline(w, r, eh.eventStore, eh.logger, roleEditor)
+	if !ok {
+		return
+	}
+
+	userID, err := utils.ReadUserID(r)
+	if err != nil {
+		eh.logger.Printf("Invalid user ID format: %v", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Invalid user ID"})
+		return
+	}
+
+	revision, err := readRevisionParam(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Invalid revision"})
+		return
+	}
+
+	ctx := r.Context()
+
+	rev, err := eh.eventStore.GetTimelineRevision(ctx, db.GetTimelineRevisionParams{
+		TimelineID: timeline.ID,
+		Revision:   revision,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"message": "Revision not found"})
+		return
+	}
+	if err != nil {
+		eh.logger.Printf("Failed to retrieve revision: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to restore revision"})
+		return
+	}
+
+	var snapshot timelineSnapshot
+	if err := json.Unmarshal(rev.Snapshot, &snapshot); err != nil {
+		eh.logger.Printf("Failed to decode revision snapshot: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to restore revision"})
+		return
+	}
+
+	tx, err := eh.dbConn.Begin(ctx)
+	if err != nil {
+		eh.logger.Printf("Failed to begin transaction: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to restore revision"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := eh.eventStore.WithTx(tx)
+
+	if err := qtx.LockTimeline(ctx, timeline.ID); err != nil {
+		eh.logger.Printf("Failed to lock timeline: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to restore revision"})
+		return
+	}
+
+	_, err = qtx.UpdateTimeline(ctx, db.UpdateTimelineParams{
+		ID:          timeline.ID,
+		Title:       snapshot.Title,
+		Description: snapshot.Description,
+		UserID:      userID,
+	})
+	if err != nil {
+		eh.logger.Printf("Failed to restore timeline: %v", err)
+		if isTitleConflict(err) {
+			utils.WriteJSON(w, http.StatusConflict, utils.Envelope{"message": "The owner already has another timeline with this title"})
+			return
+		}
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to restore revision"})
+		return
+	}
+
+	// The current events go to the trash. Events keep their IDs, so links to
+	// them still work after a restore; any copy left in the trash is dropped
+	// to make way for them.
+	if err := qtx.TrashEventsByTimelineId(ctx, timeline.ID); err != nil {
+		eh.logger.Printf("Failed to trash events: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to restore revision"})
+		return
+	}
+
+	restoreIDs := make([]pgtype.UUID, len(snapshot.Events))
+	for i, e := range snapshot.Events {
+		restoreIDs[i] = e.ID
+	}
+	if err := qtx.PurgeEventsByIds(ctx, db.PurgeEventsByIdsParams{
+		TimelineID: timeline.ID,
+		Ids:        restoreIDs,
+	}); err != nil {
+		eh.logger.Printf("Failed to purge events: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to restore revision"})
+		return
+	}
+
+	restoreParams := make([]db.RestoreEventsParams, len(snapshot.Events))
+	for i, e := range snapshot.Events {
+		restoreParams[i] = db.RestoreEventsParams{
+			ID:               e.ID,
+			TimelineID:       timeline.ID,
+			Title:            e.Title,
+			CardTitle:        e.CardTitle,
+			CardSubtitle:     e.CardSubtitle,
+			CardDetailedText: e.CardDetailedText,
+			StartYear:        e.StartYear,
+			StartMonth:       e.StartMonth,
+			StartDay:         e.StartDay,
+			StartMinuteOfDay: e.StartMinuteOfDay,
+			EndYear:          e.EndYear,
+			EndMonth:         e.EndMonth,
+			EndDay:           e.EndDay,
+			EndMinuteOfDay:   e.EndMinuteOfDay,
+			DatePrecision:    e.DatePrecision,
+			Position:         e.Position,
+			CreatedAt:        e.CreatedAt,
+		}
+	}
+	if len(restoreParams) > 0 {
+		if _, err := qtx.RestoreEvents(ctx, restoreParams); err != nil {
+			eh.logger.Printf("Failed to restore events: %v", err)
+			utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to restore revision"})
+			return
+		}
+	}
+
+	var tagEventIDs, tagIDs []pgtype.UUID
+	for _, e := range snapshot.Events {
+		for _, tagID := range e.TagIDs {
+			tagEventIDs = append(tagEventIDs, e.ID)
+			tagIDs = append(tagIDs, tagID)
+		}
+	}
+	if len(tagEventIDs) > 0 {
+		if err := qtx.AddEventTags(ctx, db.AddEventTagsParams{
+			EventIds: tagEventIDs,
+			TagIds:   tagIDs,
+		}); err != nil {
+			eh.logger.Printf("Failed to restore event tags: %v", err)
+			utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to restore revision"})
+			return
+		}
+	}
+
+	if err := recordRevision(ctx, qtx, timeline.ID, userID, revisionRestore, pgtype.Int4{Int32: revision, Valid: true}); err != nil {
+		eh.logger.Printf("Failed to record revision: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to restore revision"})
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		eh.logger.Printf("Failed to commit transaction: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to restore revision"})
+		return
+	}
+
+	events, err := eh.listTaggedEvents(r, timeline.ID)
+	if err != nil {
+		eh.logger.Printf("Failed to retrieve events: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to retrieve events"})
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"message": "Revision restored successfully", "events": events})
+}
@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/nabsk911/chronify/internal/ai"
+	"github.com/nabsk911/chronify/internal/db"
+)
+
+func TestGetRevision(t *testing.T) {
+	history := testEventID(0x40)
+	war := pgtype.UUID{Bytes: [16]byte{0: 0x7a, 15: 1}, Valid: true}
+	deleted := pgtype.UUID{Bytes: [16]byte{0: 0x7a, 15: 2}, Valid: true}
+	// What timeline_snapshot() stores: every column of the events table.
+	snapshot := `{"title": "Wars", "description": "Old", "events": [{
+		"id": "` + history.String() + `", "timeline_id": "` + testTimelineID.String() + `",
+		"title": "Hastings", "card_title": "1066", "position": "a0", "start_year": 1066,
+		"search_vector": "'hastings':1", "deleted_at": null,
+		"tag_ids": ["` + war.String() + `", "` + deleted.String() + `"]
+	}]}`
+
+	fake := newFakeTimeline(t)
+	fake.On("GetTimelineRevision", func(args []any) (any, error) {
+		return db.GetTimelineRevisionRow{Revision: 3, UserID: testUserID, Action: revisionUpdate, Snapshot: []byte(snapshot)}, nil
+	})
+	fake.On("GetTagsByIds", func(args []any) (any, error) {
+		if !slices.Contains(args[0].([]pgtype.UUID), war) {
+			return nil, nil
+		}
+		return []db.GetTagsByIdsRow{{ID: war, Name: "War", Color: "#ff0000"}}, nil
+	})
+	eh := NewEventHandler(db.New(fake), fake, ai.NewFake(nil), ai.Limits{}, testLogger)
+
+	r := newTimelineRequest("GET", "/revisions/3", "")
+	r.SetPathValue("revision", "3")
+	w := httptest.NewRecorder()
+	eh.HandleGetRevision(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", w.Code, w.Body)
+	}
+
+	if strings.Contains(w.Body.String(), "search_vector") || strings.Contains(w.Body.String(), "tag_ids") {
+		t.Errorf("response exposes the snapshot's internal columns: %s", w.Body)
+	}
+	var resp struct {
+		Data struct {
+			Timeline struct {
+				Title  string        `json:"title"`
+				Events []taggedEvent `json:"events"`
+			} `json:"timeline"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	timeline := resp.Data.Timeline
+	if timeline.Title != "Wars" || len(timeline.Events) != 1 {
+		t.Fatalf("timeline = %+v, want the snapshot's title and event", timeline)
+	}
+	e := timeline.Events[0]
+	if e.ID != history || e.Title != "Hastings" || e.StartYear.Int64 != 1066 {
+		t.Errorf("event = %+v, want the snapshot's", e.Event)
+	}
+	if want := []tagLabel{{ID: war, Name: "War", Color: "#ff0000"}}; !slices.Equal(e.Tags, want) {
+		t.Errorf("tags = %+v, want %+v without the deleted tag", e.Tags, want)
+	}
+}
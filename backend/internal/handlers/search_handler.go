@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"html"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/nabsk911/chronify/internal/db"
+	"github.com/nabsk911/chronify/internal/utils"
+)
+
+const (
+	defaultSearchResults  = 20
+	maxSearchResults      = 50
+	searchHitsPerTimeline = 5
+)
+
+type searchHit struct {
+	ID      pgtype.UUID `json:"id"`
+	Title   string      `json:"title"`
+	Snippet string      `json:"snippet"`
+	Rank    float32     `json:"rank"`
+}
+
+type searchResult struct {
+	ID      pgtype.UUID `json:"id"`
+	Role    string      `json:"role"`
+	Title   string      `json:"title"`
+	Snippet string      `json:"snippet"`
+	Rank    float32     `json:"rank"`
+	Events  []searchHit `json:"events"`
+}
+
+// highlight escapes a ts_headline result for use as HTML, keeping only the
+// <mark> tags around the matches.
+func highlight(s string) string {
+	s = html.EscapeString(s)
+	s = strings.ReplaceAll(s, "&lt;mark&gt;", "<mark>")
+	return strings.ReplaceAll(s, "&lt;/mark&gt;", "</mark>")
+}
+
+// HandleSearch runs a full-text search over the titles and descriptions of
+// the user's timelines and the content of their events. Results are
+// timelines, best match first, each with its best matching events. Titles
+// and snippets are HTML with the matches in <mark> tags.
+func (th *TimelineHandler) HandleSearch(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.ReadUserID(r)
+	if err != nil {
+		th.logger.Printf("Invalid user ID format: %v", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Invalid user ID"})
+		return
+	}
+
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Search query is required"})
+		return
+	}
+
+	limit := defaultSearchResults
+	if v := r.URL.Query().Get("limit"); v != "" {
+		limit, err = strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > maxSearchResults {
+			utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Limit must be between 1 and " + strconv.Itoa(maxSearchResults)})
+			return
+		}
+	}
+
+	timelines, err := th.timelineStore.SearchTimelines(r.Context(), db.SearchTimelinesParams{
+		Query:      query,
+		UserID:     userID,
+		MaxResults: int32(limit),
+	})
+	if err != nil {
+		th.logger.Printf("Failed to search timelines: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to search"})
+		return
+	}
+
+	results := make([]searchResult, len(timelines))
+	index := make(map[pgtype.UUID]int, len(timelines))
+	timelineIDs := make([]pgtype.UUID, len(timelines))
+	for i, t := range timelines {
+		results[i] = searchResult{
+			ID:      t.ID,
+			Role:    t.Role,
+			Title:   highlight(t.Title),
+			Snippet: highlight(t.Snippet),
+			Rank:    t.Rank,
+			Events:  []searchHit{},
+		}
+		index[t.ID] = i
+		timelineIDs[i] = t.ID
+	}
+
+	if len(timelineIDs) > 0 {
+		events, err := th.timelineStore.SearchEvents(r.Context(), db.SearchEventsParams{
+			Query:           query,
+			TimelineIds:     timelineIDs,
+			HitsPerTimeline: searchHitsPerTimeline,
+		})
+		if err != nil {
+			th.logger.Printf("Failed to search events: %v", err)
+			utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to search"})
+			return
+		}
+		for _, e := range events {
+			i := index[e.TimelineID]
+			results[i].Events = append(results[i].Events, searchHit{
+				ID:      e.ID,
+				Title:   highlight(e.Title),
+				Snippet: highlight(e.Snippet),
+				Rank:    e.Rank,
+			})
+		}
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"data": results})
+}
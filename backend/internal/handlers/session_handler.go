@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/nabsk911/chronify/internal/auth"
+	"github.com/nabsk911/chronify/internal/db"
+	"github.com/nabsk911/chronify/internal/utils"
+)
+
+type refreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type sessionTokens struct {
+	AccessToken  string
+	RefreshToken string
+}
+
+// issueSession starts a new session for the user, or continues an existing
+// session family when familyID is valid, and returns a fresh token pair. The
+// session is stored through store, so it can be part of a transaction.
+func (uh *UserHandler) issueSession(ctx context.Context, store *db.Queries, userID pgtype.UUID, familyID pgtype.UUID) (sessionTokens, error) {
+	refreshToken, refreshTokenHash, err := auth.GenerateRefreshToken()
+	if err != nil {
+		return sessionTokens{}, err
+	}
+
+	expiresAt := pgtype.Timestamptz{Time: time.Now().Add(auth.RefreshTokenTTL), Valid: true}
+
+	var session db.Session
+	if familyID.Valid {
+		session, err = store.CreateRotatedSession(ctx, db.CreateRotatedSessionParams{
+			UserID:           userID,
+			FamilyID:         familyID,
+			RefreshTokenHash: refreshTokenHash,
+			ExpiresAt:        expiresAt,
+		})
+	} else {
+		session, err = store.CreateSession(ctx, db.CreateSessionParams{
+			UserID:           userID,
+			RefreshTokenHash: refreshTokenHash,
+			ExpiresAt:        expiresAt,
+		})
+	}
+	if err != nil {
+		return sessionTokens{}, err
+	}
+
+	accessToken, err := uh.tokenKeys.GenerateToken(userID.String(), session.FamilyID.String())
+	if err != nil {
+		return sessionTokens{}, err
+	}
+
+	return sessionTokens{AccessToken: accessToken, RefreshToken: refreshToken}, nil
+}
+
+// Refresh
+func (uh *UserHandler) HandleRefreshToken(w http.ResponseWriter, r *http.Request) {
+	var req refreshTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		uh.logger.Printf("Failed to decode refresh request: %v", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Invalid request payload!"})
+		return
+	}
+
+	if req.RefreshToken == "" {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Refresh token is required"})
+		return
+	}
+
+	refreshTokenHash := auth.HashRefreshToken(req.RefreshToken)
+
+	// The old token is only spent if its replacement is stored, or a failed
+	// refresh would make the client's retry look like reuse.
+	tx, err := uh.dbConn.Begin(r.Context())
+	if err != nil {
+		uh.logger.Printf("Failed to begin transaction: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Internal server error!"})
+		return
+	}
+	defer tx.Rollback(r.Context())
+	qtx := uh.userStore.WithTx(tx)
+
+	session, err := qtx.RotateSession(r.Context(), refreshTokenHash)
+	if err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			uh.logger.Printf("Failed to rotate session: %v", err)
+			utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Internal server error!"})
+			return
+		}
+		tx.Rollback(r.Context())
+
+		// A token that was already rotated is being replayed, so whoever holds
+		// it can't be trusted: revoke every token in its family.
+		stale, err := uh.userStore.GetSessionByRefreshTokenHash(r.Context(), refreshTokenHash)
+		if err == nil && stale.RotatedAt.Valid && !stale.RevokedAt.Valid {
+			uh.logger.Printf("Refresh token reuse detected for session %s", stale.FamilyID.String())
+			err = uh.userStore.RevokeSessionFamily(r.Context(), db.RevokeSessionFamilyParams{
+				FamilyID: stale.FamilyID,
+				UserID:   stale.UserID,
+			})
+			if err != nil {
+				uh.logger.Printf("Failed to revoke session: %v", err)
+			}
+		}
+
+		utils.WriteJSON(w, http.StatusUnauthorized, utils.Envelope{"message": "Invalid refresh token!"})
+		return
+	}
+
+	tokens, err := uh.issueSession(r.Context(), qtx, session.UserID, session.FamilyID)
+	if err != nil {
+		uh.logger.Printf("Failed to issue session: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Internal server error"})
+		return
+	}
+
+	if err := tx.Commit(r.Context()); err != nil {
+		uh.logger.Printf("Failed to commit transaction: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Internal server error!"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{
+		"token":         tokens.AccessToken,
+		"refresh_token": tokens.RefreshToken,
+	})
+}
+
+// Logout
+func (uh *UserHandler) HandleLogout(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.ReadUserID(r)
+	if err != nil {
+		uh.logger.Printf("Invalid user ID format: %v", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Invalid user ID"})
+		return
+	}
+
+	sessionID, err := utils.ReadSessionID(r)
+	if err != nil {
+		uh.logger.Printf("Invalid session ID format: %v", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Invalid session ID"})
+		return
+	}
+
+	err = uh.userStore.RevokeSessionFamily(r.Context(), db.RevokeSessionFamilyParams{
+		FamilyID: sessionID,
+		UserID:   userID,
+	})
+	if err != nil {
+		uh.logger.Printf("Failed to revoke session: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to log out"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"message": "Logged out successfully"})
+}
+
+func (uh *UserHandler) HandleLogoutAll(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.ReadUserID(r)
+	if err != nil {
+		uh.logger.Printf("Invalid user ID format: %v", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Invalid user ID"})
+		return
+	}
+
+	err = uh.userStore.RevokeUserSessions(r.Context(), userID)
+	if err != nil {
+		uh.logger.Printf("Failed to revoke sessions for user %s: %v", userID.String(), err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to log out"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"message": "Logged out of all sessions successfully"})
+}
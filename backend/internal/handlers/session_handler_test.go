@@ -0,0 +1,231 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/nabsk911/chronify/internal/auth"
+	"github.com/nabsk911/chronify/internal/db"
+	"github.com/nabsk911/chronify/internal/dbtest"
+)
+
+// fakeSessions is a database holding the sessions table.
+type fakeSessions struct {
+	*dbtest.DB
+
+	mu       sync.Mutex
+	sessions []db.Session
+	nextID   byte
+}
+
+func newFakeSessions() *fakeSessions {
+	f := &fakeSessions{DB: dbtest.New(), nextID: 1}
+	f.On("CreateSession", func(args []any) (any, error) {
+		return f.create(args[0].(pgtype.UUID), pgtype.UUID{}, args[1].(string), args[2].(pgtype.Timestamptz)), nil
+	})
+	f.On("CreateRotatedSession", func(args []any) (any, error) {
+		return f.create(args[0].(pgtype.UUID), args[1].(pgtype.UUID), args[2].(string), args[3].(pgtype.Timestamptz)), nil
+	})
+	f.On("RotateSession", func(args []any) (any, error) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		for i, s := range f.sessions {
+			if s.RefreshTokenHash == args[0] && !s.RotatedAt.Valid && !s.RevokedAt.Valid && s.ExpiresAt.Time.After(time.Now()) {
+				f.sessions[i].RotatedAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+				return f.sessions[i], nil
+			}
+		}
+		return nil, nil
+	})
+	f.On("GetSessionByRefreshTokenHash", func(args []any) (any, error) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		for _, s := range f.sessions {
+			if s.RefreshTokenHash == args[0] {
+				return s, nil
+			}
+		}
+		return nil, nil
+	})
+	f.On("RevokeSessionFamily", func(args []any) (any, error) {
+		f.revoke(func(s db.Session) bool { return s.FamilyID == args[0] && s.UserID == args[1] })
+		return nil, nil
+	})
+	f.On("RevokeUserSessions", func(args []any) (any, error) {
+		f.revoke(func(s db.Session) bool { return s.UserID == args[0] })
+		return nil, nil
+	})
+	return f
+}
+
+func (f *fakeSessions) create(userID, familyID pgtype.UUID, hash string, expiresAt pgtype.Timestamptz) db.Session {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	id := pgtype.UUID{Bytes: [16]byte{0: 0x5e, 15: f.nextID}, Valid: true}
+	f.nextID++
+	if !familyID.Valid {
+		familyID = id
+	}
+	s := db.Session{ID: id, UserID: userID, FamilyID: familyID, RefreshTokenHash: hash, ExpiresAt: expiresAt}
+	f.sessions = append(f.sessions, s)
+	return s
+}
+
+func (f *fakeSessions) revoke(match func(db.Session) bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i, s := range f.sessions {
+		if match(s) && !s.RevokedAt.Valid {
+			f.sessions[i].RevokedAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+		}
+	}
+}
+
+// login starts a session for the user and returns its refresh token.
+func (f *fakeSessions) login(t *testing.T, uh *UserHandler, userID pgtype.UUID) string {
+	t.Helper()
+	tokens, err := uh.issueSession(context.Background(), uh.userStore, userID, pgtype.UUID{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tokens.RefreshToken
+}
+
+// active returns the refresh token hashes of the sessions that can still be
+// refreshed.
+func (f *fakeSessions) active() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var hashes []string
+	for _, s := range f.sessions {
+		if !s.RotatedAt.Valid && !s.RevokedAt.Valid {
+			hashes = append(hashes, s.RefreshTokenHash)
+		}
+	}
+	return hashes
+}
+
+func newSessionHandler(t *testing.T, fake *fakeSessions) *UserHandler {
+	return NewUserHandler(db.New(fake), fake, newTestKeySet(t), nil, "", false, nil, testLogger)
+}
+
+func refresh(uh *UserHandler, refreshToken string) (*httptest.ResponseRecorder, string) {
+	r := httptest.NewRequest("POST", "/refresh", strings.NewReader(`{"refresh_token":"`+refreshToken+`"}`))
+	w := httptest.NewRecorder()
+	uh.HandleRefreshToken(w, r)
+
+	var resp struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	return w, resp.RefreshToken
+}
+
+func TestRefreshTokenRotation(t *testing.T) {
+	fake := newFakeSessions()
+	uh := newSessionHandler(t, fake)
+	first := fake.login(t, uh, testUserID)
+
+	w, second := refresh(uh, first)
+	if w.Code != http.StatusOK || second == "" || second == first {
+		t.Fatalf("refresh = %d %s, want a new refresh token", w.Code, w.Body)
+	}
+	if got := fake.active(); len(got) != 1 || got[0] != auth.HashRefreshToken(second) {
+		t.Errorf("refreshable sessions = %v, want only the new one", got)
+	}
+	if fake.sessions[1].FamilyID != fake.sessions[0].FamilyID {
+		t.Error("the new session left the family")
+	}
+
+	w, third := refresh(uh, second)
+	if w.Code != http.StatusOK || third == "" {
+		t.Errorf("refreshing the new token = %d %s, want 200", w.Code, w.Body)
+	}
+	if fake.Commits() != 2 {
+		t.Errorf("%d commits, want one per refresh", fake.Commits())
+	}
+}
+
+func TestRefreshTokenReuseRevokesFamily(t *testing.T) {
+	fake := newFakeSessions()
+	uh := newSessionHandler(t, fake)
+	stolen := fake.login(t, uh, testUserID)
+	other := fake.login(t, uh, testUserID)
+
+	_, current := refresh(uh, stolen)
+	if w, _ := refresh(uh, stolen); w.Code != http.StatusUnauthorized {
+		t.Fatalf("reusing a rotated token = %d, want 401", w.Code)
+	}
+	if w, _ := refresh(uh, current); w.Code != http.StatusUnauthorized {
+		t.Errorf("refreshing after reuse = %d, want 401: the family should be revoked", w.Code)
+	}
+	if got := fake.active(); len(got) != 1 || got[0] != auth.HashRefreshToken(other) {
+		t.Errorf("refreshable sessions = %v, want only the other login's", got)
+	}
+}
+
+func TestRefreshTokenKeptWhenNewSessionFails(t *testing.T) {
+	fake := newFakeSessions()
+	uh := newSessionHandler(t, fake)
+	token := fake.login(t, uh, testUserID)
+	fake.On("CreateRotatedSession", func(args []any) (any, error) {
+		return nil, errors.New("connection reset")
+	})
+
+	if w, _ := refresh(uh, token); w.Code != http.StatusInternalServerError {
+		t.Fatalf("refresh = %d, want 500", w.Code)
+	}
+	if fake.Commits() != 0 || fake.Rollbacks() != 1 {
+		t.Errorf("%d commits and %d rollbacks, want the rotation rolled back with its replacement", fake.Commits(), fake.Rollbacks())
+	}
+	if fake.Called("RevokeSessionFamily") != 0 {
+		t.Error("the family was revoked")
+	}
+}
+
+func TestLogout(t *testing.T) {
+	otherUser := pgtype.UUID{Bytes: [16]byte{0: 0xb2}, Valid: true}
+
+	tests := []struct {
+		name       string
+		handle     func(uh *UserHandler) http.HandlerFunc
+		wantActive int
+	}{
+		{"this session", func(uh *UserHandler) http.HandlerFunc { return uh.HandleLogout }, 2},
+		{"every session", func(uh *UserHandler) http.HandlerFunc { return uh.HandleLogoutAll }, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeSessions()
+			uh := newSessionHandler(t, fake)
+			fake.login(t, uh, testUserID)
+			fake.login(t, uh, testUserID)
+			others := fake.login(t, uh, otherUser)
+
+			r := httptest.NewRequest("POST", "/logout", nil)
+			ctx := context.WithValue(r.Context(), "userID", testUserID.String())
+			ctx = context.WithValue(ctx, "sessionID", fake.sessions[0].FamilyID.String())
+			w := httptest.NewRecorder()
+			tt.handle(uh)(w, r.WithContext(ctx))
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d, want 200: %s", w.Code, w.Body)
+			}
+			active := fake.active()
+			if len(active) != tt.wantActive {
+				t.Errorf("%d sessions left, want %d", len(active), tt.wantActive)
+			}
+			if active[len(active)-1] != auth.HashRefreshToken(others) {
+				t.Error("another user's session was revoked")
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/nabsk911/chronify/internal/auth"
+	"github.com/nabsk911/chronify/internal/db"
+	"github.com/nabsk911/chronify/internal/utils"
+)
+
+type shareLinkRequest struct {
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	Password  string     `json:"password,omitempty"`
+}
+
+func (th *TimelineHandler) HandleGetShareLinks(w http.ResponseWriter, r *http.Request) {
+	timeline, ok := authorizeTimeline(w, r, th.timelineStore, th.logger, roleOwner)
+	if !ok {
+		return
+	}
+
+	links, err := th.timelineStore.GetShareLinksByTimelineId(r.Context(), timeline.ID)
+	if err != nil {
+		th.logger.Printf("Failed to retrieve share links: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to retrieve share links"})
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"data": links})
+}
+
+// HandleCreateShareLink returns the link's token once; only its hash is
+// stored.
+func (th *TimelineHandler) HandleCreateShareLink(w http.ResponseWriter, r *http.Request) {
+	timeline, ok := authorizeTimeline(w, r, th.timelineStore, th.logger, roleOwner)
+	if !ok {
+		return
+	}
+
+	userID, err := utils.ReadUserID(r)
+	if err != nil {
+		th.logger.Printf("Invalid user ID format: %v", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Invalid user ID"})
+		return
+	}
+
+	var req shareLinkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		th.logger.Printf("Failed to decode request: %v", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Invalid request payload"})
+		return
+	}
+
+	var expiresAt pgtype.Timestamptz
+	if req.ExpiresAt != nil {
+		if !req.ExpiresAt.After(time.Now()) {
+			utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Expiry must be in the future"})
+			return
+		}
+		expiresAt = pgtype.Timestamptz{Time: *req.ExpiresAt, Valid: true}
+	}
+
+	var passwordHash pgtype.Text
+	if req.Password != "" {
+		if len(req.Password) < 8 {
+			utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Password must be at least 8 characters"})
+			return
+		}
+		hash, err := auth.SetPasswordHash(req.Password)
+		if err != nil {
+			th.logger.Printf("Failed to hash password: %v", err)
+			utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to create share link"})
+			return
+		}
+		passwordHash = pgtype.Text{String: hash, Valid: true}
+	}
+
+	token, tokenHash, err := auth.GenerateShareToken()
+	if err != nil {
+		th.logger.Printf("Failed to generate share token: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to create share link"})
+		return
+	}
+
+	link, err := th.timelineStore.CreateShareLink(r.Context(), db.CreateShareLinkParams{
+		TimelineID:   timeline.ID,
+		TokenHash:    tokenHash,
+		PasswordHash: passwordHash,
+		ExpiresAt:    expiresAt,
+		CreatedBy:    userID,
+	})
+	if err != nil {
+		th.logger.Printf("Failed to create share link: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to create share link"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, utils.Envelope{
+		"data":    link,
+		"token":   token,
+		"path":    "/public/" + token,
+		"message": "Share link created successfully",
+	})
+}
+
+func (th *TimelineHandler) HandleRevokeShareLink(w http.ResponseWriter, r *http.Request) {
+	timeline, ok := authorizeTimeline(w, r, th.timelineStore, th.logger, roleOwner)
+	if !ok {
+		return
+	}
+
+	linkID, err := utils.ReadIDParam(r, "linkId")
+	if err != nil {
+		th.logger.Printf("Invalid share link ID: %v", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Invalid share link ID"})
+		return
+	}
+
+	revoked, err := th.timelineStore.RevokeShareLink(r.Context(), db.RevokeShareLinkParams{
+		ID:         linkID,
+		TimelineID: timeline.ID,
+	})
+	if err != nil {
+		th.logger.Printf("Failed to revoke share link: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to revoke share link"})
+		return
+	}
+	if revoked == 0 {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"message": "Share link not found"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"message": "Share link revoked successfully"})
+}
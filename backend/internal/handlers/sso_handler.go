@@ -0,0 +1,284 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/nabsk911/chronify/internal/auth"
+	"github.com/nabsk911/chronify/internal/db"
+	"github.com/nabsk911/chronify/internal/oidc"
+	"github.com/nabsk911/chronify/internal/utils"
+)
+
+const (
+	oidcLoginStateTTL = 10 * time.Minute
+	maxUsernameTries  = 5
+)
+
+// errUnverifiedAccount is returned when an SSO login's email belongs to an
+// account that hasn't verified it.
+var errUnverifiedAccount = errors.New("account email not verified")
+
+type ssoCallbackRequest struct {
+	Code  string `json:"code"`
+	State string `json:"state"`
+}
+
+type ssoProvider struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name"`
+}
+
+// SSOHandler logs users in through OpenID Connect providers. Once the
+// provider vouches for them, they carry on like a password login.
+type SSOHandler struct {
+	userStore *db.Queries
+	dbConn    TxStarter
+	providers map[string]*oidc.Provider
+	users     *UserHandler
+	logger    *log.Logger
+}
+
+func NewSSOHandler(userStore *db.Queries, dbConn TxStarter, providers map[string]*oidc.Provider, users *UserHandler, logger *log.Logger) *SSOHandler {
+	return &SSOHandler{
+		userStore: userStore,
+		dbConn:    dbConn,
+		providers: providers,
+		users:     users,
+		logger:    logger,
+	}
+}
+
+func (sh *SSOHandler) HandleGetProviders(w http.ResponseWriter, r *http.Request) {
+	providers := make([]ssoProvider, 0, len(sh.providers))
+	for _, p := range sh.providers {
+		providers = append(providers, ssoProvider{Name: p.Name(), DisplayName: p.DisplayName()})
+	}
+	slices.SortFunc(providers, func(a, b ssoProvider) int { return strings.Compare(a.Name, b.Name) })
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"data": providers})
+}
+
+// HandleStartLogin returns the provider's login page to send the user to.
+// The web app should keep the state and check the provider hands the same
+// one back before posting it to the callback.
+func (sh *SSOHandler) HandleStartLogin(w http.ResponseWriter, r *http.Request) {
+	provider, ok := sh.providers[r.PathValue("provider")]
+	if !ok {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"message": "SSO provider not found"})
+		return
+	}
+
+	if err := sh.userStore.DeleteExpiredOIDCLoginStates(r.Context()); err != nil {
+		sh.logger.Printf("Failed to delete expired SSO logins: %v", err)
+	}
+
+	state, stateHash, err := auth.GenerateOIDCState()
+	if err != nil {
+		sh.logger.Printf("Failed to generate SSO state: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to start SSO login"})
+		return
+	}
+	nonce, err := oidc.NewNonce()
+	if err != nil {
+		sh.logger.Printf("Failed to generate SSO nonce: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to start SSO login"})
+		return
+	}
+	verifier, err := oidc.NewCodeVerifier()
+	if err != nil {
+		sh.logger.Printf("Failed to generate PKCE verifier: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to start SSO login"})
+		return
+	}
+
+	authURL, err := provider.AuthCodeURL(r.Context(), state, nonce, verifier)
+	if err != nil {
+		sh.logger.Printf("Failed to reach SSO provider %s: %v", provider.Name(), err)
+		utils.WriteJSON(w, http.StatusBadGateway, utils.Envelope{"message": "SSO provider is unavailable"})
+		return
+	}
+
+	err = sh.userStore.CreateOIDCLoginState(r.Context(), db.CreateOIDCLoginStateParams{
+		Provider:     provider.Name(),
+		StateHash:    stateHash,
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		ExpiresAt:    pgtype.Timestamptz{Time: time.Now().Add(oidcLoginStateTTL), Valid: true},
+	})
+	if err != nil {
+		sh.logger.Printf("Failed to store SSO login: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to start SSO login"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"data": map[string]any{
+		"authorization_url": authURL,
+		"state":             state,
+	}})
+}
+
+// HandleCallback finishes an SSO login with the code the provider sent the
+// user back with. The response is the same as for a password login.
+func (sh *SSOHandler) HandleCallback(w http.ResponseWriter, r *http.Request) {
+	provider, ok := sh.providers[r.PathValue("provider")]
+	if !ok {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"message": "SSO provider not found"})
+		return
+	}
+
+	var req ssoCallbackRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sh.logger.Printf("Failed to decode SSO callback request: %v", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Invalid request payload!"})
+		return
+	}
+
+	if req.Code == "" || req.State == "" {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Code and state are required"})
+		return
+	}
+
+	login, err := sh.userStore.UseOIDCLoginState(r.Context(), db.UseOIDCLoginStateParams{
+		StateHash: auth.HashOIDCState(req.State),
+		Provider:  provider.Name(),
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Invalid or expired SSO login, please try again"})
+			return
+		}
+		sh.logger.Printf("Failed to retrieve SSO login: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Internal server error!"})
+		return
+	}
+
+	claims, err := provider.Exchange(r.Context(), req.Code, login.CodeVerifier, login.Nonce)
+	if err != nil {
+		sh.logger.Printf("SSO login with %s failed: %v", provider.Name(), err)
+		utils.WriteJSON(w, http.StatusUnauthorized, utils.Envelope{"message": "SSO login failed"})
+		return
+	}
+
+	// Accounts are matched on email, so only an address the provider has
+	// verified can be trusted to pick one.
+	if !claims.EmailVerified || !utils.IsValidEmail(claims.Email) {
+		utils.WriteJSON(w, http.StatusForbidden, utils.Envelope{"message": "Your SSO account has no verified email"})
+		return
+	}
+
+	user, err := sh.userForIdentity(r.Context(), provider.Name(), claims)
+	if errors.Is(err, errUnverifiedAccount) {
+		utils.WriteJSON(w, http.StatusConflict, utils.Envelope{"message": "An account with this email already exists. Log in with your password and verify your email to use SSO"})
+		return
+	}
+	if err != nil {
+		sh.logger.Printf("Failed to find or create user for %s identity %s: %v", provider.Name(), claims.Subject, err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Internal server error!"})
+		return
+	}
+
+	sh.users.finishLogin(w, r, user)
+}
+
+// userForIdentity returns the user linked to the provider's account. The
+// first time, it is linked to the user with the same email, who is created
+// if there isn't one. A user who hasn't verified the email isn't linked:
+// whoever signed up with it may not own it, and their password would still
+// work.
+func (sh *SSOHandler) userForIdentity(ctx context.Context, provider string, claims *oidc.Claims) (db.User, error) {
+	user, err := sh.userStore.GetUserByIdentity(ctx, db.GetUserByIdentityParams{
+		Provider: provider,
+		Subject:  claims.Subject,
+	})
+	if err == nil || !errors.Is(err, pgx.ErrNoRows) {
+		return user, err
+	}
+
+	tx, err := sh.dbConn.Begin(ctx)
+	if err != nil {
+		return db.User{}, err
+	}
+	defer tx.Rollback(ctx)
+	qtx := sh.userStore.WithTx(tx)
+
+	user, err = qtx.GetUserByEmail(ctx, claims.Email)
+	switch {
+	case err == nil:
+		if !user.EmailVerifiedAt.Valid {
+			return db.User{}, errUnverifiedAccount
+		}
+	case errors.Is(err, pgx.ErrNoRows):
+		user, err = sh.createUser(ctx, qtx, claims)
+		if err != nil {
+			return db.User{}, err
+		}
+	default:
+		return db.User{}, err
+	}
+
+	err = qtx.CreateUserIdentity(ctx, db.CreateUserIdentityParams{
+		UserID:   user.ID,
+		Provider: provider,
+		Subject:  claims.Subject,
+		Email:    claims.Email,
+	})
+	if err != nil {
+		return db.User{}, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return db.User{}, err
+	}
+	return user, nil
+}
+
+// createUser signs up a user from their SSO account, without a password.
+// Their username comes from the provider, with a number added if it's taken.
+func (sh *SSOHandler) createUser(ctx context.Context, qtx *db.Queries, claims *oidc.Claims) (db.User, error) {
+	base := ssoUsername(claims)
+	username := base
+	for range maxUsernameTries {
+		user, err := qtx.CreateUserWithoutPassword(ctx, db.CreateUserWithoutPasswordParams{
+			Email:    claims.Email,
+			Username: username,
+		})
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return user, err
+		}
+		username = fmt.Sprintf("%s%04d", base, rand.IntN(10000))
+	}
+	return db.User{}, fmt.Errorf("no free username like %q", base)
+}
+
+func ssoUsername(claims *oidc.Claims) string {
+	name := claims.PreferredUsername
+	if name == "" {
+		name, _, _ = strings.Cut(claims.Email, "@")
+	}
+	name = strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '.', r == '_', r == '-':
+			return r
+		default:
+			return -1
+		}
+	}, strings.ToLower(name))
+
+	if len(name) > 90 {
+		name = name[:90]
+	}
+	if len(name) < 3 {
+		name = "user" + name
+	}
+	return name
+}
@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/nabsk911/chronify/internal/auth"
+	"github.com/nabsk911/chronify/internal/db"
+	"github.com/nabsk911/chronify/internal/dbtest"
+	"github.com/nabsk911/chronify/internal/oidc"
+	"github.com/nabsk911/chronify/internal/oidc/oidctest"
+)
+
+func newTestKeySet(t *testing.T) *auth.KeySet {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	data := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	if err := os.WriteFile(filepath.Join(dir, "test.pem"), data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	keys, err := auth.LoadKeySet(dir, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return keys
+}
+
+func TestSSOCallback(t *testing.T) {
+	const (
+		state = "state-1"
+		nonce = "nonce-1"
+	)
+	verified := db.User{ID: testUserID, Email: "ada@example.com", Username: "ada", EmailVerifiedAt: pgtype.Timestamptz{Time: time.Now(), Valid: true}}
+	unverified := db.User{ID: testUserID, Email: "ada@example.com", Username: "ada", PasswordHash: "set-by-whoever-signed-up"}
+	newUserID := pgtype.UUID{Bytes: [16]byte{0: 0xa1}, Valid: true}
+
+	tests := []struct {
+		name       string
+		users      []db.User
+		modify     func(claims jwt.MapClaims)
+		wantStatus int
+		// wantLinked is the user the identity is linked to, if it is.
+		wantLinked db.User
+		// wantInserts counts the tries at creating a user.
+		wantInserts int
+	}{
+		{name: "existing verified account", users: []db.User{verified}, wantStatus: http.StatusOK, wantLinked: verified},
+		{name: "existing unverified local account", users: []db.User{unverified}, wantStatus: http.StatusConflict},
+		{name: "new user", wantStatus: http.StatusOK, wantLinked: db.User{ID: newUserID, Email: "ada@example.com"}, wantInserts: 2},
+		{name: "unverified email", users: []db.User{verified}, modify: func(c jwt.MapClaims) { c["email_verified"] = false }, wantStatus: http.StatusForbidden},
+		{name: "another login's nonce", users: []db.User{verified}, modify: func(c jwt.MapClaims) { c["nonce"] = "nonce-2" }, wantStatus: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idp := oidctest.NewIdP(t)
+			provider := oidc.NewProvider(oidc.Config{
+				Name:         "test",
+				Issuer:       idp.URL,
+				ClientID:     oidctest.ClientID,
+				ClientSecret: oidctest.ClientSecret,
+				RedirectURL:  "https://chronify.example/sso/callback",
+			})
+			claims := idp.Claims(nonce)
+			if tt.modify != nil {
+				tt.modify(claims)
+			}
+			code := idp.IssueCode(idp.Sign(t, claims))
+
+			fake := dbtest.New()
+			fake.On("UseOIDCLoginState", func(args []any) (any, error) {
+				if args[0] != auth.HashOIDCState(state) || args[1] != "test" {
+					return nil, nil
+				}
+				return db.UseOIDCLoginStateRow{Nonce: nonce, CodeVerifier: "verifier-1"}, nil
+			})
+			fake.On("GetUserByIdentity", func(args []any) (any, error) { return nil, nil })
+			fake.On("GetUserByEmail", func(args []any) (any, error) {
+				for _, u := range tt.users {
+					if u.Email == args[0] {
+						return u, nil
+					}
+				}
+				return nil, nil
+			})
+			fake.On("CreateUserWithoutPassword", func(args []any) (any, error) {
+				// Someone else already has the username from the email.
+				if args[1] == "ada" {
+					return nil, nil
+				}
+				return db.User{ID: newUserID, Email: args[0].(string), Username: args[1].(string), EmailVerifiedAt: pgtype.Timestamptz{Time: time.Now(), Valid: true}}, nil
+			})
+			fake.On("CreateUserIdentity", func(args []any) (any, error) { return nil, nil })
+			fake.On("GetTOTPByUserId", func(args []any) (any, error) { return nil, nil })
+			fake.On("CreateSession", func(args []any) (any, error) {
+				return db.Session{UserID: args[0].(pgtype.UUID), FamilyID: pgtype.UUID{Bytes: [16]byte{0: 0x5e}, Valid: true}}, nil
+			})
+			queries := db.New(fake)
+			userHandler := NewUserHandler(queries, nil, newTestKeySet(t), nil, "", false, nil, testLogger)
+			sh := NewSSOHandler(queries, fake, map[string]*oidc.Provider{"test": provider}, userHandler, testLogger)
+
+			r := httptest.NewRequest("POST", "/sso/test/callback", strings.NewReader(`{"code":"`+code+`","state":"`+state+`"}`))
+			r.SetPathValue("provider", "test")
+			w := httptest.NewRecorder()
+			sh.HandleCallback(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if !tt.wantLinked.ID.Valid {
+				for _, name := range []string{"CreateUserWithoutPassword", "CreateUserIdentity", "CreateSession"} {
+					if fake.Called(name) != 0 {
+						t.Errorf("%s ran", name)
+					}
+				}
+				if fake.Commits() != 0 {
+					t.Error("the transaction was committed")
+				}
+				return
+			}
+
+			var linked bool
+			for _, call := range fake.Calls() {
+				if call.Name == "CreateUserIdentity" {
+					linked = call.Args[0] == tt.wantLinked.ID && call.Args[1] == "test" && call.Args[2] == "idp-user-1" && call.Args[3] == tt.wantLinked.Email
+				}
+			}
+			if !linked || fake.Commits() != 1 {
+				t.Errorf("identity linked to the user: %v, %d commits, want linked in one commit", linked, fake.Commits())
+			}
+			if got := fake.Called("CreateUserWithoutPassword"); got != tt.wantInserts {
+				t.Errorf("%d tries at creating a user, want %d", got, tt.wantInserts)
+			}
+
+			var resp struct {
+				Token string `json:"token"`
+				User  struct {
+					Username      string `json:"username"`
+					EmailVerified bool   `json:"email_verified"`
+				} `json:"user"`
+			}
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatal(err)
+			}
+			if resp.Token == "" || resp.User.Username == "" || !resp.User.EmailVerified {
+				t.Errorf("response = %+v, want a token for a verified user", resp)
+			}
+		})
+	}
+}
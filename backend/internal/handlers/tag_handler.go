@@ -0,0 +1,406 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/nabsk911/chronify/internal/db"
+	"github.com/nabsk911/chronify/internal/utils"
+)
+
+const (
+	maxTagNameLength = 50
+	defaultTagColor  = "#6b7280"
+)
+
+var tagColorPattern = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+type tagRequest struct {
+	Name  string `json:"name"`
+	Color string `json:"color"`
+}
+
+type timelineTagsRequest struct {
+	TagIDs []pgtype.UUID `json:"tag_ids"`
+}
+
+// tagLabel is a tag as shown on the timelines and events it is attached to.
+type tagLabel struct {
+	ID    pgtype.UUID `json:"id"`
+	Name  string      `json:"name"`
+	Color string      `json:"color"`
+}
+
+type taggedEvent struct {
+	db.Event
+	Tags []tagLabel `json:"tags"`
+}
+
+type taggedTimeline struct {
+	db.GetTimelinesByUserIdRow
+	Tags []tagLabel `json:"tags"`
+}
+
+// isTagNameConflict reports whether err is the user already having another
+// tag with the same name, ignoring case.
+func isTagNameConflict(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505" && pgErr.ConstraintName == "tags_user_id_name_key"
+}
+
+// normalize trims the name and fills in the default color, then checks both.
+func (req *tagRequest) normalize() error {
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		return errors.New("name is required")
+	}
+	if utf8.RuneCountInString(req.Name) > maxTagNameLength {
+		return fmt.Errorf("name must be at most %d characters", maxTagNameLength)
+	}
+
+	if req.Color == "" {
+		req.Color = defaultTagColor
+	}
+	if !tagColorPattern.MatchString(req.Color) {
+		return errors.New("color must be a hex color like #1a2b3c")
+	}
+	req.Color = strings.ToLower(req.Color)
+	return nil
+}
+
+// readTagFilter reads the repeatable ?tag= parameter. A nil result means no
+// filter; otherwise rows with any of the tags match.
+func readTagFilter(r *http.Request) ([]pgtype.UUID, error) {
+	values := r.URL.Query()["tag"]
+	if len(values) == 0 {
+		return nil, nil
+	}
+	ids := make([]pgtype.UUID, len(values))
+	for i, v := range values {
+		if err := ids[i].Scan(v); err != nil {
+			return nil, err
+		}
+	}
+	return ids, nil
+}
+
+// unknownTags returns the tags in ids that don't belong to the user.
+func unknownTags(ctx context.Context, store *db.Queries, userID pgtype.UUID, ids []pgtype.UUID) (map[pgtype.UUID]bool, error) {
+	unknown := make(map[pgtype.UUID]bool)
+	if len(ids) == 0 {
+		return unknown, nil
+	}
+	owned, err := store.GetTagIdsByUserId(ctx, db.GetTagIdsByUserIdParams{
+		UserID: userID,
+		Ids:    ids,
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range ids {
+		unknown[id] = true
+	}
+	for _, id := range owned {
+		delete(unknown, id)
+	}
+	return unknown, nil
+}
+
+// tagEvents attaches each event's tags.
+func tagEvents(ctx context.Context, store *db.Queries, events []db.Event) ([]taggedEvent, error) {
+	tagged := make([]taggedEvent, len(events))
+	index := make(map[pgtype.UUID]int, len(events))
+	ids := make([]pgtype.UUID, len(events))
+	for i, e := range events {
+		tagged[i] = taggedEvent{Event: e, Tags: []tagLabel{}}
+		index[e.ID] = i
+		ids[i] = e.ID
+	}
+	if len(ids) == 0 {
+		return tagged, nil
+	}
+
+	tags, err := store.GetEventTagsByEventIds(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range tags {
+		i := index[t.EventID]
+		tagged[i].Tags = append(tagged[i].Tags, tagLabel{ID: t.ID, Name: t.Name, Color: t.Color})
+	}
+	return tagged, nil
+}
+
+// tagTimelines attaches the user's own tags to each timeline.
+func tagTimelines(ctx context.Context, store *db.Queries, userID pgtype.UUID, timelines []db.GetTimelinesByUserIdRow) ([]taggedTimeline, error) {
+	tagged := make([]taggedTimeline, len(timelines))
+	index := make(map[pgtype.UUID]int, len(timelines))
+	ids := make([]pgtype.UUID, len(timelines))
+	for i, t := range timelines {
+		tagged[i] = taggedTimeline{GetTimelinesByUserIdRow: t, Tags: []tagLabel{}}
+		index[t.ID] = i
+		ids[i] = t.ID
+	}
+	if len(ids) == 0 {
+		return tagged, nil
+	}
+
+	tags, err := store.GetTimelineTagsByTimelineIds(ctx, db.GetTimelineTagsByTimelineIdsParams{
+		UserID:      userID,
+		TimelineIds: ids,
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range tags {
+		i := index[t.TimelineID]
+		tagged[i].Tags = append(tagged[i].Tags, tagLabel{ID: t.ID, Name: t.Name, Color: t.Color})
+	}
+	return tagged, nil
+}
+
+type TagHandler struct {
+	tagStore *db.Queries
+	dbConn   *pgxpool.Pool
+	logger   *log.Logger
+}
+
+func NewTagHandler(tagStore *db.Queries, dbConn *pgxpool.Pool, logger *log.Logger) *TagHandler {
+	return &TagHandler{
+		tagStore: tagStore,
+		dbConn:   dbConn,
+		logger:   logger,
+	}
+}
+
+func (th *TagHandler) HandleGetTags(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.ReadUserID(r)
+	if err != nil {
+		th.logger.Printf("Invalid user ID format: %v", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Invalid user ID"})
+		return
+	}
+
+	tags, err := th.tagStore.GetTagsByUserId(r.Context(), userID)
+	if err != nil {
+		th.logger.Printf("Failed to retrieve tags: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to retrieve tags"})
+		return
+	}
+	if tags == nil {
+		tags = []db.Tag{}
+	}
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"data": tags})
+}
+
+func (th *TagHandler) HandleCreateTag(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.ReadUserID(r)
+	if err != nil {
+		th.logger.Printf("Invalid user ID format: %v", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Invalid user ID"})
+		return
+	}
+
+	var req tagRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		th.logger.Printf("Failed to decode request: %v", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Invalid request payload"})
+		return
+	}
+	if err := req.normalize(); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Invalid tag: " + err.Error()})
+		return
+	}
+
+	tag, err := th.tagStore.CreateTag(r.Context(), db.CreateTagParams{
+		UserID: userID,
+		Name:   req.Name,
+		Color:  req.Color,
+	})
+	if err != nil {
+		th.logger.Printf("Failed to create tag: %v", err)
+		if isTagNameConflict(err) {
+			utils.WriteJSON(w, http.StatusConflict, utils.Envelope{"message": "You already have a tag with this name"})
+			return
+		}
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to create tag"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, utils.Envelope{"data": tag, "message": "Tag created successfully"})
+}
+
+func (th *TagHandler) HandleUpdateTag(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.ReadUserID(r)
+	if err != nil {
+		th.logger.Printf("Invalid user ID format: %v", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Invalid user ID"})
+		return
+	}
+
+	tagID, err := utils.ReadIDParam(r, "tagId")
+	if err != nil {
+		th.logger.Printf("Invalid tag ID: %v", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Invalid tag ID"})
+		return
+	}
+
+	var req tagRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		th.logger.Printf("Failed to decode request: %v", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Invalid request payload"})
+		return
+	}
+	if err := req.normalize(); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Invalid tag: " + err.Error()})
+		return
+	}
+
+	tag, err := th.tagStore.UpdateTag(r.Context(), db.UpdateTagParams{
+		ID:     tagID,
+		UserID: userID,
+		Name:   req.Name,
+		Color:  req.Color,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"message": "Tag not found"})
+		return
+	}
+	if err != nil {
+		th.logger.Printf("Failed to update tag: %v", err)
+		if isTagNameConflict(err) {
+			utils.WriteJSON(w, http.StatusConflict, utils.Envelope{"message": "You already have a tag with this name"})
+			return
+		}
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to update tag"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"data": tag, "message": "Tag updated successfully"})
+}
+
+// HandleDeleteTag deletes the tag and removes it from every timeline and
+// event it was on.
+func (th *TagHandler) HandleDeleteTag(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.ReadUserID(r)
+	if err != nil {
+		th.logger.Printf("Invalid user ID format: %v", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Invalid user ID"})
+		return
+	}
+
+	tagID, err := utils.ReadIDParam(r, "tagId")
+	if err != nil {
+		th.logger.Printf("Invalid tag ID: %v", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Invalid tag ID"})
+		return
+	}
+
+	deleted, err := th.tagStore.DeleteTag(r.Context(), db.DeleteTagParams{
+		ID:     tagID,
+		UserID: userID,
+	})
+	if err != nil {
+		th.logger.Printf("Failed to delete tag: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to delete tag"})
+		return
+	}
+	if deleted == 0 {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"message": "Tag not found"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"message": "Tag deleted successfully"})
+}
+
+// HandleSetTimelineTags replaces the user's own tags on the timeline. Any
+// member can label a timeline; other members don't see the labels.
+func (th *TagHandler) HandleSetTimelineTags(w http.ResponseWriter, r *http.Request) {
+	timeline, ok := authorizeTimeline(w, r, th.tagStore, th.logger, roleViewer)
+	if !ok {
+		return
+	}
+
+	userID, err := utils.ReadUserID(r)
+	if err != nil {
+		th.logger.Printf("Invalid user ID format: %v", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Invalid user ID"})
+		return
+	}
+
+	var req timelineTagsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		th.logger.Printf("Failed to decode request: %v", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Invalid request payload"})
+		return
+	}
+
+	ctx := r.Context()
+
+	unknown, err := unknownTags(ctx, th.tagStore, userID, req.TagIDs)
+	if err != nil {
+		th.logger.Printf("Failed to check tags: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to tag timeline"})
+		return
+	}
+	if len(unknown) > 0 {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Tag not found"})
+		return
+	}
+
+	tx, err := th.dbConn.Begin(ctx)
+	if err != nil {
+		th.logger.Printf("Failed to begin transaction: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to tag timeline"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := th.tagStore.WithTx(tx)
+
+	if err := qtx.DeleteTimelineTagsByUserId(ctx, db.DeleteTimelineTagsByUserIdParams{
+		TimelineID: timeline.ID,
+		UserID:     userID,
+	}); err != nil {
+		th.logger.Printf("Failed to remove timeline tags: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to tag timeline"})
+		return
+	}
+
+	if len(req.TagIDs) > 0 {
+		if err := qtx.AddTimelineTags(ctx, db.AddTimelineTagsParams{
+			TimelineID: timeline.ID,
+			TagIds:     req.TagIDs,
+		}); err != nil {
+			th.logger.Printf("Failed to add timeline tags: %v", err)
+			utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to tag timeline"})
+			return
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		th.logger.Printf("Failed to commit transaction: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to tag timeline"})
+		return
+	}
+
+	tagged, err := tagTimelines(ctx, th.tagStore, userID, []db.GetTimelinesByUserIdRow{db.GetTimelinesByUserIdRow(timeline)})
+	if err != nil {
+		th.logger.Printf("Failed to retrieve timeline tags: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to retrieve timeline"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"data": tagged[0], "message": "Timeline tags updated successfully"})
+}
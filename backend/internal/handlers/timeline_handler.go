@@ -6,6 +6,7 @@ import (
 	"log"
 	"net/http"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/nabsk911/chronify/internal/db"
@@ -17,31 +18,39 @@ type timelineRequest struct {
 	Title       string      `json:"title"`
 	Description string      `json:"description,omitempty"`
 }
+
+// isTitleConflict reports whether err is the timeline's owner already having
+// another timeline with the same title, ignoring case.
+func isTitleConflict(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505" && pgErr.ConstraintName == "timelines_user_id_title_key"
+}
+
 type TimelineHandler struct {
 	timelineStore *db.Queries
+	dbConn        TxStarter
 	logger        *log.Logger
 }
 
-func NewTimelineHandler(timelineStore *db.Queries, logger *log.Logger) *TimelineHandler {
+func NewTimelineHandler(timelineStore *db.Queries, dbConn TxStarter, logger *log.Logger) *TimelineHandler {
 	return &TimelineHandler{
 		timelineStore: timelineStore,
+		dbConn:        dbConn,
 		logger:        logger,
 	}
 }
 
 // Create
 func (th *TimelineHandler) HandleCreateTimeline(w http.ResponseWriter, r *http.Request) {
-	userIDStr := r.Context().Value("userID").(string)
-
-	var userID pgtype.UUID
-	if err := userID.Scan(userIDStr); err != nil {
+	userID, err := utils.ReadUserID(r)
+	if err != nil {
 		th.logger.Printf("Invalid user ID format: %v", err)
 		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Invalid user ID"})
 		return
 	}
 
 	var req timelineRequest
-	err := json.NewDecoder(r.Body).Decode(&req)
+	err = json.NewDecoder(r.Body).Decode(&req)
 	if err != nil {
 		th.logger.Printf("Failed to decode timeline request: %v", err)
 		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Invalid request payload!"})
@@ -52,95 +61,211 @@ func (th *TimelineHandler) HandleCreateTimeline(w http.ResponseWriter, r *http.R
 		return
 	}
 
+	ctx := r.Context()
+
+	tx, err := th.dbConn.Begin(ctx)
+	if err != nil {
+		th.logger.Printf("Failed to begin transaction: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to create timeline"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := th.timelineStore.WithTx(tx)
+
 	// Convert string to pgtype.Text
-	timeline, err := th.timelineStore.CreateTimeline(r.Context(), db.CreateTimelineParams{
+	timeline, err := qtx.CreateTimeline(ctx, db.CreateTimelineParams{
 		UserID:      userID,
 		Title:       req.Title,
 		Description: pgtype.Text{String: req.Description, Valid: true},
 	})
 	if err != nil {
-		th.logger.Printf("Failed to create user in store: %v", err)
-		var pgErr *pgconn.PgError
-		if errors.As(err, &pgErr) {
-			if pgErr.Code == "23505" {
-				switch pgErr.ConstraintName {
-				case "timelines_title_key":
-					utils.WriteJSON(w, http.StatusConflict, utils.Envelope{"message": "Timeline with this title already exists"})
-					return
-				}
-			}
+		th.logger.Printf("Failed to create timeline: %v", err)
+		if isTitleConflict(err) {
+			utils.WriteJSON(w, http.StatusConflict, utils.Envelope{"message": "You already have a timeline with this title"})
+			return
 		}
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to create timeline"})
 		return
 
 	}
 
+	if err := recordRevision(ctx, qtx, timeline.ID, userID, revisionCreate, pgtype.Int4{}); err != nil {
+		th.logger.Printf("Failed to record revision: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to create timeline"})
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		th.logger.Printf("Failed to commit transaction: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to create timeline"})
+		return
+	}
+
 	utils.WriteJSON(w, http.StatusCreated, utils.Envelope{"data": timeline, "message": "Timeline created successfully"})
 }
 
 func (th *TimelineHandler) HandleGetTimelines(w http.ResponseWriter, r *http.Request) {
-	userIDStr := r.Context().Value("userID").(string)
-
-	var userID pgtype.UUID
-	if err := userID.Scan(userIDStr); err != nil {
+	userID, err := utils.ReadUserID(r)
+	if err != nil {
 		th.logger.Printf("Invalid user ID format: %v", err)
 		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Invalid user ID"})
 		return
 	}
 
-	timelines, err := th.timelineStore.GetTimelinesByUserId(r.Context(), userID)
-	if err != nil {
-		th.logger.Printf("Failed to retrieve timeline for user %s: %v", userIDStr, err)
-		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to retrieve timeline"})
-		return
-	}
-	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"data": timelines})
+	th.listTimelines(w, r, userID, "")
 }
 
 func (th *TimelineHandler) HandleGetTimelineById(w http.ResponseWriter, r *http.Request) {
-	timelineID, err := utils.ReadIDParam(r, "timelineId")
+	timeline, ok := authorizeTimeline(w, r, th.timelineStore, th.logger, roleViewer)
+	if !ok {
+		return
+	}
+
+	userID, err := utils.ReadUserID(r)
 	if err != nil {
-		th.logger.Printf("Invalid timeline ID: %v", err)
-		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Invalid timeline ID"})
+		th.logger.Printf("Invalid user ID format: %v", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Invalid user ID"})
 		return
 	}
-	timeline, err := th.timelineStore.GetTimeLineById(r.Context(), timelineID)
+
+	tagged, err := tagTimelines(r.Context(), th.timelineStore, userID, []db.GetTimelinesByUserIdRow{db.GetTimelinesByUserIdRow(timeline)})
 	if err != nil {
-		th.logger.Printf("Failed to retrieve timeline: %v", err)
+		th.logger.Printf("Failed to retrieve timeline tags: %v", err)
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to retrieve timeline"})
 		return
 	}
-	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"data": timeline})
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"data": tagged[0]})
 }
 
 func (th *TimelineHandler) HandleSearchTimeline(w http.ResponseWriter, r *http.Request) {
 	title := r.URL.Query().Get("title")
-	userIDStr := r.Context().Value("userID").(string)
-
-	var userID pgtype.UUID
-	if err := userID.Scan(userIDStr); err != nil {
+	userID, err := utils.ReadUserID(r)
+	if err != nil {
 		th.logger.Printf("Invalid user ID format: %v", err)
 		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Invalid user ID"})
 		return
 	}
 
-	timelines, err := th.timelineStore.GetTimelinesByUserIdAndTitle(r.Context(), db.GetTimelinesByUserIdAndTitleParams{
-		UserID:  userID,
-		Column2: pgtype.Text{String: title, Valid: true},
-	})
+	th.listTimelines(w, r, userID, title)
+}
+
+type timelineCreatedAtKey struct {
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+	ID        pgtype.UUID        `json:"id"`
+}
+
+type timelineTitleKey struct {
+	Title string      `json:"title"`
+	ID    pgtype.UUID `json:"id"`
+}
+
+// listTimelines writes a page of the user's timelines whose title contains
+// title, or all of them when it is empty. They are newest first unless
+// ?sort= asks for created_at, title or -title. With ?tag=, only timelines
+// the user tagged with one of the tags are listed.
+func (th *TimelineHandler) listTimelines(w http.ResponseWriter, r *http.Request, userID pgtype.UUID, title string) {
+	p, err := readPage(r, "-created_at", "created_at", "title", "-title")
 	if err != nil {
-		th.logger.Printf("Failed to retrieve timeline for user %s: %v", userIDStr, err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Invalid pagination: " + err.Error()})
+		return
+	}
+
+	tagIDs, err := readTagFilter(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Invalid tag ID"})
+		return
+	}
+
+	var timelines []db.GetTimelinesByUserIdRow
+	switch p.Sort {
+	case "-created_at", "created_at":
+		var after timelineCreatedAtKey
+		if err := p.readAfter(&after); err != nil {
+			utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Invalid pagination: " + err.Error()})
+			return
+		}
+		rows, err := th.timelineStore.GetTimelinesPageByCreatedAt(r.Context(), db.GetTimelinesPageByCreatedAtParams{
+			UserID:          userID,
+			Title:           title,
+			CursorID:        after.ID,
+			Descending:      p.Sort == "-created_at",
+			CursorCreatedAt: after.CreatedAt,
+			TagIds:          tagIDs,
+			MaxRows:         int32(p.Limit + 1),
+		})
+		if err != nil {
+			th.logger.Printf("Failed to retrieve timeline for user %s: %v", userID.String(), err)
+			utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to retrieve timeline"})
+			return
+		}
+		for _, row := range rows {
+			timelines = append(timelines, db.GetTimelinesByUserIdRow(row))
+		}
+	case "title", "-title":
+		var after timelineTitleKey
+		if err := p.readAfter(&after); err != nil {
+			utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Invalid pagination: " + err.Error()})
+			return
+		}
+		rows, err := th.timelineStore.GetTimelinesPageByTitle(r.Context(), db.GetTimelinesPageByTitleParams{
+			UserID:      userID,
+			Title:       title,
+			CursorID:    after.ID,
+			Descending:  p.Sort == "-title",
+			CursorTitle: pgtype.Text{String: after.Title, Valid: after.ID.Valid},
+			TagIds:      tagIDs,
+			MaxRows:     int32(p.Limit + 1),
+		})
+		if err != nil {
+			th.logger.Printf("Failed to retrieve timeline for user %s: %v", userID.String(), err)
+			utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to retrieve timeline"})
+			return
+		}
+		for _, row := range rows {
+			timelines = append(timelines, db.GetTimelinesByUserIdRow(row))
+		}
+	}
+
+	var total *int64
+	if p.Count {
+		count, err := th.timelineStore.CountTimelinesByUserId(r.Context(), db.CountTimelinesByUserIdParams{
+			UserID: userID,
+			Title:  title,
+			TagIds: tagIDs,
+		})
+		if err != nil {
+			th.logger.Printf("Failed to count timelines for user %s: %v", userID.String(), err)
+			utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to retrieve timeline"})
+			return
+		}
+		total = &count
+	}
+
+	tagged, err := tagTimelines(r.Context(), th.timelineStore, userID, timelines)
+	if err != nil {
+		th.logger.Printf("Failed to retrieve timeline tags for user %s: %v", userID.String(), err)
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to retrieve timeline"})
 		return
 	}
-	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"data": timelines})
+
+	utils.WriteJSON(w, http.StatusOK, pageEnvelope(p, "data", tagged, func(t taggedTimeline) any {
+		if p.Sort == "title" || p.Sort == "-title" {
+			return timelineTitleKey{Title: t.Title, ID: t.ID}
+		}
+		return timelineCreatedAtKey{CreatedAt: t.CreatedAt, ID: t.ID}
+	}, total))
 }
 
 func (th *TimelineHandler) HandleUpdateTimeline(w http.ResponseWriter, r *http.Request) {
-	timelineID, err := utils.ReadIDParam(r, "timelineId")
+	current, ok := authorizeTimeline(w, r, th.timelineStore, th.logger, roleEditor)
+	if !ok {
+		return
+	}
+	userID, err := utils.ReadUserID(r)
 	if err != nil {
-		th.logger.Printf("Invalid timeline ID: %v", err)
-		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Invalid timeline ID"})
+		th.logger.Printf("Invalid user ID format: %v", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Invalid user ID"})
 		return
 	}
 	var req timelineRequest
@@ -155,14 +280,53 @@ func (th *TimelineHandler) HandleUpdateTimeline(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	timeline, err := th.timelineStore.UpdateTimeline(r.Context(), db.UpdateTimelineParams{
-		ID:          timelineID,
+	ctx := r.Context()
+
+	tx, err := th.dbConn.Begin(ctx)
+	if err != nil {
+		th.logger.Printf("Failed to begin transaction: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to update timeline"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := th.timelineStore.WithTx(tx)
+
+	if err := qtx.LockTimeline(ctx, current.ID); err != nil {
+		th.logger.Printf("Failed to lock timeline: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to update timeline"})
+		return
+	}
+
+	timeline, err := qtx.UpdateTimeline(ctx, db.UpdateTimelineParams{
+		ID:          current.ID,
 		Title:       req.Title,
 		Description: pgtype.Text{String: req.Description, Valid: true},
+		UserID:      userID,
 	})
 
 	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"message": "Timeline not found"})
+			return
+		}
 		th.logger.Printf("Failed to update timeline: %v", err)
+		if isTitleConflict(err) {
+			utils.WriteJSON(w, http.StatusConflict, utils.Envelope{"message": "The owner already has a timeline with this title"})
+			return
+		}
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to update timeline"})
+		return
+	}
+
+	if err := recordRevision(ctx, qtx, timeline.ID, userID, revisionUpdate, pgtype.Int4{}); err != nil {
+		th.logger.Printf("Failed to record revision: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to update timeline"})
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		th.logger.Printf("Failed to commit transaction: %v", err)
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to update timeline"})
 		return
 	}
@@ -170,17 +334,61 @@ func (th *TimelineHandler) HandleUpdateTimeline(w http.ResponseWriter, r *http.R
 }
 
 func (th *TimelineHandler) HandleDeleteTimeline(w http.ResponseWriter, r *http.Request) {
-	timelineID, err := utils.ReadIDParam(r, "timelineId")
+	timeline, ok := authorizeTimeline(w, r, th.timelineStore, th.logger, roleOwner)
+	if !ok {
+		return
+	}
+	userID, err := utils.ReadUserID(r)
 	if err != nil {
-		th.logger.Printf("Invalid timeline ID: %v", err)
-		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Invalid timeline ID"})
+		th.logger.Printf("Invalid user ID format: %v", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Invalid user ID"})
 		return
 	}
-	err = th.timelineStore.DeleteTimeline(r.Context(), timelineID)
+
+	ctx := r.Context()
+
+	tx, err := th.dbConn.Begin(ctx)
+	if err != nil {
+		th.logger.Printf("Failed to begin transaction: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to delete timeline"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := th.timelineStore.WithTx(tx)
+
+	if err := qtx.LockTimeline(ctx, timeline.ID); err != nil {
+		th.logger.Printf("Failed to lock timeline: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to delete timeline"})
+		return
+	}
+
+	deleted, err := qtx.TrashTimeline(ctx, db.TrashTimelineParams{
+		ID:     timeline.ID,
+		UserID: userID,
+	})
 	if err != nil {
 		th.logger.Printf("Failed to delete timeline: %v", err)
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to delete timeline"})
 		return
 	}
-	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"message": "Timeline deleted successfully"})
+	if deleted == 0 {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"message": "Timeline not found"})
+		return
+	}
+
+	// Restoring it from the trash records revisionRestoreTimeline, so the
+	// history shows both.
+	if err := recordRevision(ctx, qtx, timeline.ID, userID, revisionTrashTimeline, pgtype.Int4{}); err != nil {
+		th.logger.Printf("Failed to record revision: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to delete timeline"})
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		th.logger.Printf("Failed to commit transaction: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to delete timeline"})
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"message": "Timeline moved to trash"})
 }
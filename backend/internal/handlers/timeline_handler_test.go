@@ -0,0 +1,28 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/nabsk911/chronify/internal/db"
+)
+
+func TestDeleteTimelineRecordsRevision(t *testing.T) {
+	fake := newFakeTimeline(t)
+	fake.On("TrashTimeline", func(args []any) (any, error) { return int64(1), nil })
+	th := NewTimelineHandler(db.New(fake), fake, testLogger)
+
+	w := httptest.NewRecorder()
+	th.HandleDeleteTimeline(w, newTimelineRequest("DELETE", "/timelines/x", ""))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", w.Code, w.Body)
+	}
+	if !slices.Equal(fake.revisions, []string{revisionTrashTimeline}) {
+		t.Errorf("revisions = %q, want %q", fake.revisions, revisionTrashTimeline)
+	}
+	if fake.Commits() != 1 || fake.Called("LockTimeline") != 1 {
+		t.Errorf("%d commits, %d locks, want the trash and its revision saved together under the lock", fake.Commits(), fake.Called("LockTimeline"))
+	}
+}
@@ -0,0 +1,265 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/nabsk911/chronify/internal/db"
+	"github.com/nabsk911/chronify/internal/utils"
+)
+
+// TrashHandler serves the trash: timelines the user owns and events from
+// timelines they can edit that were deleted, until they are restored or
+// purged.
+type TrashHandler struct {
+	store     *db.Queries
+	dbConn    TxStarter
+	retention time.Duration
+	logger    *log.Logger
+}
+
+func NewTrashHandler(store *db.Queries, dbConn TxStarter, retention time.Duration, logger *log.Logger) *TrashHandler {
+	return &TrashHandler{
+		store:     store,
+		dbConn:    dbConn,
+		retention: retention,
+		logger:    logger,
+	}
+}
+
+func (th *TrashHandler) HandleGetTrash(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.ReadUserID(r)
+	if err != nil {
+		th.logger.Printf("Invalid user ID format: %v", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Invalid user ID"})
+		return
+	}
+
+	timelines, err := th.store.GetTrashedTimelinesByUserId(r.Context(), userID)
+	if err != nil {
+		th.logger.Printf("Failed to retrieve trashed timelines: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to retrieve trash"})
+		return
+	}
+	events, err := th.store.GetTrashedEventsByUserId(r.Context(), userID)
+	if err != nil {
+		th.logger.Printf("Failed to retrieve trashed events: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to retrieve trash"})
+		return
+	}
+
+	if timelines == nil {
+		timelines = []db.GetTrashedTimelinesByUserIdRow{}
+	}
+	if events == nil {
+		events = []db.GetTrashedEventsByUserIdRow{}
+	}
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{
+		"timelines":      timelines,
+		"events":         events,
+		"retention_days": int(th.retention.Hours() / 24),
+	})
+}
+
+// HandleRestoreTrashItem takes a timeline or an event out of the trash. The
+// restore is recorded as a new revision of the timeline.
+func (th *TrashHandler) HandleRestoreTrashItem(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.ReadUserID(r)
+	if err != nil {
+		th.logger.Printf("Invalid user ID format: %v", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Invalid user ID"})
+		return
+	}
+
+	id, err := utils.ReadIDParam(r, "id")
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Invalid ID"})
+		return
+	}
+
+	ctx := r.Context()
+
+	tx, err := th.dbConn.Begin(ctx)
+	if err != nil {
+		th.logger.Printf("Failed to begin transaction: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to restore item"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := th.store.WithTx(tx)
+
+	// id may be an event's, in which case this locks nothing.
+	if err := qtx.LockTimeline(ctx, id); err != nil {
+		th.logger.Printf("Failed to lock timeline: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to restore item"})
+		return
+	}
+
+	restored, err := qtx.RestoreTimeline(ctx, db.RestoreTimelineParams{
+		ID:     id,
+		UserID: userID,
+	})
+	if err != nil {
+		th.logger.Printf("Failed to restore timeline: %v", err)
+		if isTitleConflict(err) {
+			utils.WriteJSON(w, http.StatusConflict, utils.Envelope{"message": "You already have a timeline with this title"})
+			return
+		}
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to restore item"})
+		return
+	}
+	if restored > 0 {
+		if err := recordRevision(ctx, qtx, id, userID, revisionRestoreTimeline, pgtype.Int4{}); err != nil {
+			th.logger.Printf("Failed to record revision: %v", err)
+			utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to restore item"})
+			return
+		}
+		if err := tx.Commit(ctx); err != nil {
+			th.logger.Printf("Failed to commit transaction: %v", err)
+			utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to restore item"})
+			return
+		}
+		utils.WriteJSON(w, http.StatusOK, utils.Envelope{"message": "Timeline restored successfully"})
+		return
+	}
+
+	timelineID, err := qtx.GetTrashedEventTimelineId(ctx, db.GetTrashedEventTimelineIdParams{
+		ID:     id,
+		UserID: userID,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"message": "Item not found in trash"})
+		return
+	}
+	if err != nil {
+		th.logger.Printf("Failed to retrieve trashed event: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to restore item"})
+		return
+	}
+
+	if err := qtx.LockTimeline(ctx, timelineID); err != nil {
+		th.logger.Printf("Failed to lock timeline: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to restore item"})
+		return
+	}
+
+	restored, err = qtx.RestoreEvent(ctx, db.RestoreEventParams{
+		ID:         id,
+		TimelineID: timelineID,
+	})
+	if err != nil {
+		th.logger.Printf("Failed to restore event: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to restore item"})
+		return
+	}
+	if restored == 0 {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"message": "Item not found in trash"})
+		return
+	}
+
+	if err := recordRevision(ctx, qtx, timelineID, userID, revisionRestoreEvent, pgtype.Int4{}); err != nil {
+		th.logger.Printf("Failed to record revision: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to restore item"})
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		th.logger.Printf("Failed to commit transaction: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to restore item"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"message": "Event restored successfully"})
+}
+
+// HandlePurgeTrashItem permanently deletes a timeline or an event from the
+// trash.
+func (th *TrashHandler) HandlePurgeTrashItem(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.ReadUserID(r)
+	if err != nil {
+		th.logger.Printf("Invalid user ID format: %v", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Invalid user ID"})
+		return
+	}
+
+	id, err := utils.ReadIDParam(r, "id")
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Invalid ID"})
+		return
+	}
+
+	purged, err := th.store.PurgeTimeline(r.Context(), db.PurgeTimelineParams{
+		ID:     id,
+		UserID: userID,
+	})
+	if err != nil {
+		th.logger.Printf("Failed to purge timeline: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to purge item"})
+		return
+	}
+	if purged == 0 {
+		purged, err = th.store.PurgeEvent(r.Context(), db.PurgeEventParams{
+			ID:     id,
+			UserID: userID,
+		})
+		if err != nil {
+			th.logger.Printf("Failed to purge event: %v", err)
+			utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to purge item"})
+			return
+		}
+	}
+	if purged == 0 {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"message": "Item not found in trash"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"message": "Item permanently deleted"})
+}
+
+// HandleEmptyTrash permanently deletes everything in the user's trash.
+func (th *TrashHandler) HandleEmptyTrash(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.ReadUserID(r)
+	if err != nil {
+		th.logger.Printf("Invalid user ID format: %v", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Invalid user ID"})
+		return
+	}
+
+	ctx := r.Context()
+
+	tx, err := th.dbConn.Begin(ctx)
+	if err != nil {
+		th.logger.Printf("Failed to begin transaction: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to empty trash"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := th.store.WithTx(tx)
+
+	events, err := qtx.PurgeEventsByUserId(ctx, userID)
+	if err != nil {
+		th.logger.Printf("Failed to purge events: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to empty trash"})
+		return
+	}
+	timelines, err := qtx.PurgeTimelinesByUserId(ctx, userID)
+	if err != nil {
+		th.logger.Printf("Failed to purge timelines: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to empty trash"})
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		th.logger.Printf("Failed to commit transaction: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to empty trash"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"message": "Trash emptied", "timelines": timelines, "events": events})
+}
@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/nabsk911/chronify/internal/db"
+)
+
+func TestRestoreTrashItem(t *testing.T) {
+	trashedEventID := testEventID(0x20)
+	tests := []struct {
+		name       string
+		id         pgtype.UUID
+		wantStatus int
+		wantAction string
+	}{
+		{"timeline", testTimelineID, http.StatusOK, revisionRestoreTimeline},
+		{"event", trashedEventID, http.StatusOK, revisionRestoreEvent},
+		{"neither", testEventID(0x30), http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeTimeline(t)
+			var locked []pgtype.UUID
+			fake.On("LockTimeline", func(args []any) (any, error) {
+				locked = append(locked, args[0].(pgtype.UUID))
+				return nil, nil
+			})
+			fake.On("RestoreTimeline", func(args []any) (any, error) {
+				if args[0] == testTimelineID {
+					return int64(1), nil
+				}
+				return int64(0), nil
+			})
+			fake.On("GetTrashedEventTimelineId", func(args []any) (any, error) {
+				if args[0] == trashedEventID {
+					return testTimelineID, nil
+				}
+				return nil, nil
+			})
+			fake.On("RestoreEvent", func(args []any) (any, error) { return int64(1), nil })
+			th := NewTrashHandler(db.New(fake), fake, 30*24*time.Hour, testLogger)
+
+			r := newTimelineRequest("POST", "/trash/x/restore", "")
+			r.SetPathValue("id", tt.id.String())
+			rec := httptest.NewRecorder()
+			th.HandleRestoreTrashItem(rec, r)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+
+			if tt.wantAction == "" {
+				if len(fake.revisions) != 0 || fake.Commits() != 0 {
+					t.Errorf("recorded revisions %q in %d commits, want none", fake.revisions, fake.Commits())
+				}
+				return
+			}
+			if !slices.Equal(fake.revisions, []string{tt.wantAction}) {
+				t.Errorf("revisions = %q, want %q", fake.revisions, tt.wantAction)
+			}
+			if fake.Commits() != 1 {
+				t.Errorf("%d commits, want the restore and its revision saved together", fake.Commits())
+			}
+			if !slices.Contains(locked, testTimelineID) {
+				t.Errorf("locked %v, not the timeline", locked)
+			}
+		})
+	}
+}
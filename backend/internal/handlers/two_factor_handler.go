@@ -0,0 +1,551 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/nabsk911/chronify/internal/auth"
+	"github.com/nabsk911/chronify/internal/db"
+	"github.com/nabsk911/chronify/internal/utils"
+)
+
+// A user can get their second factor wrong this many times in a row, then
+// once more a minute.
+const (
+	maxTwoFactorFailures          = 5
+	maxTwoFactorFailuresPerMinute = 1
+)
+
+var errTOTPNotConfigured = errors.New("TOTP_ENCRYPTION_KEY is not set")
+
+type twoFactorRequest struct {
+	Password string `json:"password,omitempty"`
+	Code     string `json:"code,omitempty"`
+}
+
+type loginChallengeRequest struct {
+	ChallengeToken string `json:"challenge_token"`
+	Code           string `json:"code"`
+}
+
+func (uh *UserHandler) openTOTPSecret(totp db.UserTotp) ([]byte, error) {
+	if uh.totpBox == nil {
+		return nil, errTOTPNotConfigured
+	}
+	return uh.totpBox.Open(totp.Secret, totp.UserID.Bytes[:])
+}
+
+// checkSecondFactor reports whether code is a current TOTP code or an unused
+// recovery code for the user, and uses it up if it is. A wrong code counts
+// against the user's attempts; callers check twoFactorLocked first.
+func (uh *UserHandler) checkSecondFactor(ctx context.Context, store *db.Queries, totp db.UserTotp, code string) (bool, error) {
+	ok, err := uh.useSecondFactor(ctx, store, totp, code)
+	if err == nil && !ok {
+		// Taking a token from the bucket counts the failure.
+		uh.twoFactorFailures.Allow(totp.UserID.String())
+	}
+	return ok, err
+}
+
+func (uh *UserHandler) useSecondFactor(ctx context.Context, store *db.Queries, totp db.UserTotp, code string) (bool, error) {
+	if auth.IsRecoveryCode(code) {
+		used, err := store.UseRecoveryCode(ctx, db.UseRecoveryCodeParams{
+			UserID:   totp.UserID,
+			CodeHash: auth.HashRecoveryCode(code),
+		})
+		return used > 0, err
+	}
+
+	secret, err := uh.openTOTPSecret(totp)
+	if err != nil {
+		return false, err
+	}
+	step, ok := auth.ValidateTOTP(secret, code, time.Now())
+	if !ok {
+		return false, nil
+	}
+	used, err := store.UseTOTPStep(ctx, db.UseTOTPStepParams{
+		UserID:       totp.UserID,
+		LastUsedStep: step,
+	})
+	return used > 0, err
+}
+
+// twoFactorLocked answers 429 when the user has run out of second-factor
+// attempts for now.
+func (uh *UserHandler) twoFactorLocked(w http.ResponseWriter, userID pgtype.UUID) bool {
+	if wait := uh.twoFactorFailures.Wait(userID.String()); wait > 0 {
+		writeTooManyRequests(w, wait, "Too many wrong codes, try again later")
+		return true
+	}
+	return false
+}
+
+// checkPassword re-checks the user's password before 2FA settings change, so
+// a stolen access token alone can't change them.
+func (uh *UserHandler) checkPassword(ctx context.Context, userID pgtype.UUID, password string) (bool, error) {
+	user, err := uh.userStore.GetUserById(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	return auth.CheckPasswordHash(password, user.PasswordHash)
+}
+
+// issueLoginChallenge returns a token that stands for the user's correct
+// password until they give their second factor.
+func (uh *UserHandler) issueLoginChallenge(ctx context.Context, userID pgtype.UUID) (string, error) {
+	if err := uh.userStore.DeleteExpiredLoginChallenges(ctx, userID); err != nil {
+		return "", err
+	}
+
+	token, tokenHash, err := auth.GenerateLoginChallenge()
+	if err != nil {
+		return "", err
+	}
+
+	err = uh.userStore.CreateLoginChallenge(ctx, db.CreateLoginChallengeParams{
+		UserID:    userID,
+		TokenHash: tokenHash,
+		ExpiresAt: pgtype.Timestamptz{Time: time.Now().Add(auth.LoginChallengeTTL), Valid: true},
+	})
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// replaceRecoveryCodes swaps the user's recovery codes for a new set, which
+// is returned to be shown once.
+func replaceRecoveryCodes(ctx context.Context, store *db.Queries, userID pgtype.UUID) ([]string, error) {
+	codes, hashes, err := auth.GenerateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := store.DeleteRecoveryCodes(ctx, userID); err != nil {
+		return nil, err
+	}
+	err = store.CreateRecoveryCodes(ctx, db.CreateRecoveryCodesParams{
+		UserID:     userID,
+		CodeHashes: hashes,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// Second login step
+func (uh *UserHandler) HandleLoginTwoFactor(w http.ResponseWriter, r *http.Request) {
+	var req loginChallengeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		uh.logger.Printf("Failed to decode two-factor login request: %v", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Invalid request payload!"})
+		return
+	}
+
+	if req.ChallengeToken == "" || req.Code == "" {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Challenge token and code are required"})
+		return
+	}
+
+	challengeHash := auth.HashLoginChallenge(req.ChallengeToken)
+
+	userID, err := uh.userStore.AttemptLoginChallenge(r.Context(), db.AttemptLoginChallengeParams{
+		TokenHash:   challengeHash,
+		MaxAttempts: auth.MaxLoginChallengeAttempts,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			utils.WriteJSON(w, http.StatusUnauthorized, utils.Envelope{"message": "Invalid or expired challenge, please log in again"})
+			return
+		}
+		uh.logger.Printf("Failed to check login challenge: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Internal server error!"})
+		return
+	}
+
+	totp, err := uh.userStore.GetTOTPByUserId(r.Context(), userID)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		uh.logger.Printf("Failed to retrieve TOTP for user %s: %v", userID.String(), err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Internal server error!"})
+		return
+	}
+	if err != nil || !totp.ConfirmedAt.Valid {
+		utils.WriteJSON(w, http.StatusUnauthorized, utils.Envelope{"message": "Invalid or expired challenge, please log in again"})
+		return
+	}
+
+	if uh.twoFactorLocked(w, userID) {
+		return
+	}
+	ok, err := uh.checkSecondFactor(r.Context(), uh.userStore, totp, req.Code)
+	if err != nil {
+		uh.logger.Printf("Failed to check second factor for user %s: %v", userID.String(), err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Internal server error!"})
+		return
+	}
+	if !ok {
+		utils.WriteJSON(w, http.StatusUnauthorized, utils.Envelope{"message": "Invalid code"})
+		return
+	}
+
+	if err := uh.userStore.DeleteLoginChallenge(r.Context(), challengeHash); err != nil {
+		uh.logger.Printf("Failed to delete login challenge: %v", err)
+	}
+
+	user, err := uh.userStore.GetUserById(r.Context(), userID)
+	if err != nil {
+		uh.logger.Printf("Failed to retrieve user %s: %v", userID.String(), err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Internal server error!"})
+		return
+	}
+
+	uh.completeLogin(w, r, user)
+}
+
+func (uh *UserHandler) HandleGetTwoFactor(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.ReadUserID(r)
+	if err != nil {
+		uh.logger.Printf("Invalid user ID format: %v", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Invalid user ID"})
+		return
+	}
+
+	totp, err := uh.userStore.GetTOTPByUserId(r.Context(), userID)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		uh.logger.Printf("Failed to retrieve TOTP for user %s: %v", userID.String(), err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to retrieve two-factor authentication"})
+		return
+	}
+	enabled := err == nil && totp.ConfirmedAt.Valid
+
+	var remaining int64
+	if enabled {
+		remaining, err = uh.userStore.CountUnusedRecoveryCodes(r.Context(), userID)
+		if err != nil {
+			uh.logger.Printf("Failed to count recovery codes for user %s: %v", userID.String(), err)
+			utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to retrieve two-factor authentication"})
+			return
+		}
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"data": map[string]any{
+		"enabled":                  enabled,
+		"enabled_at":               totp.ConfirmedAt,
+		"recovery_codes_remaining": remaining,
+	}})
+}
+
+// HandleEnrollTwoFactor starts enrolling with a new secret. It isn't on
+// until it has been confirmed with a first code.
+func (uh *UserHandler) HandleEnrollTwoFactor(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.ReadUserID(r)
+	if err != nil {
+		uh.logger.Printf("Invalid user ID format: %v", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Invalid user ID"})
+		return
+	}
+
+	if uh.totpBox == nil {
+		utils.WriteJSON(w, http.StatusServiceUnavailable, utils.Envelope{"message": "Two-factor authentication is not available"})
+		return
+	}
+
+	var req twoFactorRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		uh.logger.Printf("Failed to decode request: %v", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Invalid request payload"})
+		return
+	}
+
+	ok, err := uh.checkPassword(r.Context(), userID, req.Password)
+	if err != nil {
+		uh.logger.Printf("Failed to check password for user %s: %v", userID.String(), err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to enroll in two-factor authentication"})
+		return
+	}
+	if !ok {
+		utils.WriteJSON(w, http.StatusUnauthorized, utils.Envelope{"message": "Invalid password"})
+		return
+	}
+
+	user, err := uh.userStore.GetUserById(r.Context(), userID)
+	if err != nil {
+		uh.logger.Printf("Failed to retrieve user %s: %v", userID.String(), err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to enroll in two-factor authentication"})
+		return
+	}
+
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		uh.logger.Printf("Failed to generate TOTP secret: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to enroll in two-factor authentication"})
+		return
+	}
+	sealed, err := uh.totpBox.Seal(secret, userID.Bytes[:])
+	if err != nil {
+		uh.logger.Printf("Failed to encrypt TOTP secret: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to enroll in two-factor authentication"})
+		return
+	}
+
+	created, err := uh.userStore.CreatePendingTOTP(r.Context(), db.CreatePendingTOTPParams{
+		UserID: userID,
+		Secret: sealed,
+	})
+	if err != nil {
+		uh.logger.Printf("Failed to store TOTP secret: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to enroll in two-factor authentication"})
+		return
+	}
+	if created == 0 {
+		utils.WriteJSON(w, http.StatusConflict, utils.Envelope{"message": "Two-factor authentication is already enabled"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"data": map[string]any{
+		"secret":      auth.EncodeTOTPSecret(secret),
+		"otpauth_uri": auth.TOTPURI(user.Email, secret),
+	}})
+}
+
+// HandleConfirmTwoFactor turns 2FA on once the user shows their
+// authenticator works, and returns their recovery codes. They are only ever
+// shown here.
+func (uh *UserHandler) HandleConfirmTwoFactor(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.ReadUserID(r)
+	if err != nil {
+		uh.logger.Printf("Invalid user ID format: %v", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Invalid user ID"})
+		return
+	}
+
+	var req twoFactorRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		uh.logger.Printf("Failed to decode request: %v", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Invalid request payload"})
+		return
+	}
+
+	totp, err := uh.userStore.GetTOTPByUserId(r.Context(), userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			utils.WriteJSON(w, http.StatusConflict, utils.Envelope{"message": "Two-factor authentication enrollment has not been started"})
+			return
+		}
+		uh.logger.Printf("Failed to retrieve TOTP for user %s: %v", userID.String(), err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to enable two-factor authentication"})
+		return
+	}
+	if totp.ConfirmedAt.Valid {
+		utils.WriteJSON(w, http.StatusConflict, utils.Envelope{"message": "Two-factor authentication is already enabled"})
+		return
+	}
+
+	secret, err := uh.openTOTPSecret(totp)
+	if err != nil {
+		uh.logger.Printf("Failed to decrypt TOTP secret for user %s: %v", userID.String(), err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to enable two-factor authentication"})
+		return
+	}
+	step, ok := auth.ValidateTOTP(secret, req.Code, time.Now())
+	if !ok {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Invalid code"})
+		return
+	}
+
+	tx, err := uh.dbConn.Begin(r.Context())
+	if err != nil {
+		uh.logger.Printf("Failed to begin transaction: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to enable two-factor authentication"})
+		return
+	}
+	defer tx.Rollback(r.Context())
+	qtx := uh.userStore.WithTx(tx)
+
+	confirmed, err := qtx.ConfirmTOTP(r.Context(), db.ConfirmTOTPParams{
+		UserID:       userID,
+		LastUsedStep: step,
+	})
+	if err != nil {
+		uh.logger.Printf("Failed to confirm TOTP for user %s: %v", userID.String(), err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to enable two-factor authentication"})
+		return
+	}
+	if confirmed == 0 {
+		utils.WriteJSON(w, http.StatusConflict, utils.Envelope{"message": "Two-factor authentication is already enabled"})
+		return
+	}
+
+	codes, err := replaceRecoveryCodes(r.Context(), qtx, userID)
+	if err != nil {
+		uh.logger.Printf("Failed to create recovery codes for user %s: %v", userID.String(), err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to enable two-factor authentication"})
+		return
+	}
+
+	if err := tx.Commit(r.Context()); err != nil {
+		uh.logger.Printf("Failed to commit transaction: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to enable two-factor authentication"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{
+		"message": "Two-factor authentication enabled",
+		"data":    map[string]any{"recovery_codes": codes},
+	})
+}
+
+// HandleRegenerateRecoveryCodes replaces the user's recovery codes, used or
+// not, with a new set.
+func (uh *UserHandler) HandleRegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.ReadUserID(r)
+	if err != nil {
+		uh.logger.Printf("Invalid user ID format: %v", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Invalid user ID"})
+		return
+	}
+
+	var req twoFactorRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		uh.logger.Printf("Failed to decode request: %v", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Invalid request payload"})
+		return
+	}
+
+	totp, err := uh.userStore.GetTOTPByUserId(r.Context(), userID)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		uh.logger.Printf("Failed to retrieve TOTP for user %s: %v", userID.String(), err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to regenerate recovery codes"})
+		return
+	}
+	if err != nil || !totp.ConfirmedAt.Valid {
+		utils.WriteJSON(w, http.StatusConflict, utils.Envelope{"message": "Two-factor authentication is not enabled"})
+		return
+	}
+	if uh.twoFactorLocked(w, userID) {
+		return
+	}
+
+	tx, err := uh.dbConn.Begin(r.Context())
+	if err != nil {
+		uh.logger.Printf("Failed to begin transaction: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to regenerate recovery codes"})
+		return
+	}
+	defer tx.Rollback(r.Context())
+	qtx := uh.userStore.WithTx(tx)
+
+	ok, err := uh.checkSecondFactor(r.Context(), qtx, totp, req.Code)
+	if err != nil {
+		uh.logger.Printf("Failed to check second factor for user %s: %v", userID.String(), err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to regenerate recovery codes"})
+		return
+	}
+	if !ok {
+		utils.WriteJSON(w, http.StatusUnauthorized, utils.Envelope{"message": "Invalid code"})
+		return
+	}
+
+	codes, err := replaceRecoveryCodes(r.Context(), qtx, userID)
+	if err != nil {
+		uh.logger.Printf("Failed to create recovery codes for user %s: %v", userID.String(), err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to regenerate recovery codes"})
+		return
+	}
+
+	if err := tx.Commit(r.Context()); err != nil {
+		uh.logger.Printf("Failed to commit transaction: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to regenerate recovery codes"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"data": map[string]any{"recovery_codes": codes}})
+}
+
+// HandleDisableTwoFactor needs both the password and a code, so neither a
+// stolen password nor a lost phone is enough on its own.
+func (uh *UserHandler) HandleDisableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.ReadUserID(r)
+	if err != nil {
+		uh.logger.Printf("Invalid user ID format: %v", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Invalid user ID"})
+		return
+	}
+
+	var req twoFactorRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		uh.logger.Printf("Failed to decode request: %v", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"message": "Invalid request payload"})
+		return
+	}
+
+	ok, err := uh.checkPassword(r.Context(), userID, req.Password)
+	if err != nil {
+		uh.logger.Printf("Failed to check password for user %s: %v", userID.String(), err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to disable two-factor authentication"})
+		return
+	}
+	if !ok {
+		utils.WriteJSON(w, http.StatusUnauthorized, utils.Envelope{"message": "Invalid password"})
+		return
+	}
+
+	totp, err := uh.userStore.GetTOTPByUserId(r.Context(), userID)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		uh.logger.Printf("Failed to retrieve TOTP for user %s: %v", userID.String(), err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to disable two-factor authentication"})
+		return
+	}
+	if err != nil || !totp.ConfirmedAt.Valid {
+		utils.WriteJSON(w, http.StatusConflict, utils.Envelope{"message": "Two-factor authentication is not enabled"})
+		return
+	}
+	if uh.twoFactorLocked(w, userID) {
+		return
+	}
+
+	tx, err := uh.dbConn.Begin(r.Context())
+	if err != nil {
+		uh.logger.Printf("Failed to begin transaction: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to disable two-factor authentication"})
+		return
+	}
+	defer tx.Rollback(r.Context())
+	qtx := uh.userStore.WithTx(tx)
+
+	ok, err = uh.checkSecondFactor(r.Context(), qtx, totp, req.Code)
+	if err != nil {
+		uh.logger.Printf("Failed to check second factor for user %s: %v", userID.String(), err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to disable two-factor authentication"})
+		return
+	}
+	if !ok {
+		utils.WriteJSON(w, http.StatusUnauthorized, utils.Envelope{"message": "Invalid code"})
+		return
+	}
+
+	if err := qtx.DeleteTOTP(r.Context(), userID); err != nil {
+		uh.logger.Printf("Failed to delete TOTP for user %s: %v", userID.String(), err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to disable two-factor authentication"})
+		return
+	}
+	if err := qtx.DeleteRecoveryCodes(r.Context(), userID); err != nil {
+		uh.logger.Printf("Failed to delete recovery codes for user %s: %v", userID.String(), err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to disable two-factor authentication"})
+		return
+	}
+
+	if err := tx.Commit(r.Context()); err != nil {
+		uh.logger.Printf("Failed to commit transaction: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Failed to disable two-factor authentication"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"message": "Two-factor authentication disabled"})
+}
@@ -0,0 +1,210 @@
+package handlers
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/nabsk911/chronify/internal/auth"
+	"github.com/nabsk911/chronify/internal/db"
+)
+
+// fakeTwoFactor is a database holding one user, with 2FA on, and their
+// login challenges, recovery codes and sessions.
+type fakeTwoFactor struct {
+	*fakeAccounts
+
+	totp       db.UserTotp
+	challenges map[string]*db.LoginChallenge
+	recovery   map[string]bool
+}
+
+func newFakeTwoFactor(t *testing.T, box *auth.SecretBox, secret []byte, recoveryCodes []string) *fakeTwoFactor {
+	t.Helper()
+	hash, err := auth.SetPasswordHash("analytical")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := box.Seal(secret, testUserID.Bytes[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := &fakeTwoFactor{
+		fakeAccounts: newFakeAccounts(),
+		totp:         db.UserTotp{UserID: testUserID, Secret: sealed, ConfirmedAt: pgtype.Timestamptz{Time: time.Now(), Valid: true}},
+		challenges:   make(map[string]*db.LoginChallenge),
+		recovery:     make(map[string]bool),
+	}
+	f.users = []db.User{{ID: testUserID, Email: "ada@example.com", Username: "ada", PasswordHash: hash}}
+	for _, code := range recoveryCodes {
+		f.recovery[auth.HashRecoveryCode(code)] = false
+	}
+
+	f.On("GetUserById", func(args []any) (any, error) { return f.users[0], nil })
+	f.On("GetTOTPByUserId", func(args []any) (any, error) { return f.totp, nil })
+	f.On("UseTOTPStep", func(args []any) (any, error) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		if step := args[1].(int64); step > f.totp.LastUsedStep {
+			f.totp.LastUsedStep = step
+			return int64(1), nil
+		}
+		return int64(0), nil
+	})
+	f.On("UseRecoveryCode", func(args []any) (any, error) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		if used, ok := f.recovery[args[1].(string)]; ok && !used {
+			f.recovery[args[1].(string)] = true
+			return int64(1), nil
+		}
+		return int64(0), nil
+	})
+	f.On("DeleteExpiredLoginChallenges", func(args []any) (any, error) { return nil, nil })
+	f.On("CreateLoginChallenge", func(args []any) (any, error) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.challenges[args[1].(string)] = &db.LoginChallenge{UserID: args[0].(pgtype.UUID), ExpiresAt: args[2].(pgtype.Timestamptz)}
+		return nil, nil
+	})
+	f.On("AttemptLoginChallenge", func(args []any) (any, error) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		c, ok := f.challenges[args[0].(string)]
+		if !ok || c.Attempts >= args[1].(int32) || !c.ExpiresAt.Time.After(time.Now()) {
+			return nil, nil
+		}
+		c.Attempts++
+		return c.UserID, nil
+	})
+	f.On("DeleteLoginChallenge", func(args []any) (any, error) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		delete(f.challenges, args[0].(string))
+		return nil, nil
+	})
+	return f
+}
+
+// totpCodeAt works out the code an authenticator app shows at now.
+func totpCodeAt(secret []byte, now time.Time) string {
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], uint64(now.Unix()/30))
+	mac := hmac.New(sha1.New, secret)
+	mac.Write(counter[:])
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	return fmt.Sprintf("%06d", binary.BigEndian.Uint32(sum[offset:])&0x7fffffff%1000000)
+}
+
+func newTwoFactorHandler(t *testing.T, recoveryCodes ...string) (*UserHandler, *fakeTwoFactor, []byte) {
+	t.Helper()
+	key := make([]byte, 32)
+	secret := make([]byte, 20)
+	rand.Read(key)
+	rand.Read(secret)
+	box, err := auth.NewSecretBox(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fake := newFakeTwoFactor(t, box, secret, recoveryCodes)
+	return NewUserHandler(db.New(fake), fake, newTestKeySet(t), nil, "", false, box, testLogger), fake, secret
+}
+
+type loginResponse struct {
+	TwoFactorRequired bool   `json:"two_factor_required"`
+	ChallengeToken    string `json:"challenge_token"`
+	Token             string `json:"token"`
+}
+
+func login(t *testing.T, uh *UserHandler) (*httptest.ResponseRecorder, loginResponse) {
+	t.Helper()
+	w := post(uh.HandleLogin, `{"email":"ada@example.com","password":"analytical"}`)
+	var resp loginResponse
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	return w, resp
+}
+
+func loginTwoFactor(uh *UserHandler, challenge, code string) (*httptest.ResponseRecorder, loginResponse) {
+	w := post(uh.HandleLoginTwoFactor, `{"challenge_token":"`+challenge+`","code":"`+code+`"}`)
+	var resp loginResponse
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	return w, resp
+}
+
+func TestLoginWithTwoFactor(t *testing.T) {
+	uh, fake, secret := newTwoFactorHandler(t, "abcd-efgh-ijkl-mnop")
+
+	w, resp := login(t, uh)
+	if w.Code != http.StatusOK || !resp.TwoFactorRequired || resp.ChallengeToken == "" || resp.Token != "" {
+		t.Fatalf("login = %d %s, want a challenge and no session", w.Code, w.Body)
+	}
+	if fake.Called("CreateSession") != 0 {
+		t.Fatal("the password alone started a session")
+	}
+
+	code := totpCodeAt(secret, time.Now())
+	if w, resp := loginTwoFactor(uh, resp.ChallengeToken, code); w.Code != http.StatusOK || resp.Token == "" {
+		t.Fatalf("code exchange = %d %s, want a session", w.Code, w.Body)
+	}
+	if len(fake.challenges) != 0 {
+		t.Error("the used challenge was kept")
+	}
+
+	// The same code doesn't work twice, even with a new challenge.
+	_, resp = login(t, uh)
+	if w, _ := loginTwoFactor(uh, resp.ChallengeToken, code); w.Code != http.StatusUnauthorized {
+		t.Errorf("replaying the code = %d, want 401", w.Code)
+	}
+
+	// A recovery code works once, however it is typed.
+	if w, _ := loginTwoFactor(uh, resp.ChallengeToken, "ABCD EFGH IJKL MNOP"); w.Code != http.StatusOK {
+		t.Errorf("recovery code = %d %s, want 200", w.Code, w.Body)
+	}
+	_, resp = login(t, uh)
+	if w, _ := loginTwoFactor(uh, resp.ChallengeToken, "abcd-efgh-ijkl-mnop"); w.Code != http.StatusUnauthorized {
+		t.Errorf("reusing the recovery code = %d, want 401", w.Code)
+	}
+}
+
+func TestTwoFactorLockoutSpansChallenges(t *testing.T) {
+	uh, fake, secret := newTwoFactorHandler(t)
+	_, early := login(t, uh)
+
+	// Each challenge allows a few attempts of its own; the user's wrong
+	// codes are counted across all of them.
+	for i := range maxTwoFactorFailures {
+		_, resp := login(t, uh)
+		if w, _ := loginTwoFactor(uh, resp.ChallengeToken, "000000"); w.Code != http.StatusUnauthorized {
+			t.Fatalf("wrong code %d = %d, want 401", i+1, w.Code)
+		}
+	}
+
+	w, _ := login(t, uh)
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") == "" {
+		t.Errorf("password step after %d wrong codes = %d, want 429 with Retry-After", maxTwoFactorFailures, w.Code)
+	}
+	if strings.Contains(w.Body.String(), "challenge_token") {
+		t.Error("a locked-out user was given a challenge")
+	}
+
+	// A challenge from before the lockout can't be used to keep guessing,
+	// even with the right code.
+	checked := fake.Called("UseTOTPStep")
+	if w, _ := loginTwoFactor(uh, early.ChallengeToken, totpCodeAt(secret, time.Now())); w.Code != http.StatusTooManyRequests {
+		t.Errorf("code step after %d wrong codes = %d, want 429", maxTwoFactorFailures, w.Code)
+	}
+	if fake.Called("UseTOTPStep") != checked {
+		t.Error("the code was checked during the lockout")
+	}
+}
@@ -6,9 +6,13 @@ import (
 	"log"
 	"net/http"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/nabsk911/chronify/internal/auth"
 	"github.com/nabsk911/chronify/internal/db"
+	"github.com/nabsk911/chronify/internal/mail"
+	"github.com/nabsk911/chronify/internal/ratelimit"
 	"github.com/nabsk911/chronify/internal/utils"
 )
 
@@ -20,13 +24,32 @@ type authRequest struct {
 
 type UserHandler struct {
 	userStore *db.Queries
-	logger    *log.Logger
+	dbConn    TxStarter
+	tokenKeys *auth.KeySet
+	mailer    mail.Mailer
+	appURL    string
+	// requireVerifiedEmail stops users logging in until they've verified
+	// their email.
+	requireVerifiedEmail bool
+	// totpBox encrypts TOTP secrets. Without it, 2FA can't be turned on.
+	totpBox *auth.SecretBox
+	// twoFactorFailures counts each user's wrong second-factor codes across
+	// login challenges, so logging in again doesn't earn more guesses.
+	twoFactorFailures *ratelimit.Limiter
+	logger            *log.Logger
 }
 
-func NewUserHandler(userStore *db.Queries, logger *log.Logger) *UserHandler {
+func NewUserHandler(userStore *db.Queries, dbConn TxStarter, tokenKeys *auth.KeySet, mailer mail.Mailer, appURL string, requireVerifiedEmail bool, totpBox *auth.SecretBox, logger *log.Logger) *UserHandler {
 	return &UserHandler{
-		userStore: userStore,
-		logger:    logger,
+		userStore:            userStore,
+		dbConn:               dbConn,
+		tokenKeys:            tokenKeys,
+		mailer:               mailer,
+		appURL:               appURL,
+		requireVerifiedEmail: requireVerifiedEmail,
+		totpBox:              totpBox,
+		twoFactorFailures:    ratelimit.New(maxTwoFactorFailuresPerMinute, maxTwoFactorFailures),
+		logger:               logger,
 	}
 }
 
@@ -76,7 +99,7 @@ func (uh *UserHandler) HandleRegister(w http.ResponseWriter, r *http.Request) {
 		PasswordHash: password_hash,
 	}
 
-	_, err = uh.userStore.CreateUser(r.Context(), user)
+	created, err := uh.userStore.CreateUser(r.Context(), user)
 	if err != nil {
 		uh.logger.Printf("Failed to create user in store: %v", err)
 		var pgErr *pgconn.PgError
@@ -97,7 +120,13 @@ func (uh *UserHandler) HandleRegister(w http.ResponseWriter, r *http.Request) {
 
 	}
 
-	utils.WriteJSON(w, http.StatusCreated, utils.Envelope{"message": "User created successfully!"})
+	// The account is usable either way; the user can ask for another link.
+	newUser := db.User{ID: created.ID, Email: created.Email, Username: created.Username}
+	if err := uh.sendVerificationEmail(r.Context(), newUser); err != nil {
+		uh.logger.Printf("Failed to send verification email to user %s: %v", created.ID.String(), err)
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, utils.Envelope{"message": "User created successfully! Check your email to verify your account."})
 }
 
 // Login
@@ -143,7 +172,53 @@ func (uh *UserHandler) HandleLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	token, err := auth.GenerateToken(user.ID.String())
+	uh.finishLogin(w, r, user)
+}
+
+// finishLogin takes a user who has proven who they are, by password or
+// through SSO, through the checks that come after: email verification and
+// the second factor.
+func (uh *UserHandler) finishLogin(w http.ResponseWriter, r *http.Request, user db.User) {
+	if uh.requireVerifiedEmail && !user.EmailVerifiedAt.Valid {
+		utils.WriteJSON(w, http.StatusForbidden, utils.Envelope{
+			"message":        "Please verify your email before logging in",
+			"email_verified": false,
+		})
+		return
+	}
+
+	// With 2FA on, this only earns a challenge, to be exchanged for a
+	// session together with a code at /login/2fa.
+	totp, err := uh.userStore.GetTOTPByUserId(r.Context(), user.ID)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		uh.logger.Printf("Failed to retrieve TOTP for user %s: %v", user.ID.String(), err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Internal server error!"})
+		return
+	}
+	if err == nil && totp.ConfirmedAt.Valid {
+		if uh.twoFactorLocked(w, user.ID) {
+			return
+		}
+		challenge, err := uh.issueLoginChallenge(r.Context(), user.ID)
+		if err != nil {
+			uh.logger.Printf("Failed to issue login challenge: %v", err)
+			utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Internal server error"})
+			return
+		}
+		utils.WriteJSON(w, http.StatusOK, utils.Envelope{
+			"two_factor_required": true,
+			"challenge_token":     challenge,
+			"expires_in":          int(auth.LoginChallengeTTL.Seconds()),
+		})
+		return
+	}
+
+	uh.completeLogin(w, r, user)
+}
+
+// completeLogin starts a session for a user who has proven who they are.
+func (uh *UserHandler) completeLogin(w http.ResponseWriter, r *http.Request, user db.User) {
+	tokens, err := uh.issueSession(r.Context(), uh.userStore, user.ID, pgtype.UUID{})
 	if err != nil {
 		uh.logger.Printf("Failed to generate token: %v", err)
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"message": "Internal server error"})
@@ -151,11 +226,13 @@ func (uh *UserHandler) HandleLogin(w http.ResponseWriter, r *http.Request) {
 	}
 
 	utils.WriteJSON(w, http.StatusOK, utils.Envelope{
-		"token": token,
+		"token":         tokens.AccessToken,
+		"refresh_token": tokens.RefreshToken,
 		"user": map[string]any{
-			"id":       user.ID,
-			"username": user.Username,
-			"email":    user.Email,
+			"id":             user.ID,
+			"username":       user.Username,
+			"email":          user.Email,
+			"email_verified": user.EmailVerifiedAt.Valid,
 		},
 	})
 }
@@ -0,0 +1,183 @@
+package importer
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/nabsk911/chronify/internal/export"
+)
+
+// CSVFields are the event fields a CSV column can be mapped to. Besides the
+// event's own columns, start and end take a whole ISO 8601 date such as
+// 1969-07-20, which is how most spreadsheets hold dates.
+var CSVFields = []string{
+	"title", "card_title", "card_subtitle", "card_detailed_text",
+	"start", "start_year", "start_month", "start_day", "start_minute_of_day",
+	"end", "end_year", "end_month", "end_day", "end_minute_of_day",
+	"date_precision",
+}
+
+// ReadCSV reads one event per row. The mapping names the column header to
+// read each field from; without one, columns named after the fields are
+// read, which covers files written by export.CSV. A text cell starting with
+// an apostrophe loses it, since export.CSV adds one to guard formulas.
+func ReadCSV(r io.Reader, mapping map[string]string, maxRows int) (*Document, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, errors.New("the CSV file is empty")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	columns, err := csvColumns(header, mapping)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := &Document{}
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(doc.Rows) == maxRows {
+			return nil, ErrTooManyRows
+		}
+
+		row := Row{Row: len(doc.Rows) + 1}
+		row.Event, row.Err = csvEvent(record, columns)
+		doc.Rows = append(doc.Rows, row)
+	}
+	return doc, nil
+}
+
+// csvColumns maps each field to the index of the column it is read from.
+func csvColumns(header []string, mapping map[string]string) (map[string]int, error) {
+	index := make(map[string]int, len(header))
+	for i, name := range header {
+		index[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+
+	columns := make(map[string]int)
+	if mapping == nil {
+		for _, field := range CSVFields {
+			if i, ok := index[field]; ok {
+				columns[field] = i
+			}
+		}
+		return columns, nil
+	}
+
+	for field, column := range mapping {
+		if !isCSVField(field) {
+			return nil, fmt.Errorf("unknown field %q in mapping", field)
+		}
+		i, ok := index[strings.ToLower(strings.TrimSpace(column))]
+		if !ok {
+			return nil, fmt.Errorf("column %q not found", column)
+		}
+		columns[field] = i
+	}
+	return columns, nil
+}
+
+func isCSVField(field string) bool {
+	for _, f := range CSVFields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+func csvEvent(record []string, columns map[string]int) (Event, error) {
+	var e Event
+	get := func(field string) string {
+		i, ok := columns[field]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+	// Text fields may carry the apostrophe export.CSV guards formulas with.
+	getText := func(field string) string {
+		v := get(field)
+		if unquoted, ok := strings.CutPrefix(v, "'"); ok && export.SpreadsheetSafe(unquoted) == v {
+			return unquoted
+		}
+		return v
+	}
+
+	e.Title = getText("title")
+	e.CardTitle = getText("card_title")
+	if v := getText("card_subtitle"); v != "" {
+		e.CardSubtitle = pgtype.Text{String: v, Valid: true}
+	}
+	if v := getText("card_detailed_text"); v != "" {
+		e.CardDetailedText = pgtype.Text{String: v, Valid: true}
+	}
+
+	if v := get("start"); v != "" {
+		precision, err := setDate(v, &e.StartYear, &e.StartMonth, &e.StartDay, &e.StartMinuteOfDay)
+		if err != nil {
+			return e, fmt.Errorf("start: %w", err)
+		}
+		e.DatePrecision = pgtype.Text{String: precision, Valid: true}
+	}
+	if v := get("end"); v != "" {
+		if _, err := setDate(v, &e.EndYear, &e.EndMonth, &e.EndDay, &e.EndMinuteOfDay); err != nil {
+			return e, fmt.Errorf("end: %w", err)
+		}
+	}
+
+	ints := []struct {
+		field string
+		dst   any
+	}{
+		{"start_year", &e.StartYear},
+		{"start_month", &e.StartMonth},
+		{"start_day", &e.StartDay},
+		{"start_minute_of_day", &e.StartMinuteOfDay},
+		{"end_year", &e.EndYear},
+		{"end_month", &e.EndMonth},
+		{"end_day", &e.EndDay},
+		{"end_minute_of_day", &e.EndMinuteOfDay},
+	}
+	for _, f := range ints {
+		v := get(f.field)
+		if v == "" {
+			continue
+		}
+		switch dst := f.dst.(type) {
+		case *pgtype.Int8:
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				return e, fmt.Errorf("%s must be a whole number", f.field)
+			}
+			*dst = pgtype.Int8{Int64: n, Valid: true}
+		case *pgtype.Int2:
+			n, err := strconv.ParseInt(v, 10, 16)
+			if err != nil {
+				return e, fmt.Errorf("%s must be a whole number", f.field)
+			}
+			*dst = pgtype.Int2{Int16: int16(n), Valid: true}
+		}
+	}
+
+	if v := get("date_precision"); v != "" {
+		e.DatePrecision = pgtype.Text{String: strings.ToLower(v), Valid: true}
+	}
+	return e, nil
+}
@@ -0,0 +1,40 @@
+package importer
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/nabsk911/chronify/internal/db"
+	"github.com/nabsk911/chronify/internal/export"
+)
+
+func TestReadCSVUndoesFormulaGuard(t *testing.T) {
+	events := []db.Event{
+		{Title: "=1+1", CardTitle: "+44 20 7946 0000", CardSubtitle: pgtype.Text{String: "-5 degrees", Valid: true}, CardDetailedText: pgtype.Text{String: "@home", Valid: true}},
+		{Title: "'quoted", CardTitle: "'=already quoted", StartYear: pgtype.Int8{Int64: -776, Valid: true}, DatePrecision: pgtype.Text{String: "year", Valid: true}},
+	}
+	var exported []export.Event
+	for _, e := range events {
+		exported = append(exported, export.Event{Event: e})
+	}
+	var b bytes.Buffer
+	if err := export.CSV(&b, db.Timeline{}, exported); err != nil {
+		t.Fatal(err)
+	}
+
+	doc, err := ReadCSV(&b, nil, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Rows) != len(events) {
+		t.Fatalf("read %d rows, want %d", len(doc.Rows), len(events))
+	}
+	for i, row := range doc.Rows {
+		want := events[i]
+		got := row.Event
+		if row.Err != nil || got.Title != want.Title || got.CardTitle != want.CardTitle || got.CardSubtitle != want.CardSubtitle || got.CardDetailedText != want.CardDetailedText || got.StartYear != want.StartYear {
+			t.Errorf("row %d = %+v, %v, want %+v", i+1, got, row.Err, want)
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package importer
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// icsProperty is a content line such as DTSTART;VALUE=DATE:19690720.
+type icsProperty struct {
+	name   string
+	params map[string]string
+	value  string
+}
+
+// ReadICS reads each VEVENT of an RFC 5545 calendar as an event. All-day
+// events are imported to the day and timed events to the minute, in UTC.
+func ReadICS(r io.Reader, maxRows int) (*Document, error) {
+	lines, err := unfoldICS(r)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := &Document{}
+	var props []icsProperty
+	inEvent := false
+
+	for _, line := range lines {
+		prop, ok := parseICSLine(line)
+		if !ok {
+			continue
+		}
+
+		switch {
+		case prop.name == "BEGIN" && strings.EqualFold(prop.value, "VEVENT"):
+			inEvent = true
+			props = props[:0]
+		case prop.name == "END" && strings.EqualFold(prop.value, "VEVENT"):
+			if !inEvent {
+				continue
+			}
+			inEvent = false
+			if len(doc.Rows) == maxRows {
+				return nil, ErrTooManyRows
+			}
+			row := Row{Row: len(doc.Rows) + 1}
+			row.Event, row.Err = icsEvent(props)
+			doc.Rows = append(doc.Rows, row)
+		case inEvent:
+			props = append(props, prop)
+		case prop.name == "X-WR-CALNAME":
+			doc.Title = unescapeICSText(prop.value)
+		case prop.name == "X-WR-CALDESC":
+			doc.Description = unescapeICSText(prop.value)
+		}
+	}
+	return doc, nil
+}
+
+func unfoldICS(r io.Reader) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines, scanner.Err()
+}
+
+func parseICSLine(line string) (icsProperty, bool) {
+	// Parameter values may be quoted and contain colons.
+	colon, quoted := -1, false
+	for i, c := range line {
+		if c == '"' {
+			quoted = !quoted
+		} else if c == ':' && !quoted {
+			colon = i
+			break
+		}
+	}
+	if colon < 0 {
+		return icsProperty{}, false
+	}
+
+	parts := strings.Split(line[:colon], ";")
+	prop := icsProperty{
+		name:   strings.ToUpper(parts[0]),
+		params: make(map[string]string),
+		value:  line[colon+1:],
+	}
+	for _, p := range parts[1:] {
+		if k, v, ok := strings.Cut(p, "="); ok {
+			prop.params[strings.ToUpper(k)] = strings.Trim(v, `"`)
+		}
+	}
+	return prop, true
+}
+
+func icsEvent(props []icsProperty) (Event, error) {
+	var e Event
+	var start, end *icsProperty
+
+	for i := range props {
+		p := &props[i]
+		switch p.name {
+		case "SUMMARY":
+			e.CardTitle = unescapeICSText(p.value)
+		case "DESCRIPTION":
+			e.CardDetailedText = pgtype.Text{String: unescapeICSText(p.value), Valid: true}
+		case "DTSTART":
+			start = p
+		case "DTEND":
+			end = p
+		}
+	}
+
+	if start == nil {
+		return e, nil
+	}
+
+	startTime, allDay, err := parseICSTime(*start)
+	if err != nil {
+		return e, fmt.Errorf("DTSTART: %w", err)
+	}
+	setICSDate(startTime, allDay, &e.StartYear, &e.StartMonth, &e.StartDay, &e.StartMinuteOfDay)
+	if allDay {
+		e.DatePrecision = pgtype.Text{String: "day", Valid: true}
+		e.Title = startTime.Format("January 2, 2006")
+	} else {
+		e.DatePrecision = pgtype.Text{String: "minute", Valid: true}
+		e.Title = startTime.Format("January 2, 2006 15:04 MST")
+	}
+
+	if end == nil {
+		return e, nil
+	}
+	endTime, endAllDay, err := parseICSTime(*end)
+	if err != nil {
+		return e, fmt.Errorf("DTEND: %w", err)
+	}
+	if endAllDay != allDay {
+		return e, fmt.Errorf("DTEND and DTSTART must both be dates or both be times")
+	}
+	if allDay {
+		// DTEND is exclusive for all-day events.
+		endTime = endTime.AddDate(0, 0, -1)
+	}
+	if endTime.After(startTime) {
+		setICSDate(endTime, allDay, &e.EndYear, &e.EndMonth, &e.EndDay, &e.EndMinuteOfDay)
+	}
+	return e, nil
+}
+
+func parseICSTime(p icsProperty) (time.Time, bool, error) {
+	if p.params["VALUE"] == "DATE" || len(p.value) == 8 {
+		t, err := time.Parse("20060102", p.value)
+		return t, true, err
+	}
+
+	if strings.HasSuffix(p.value, "Z") {
+		t, err := time.Parse("20060102T150405Z", p.value)
+		return t, false, err
+	}
+
+	// Floating times, and zones Go doesn't know, are taken to be UTC.
+	loc := time.UTC
+	if tzid := p.params["TZID"]; tzid != "" {
+		if l, err := time.LoadLocation(tzid); err == nil {
+			loc = l
+		}
+	}
+	t, err := time.ParseInLocation("20060102T150405", p.value, loc)
+	return t.UTC(), false, err
+}
+
+func setICSDate(t time.Time, allDay bool, year *pgtype.Int8, month, day, minuteOfDay *pgtype.Int2) {
+	*year = pgtype.Int8{Int64: int64(t.Year()), Valid: true}
+	*month = pgtype.Int2{Int16: int16(t.Month()), Valid: true}
+	*day = pgtype.Int2{Int16: int16(t.Day()), Valid: true}
+	if !allDay {
+		*minuteOfDay = pgtype.Int2{Int16: int16(t.Hour()*60 + t.Minute()), Valid: true}
+	}
+}
+
+func unescapeICSText(s string) string {
+	return strings.NewReplacer(`\\`, `\`, `\;`, ";", `\,`, ",", `\n`, "\n", `\N`, "\n").Replace(s)
+}
@@ -0,0 +1,109 @@
+// Package importer reads timelines from CSV, iCalendar and the JSON documents
+// written by the export package.
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/nabsk911/chronify/internal/export"
+)
+
+// Event is an event read from a file. Its dates and tags haven't been
+// validated. Only JSON documents have tags.
+type Event struct {
+	Title            string       `json:"title"`
+	CardTitle        string       `json:"card_title"`
+	CardSubtitle     pgtype.Text  `json:"card_subtitle"`
+	CardDetailedText pgtype.Text  `json:"card_detailed_text"`
+	StartYear        pgtype.Int8  `json:"start_year"`
+	StartMonth       pgtype.Int2  `json:"start_month"`
+	StartDay         pgtype.Int2  `json:"start_day"`
+	StartMinuteOfDay pgtype.Int2  `json:"start_minute_of_day"`
+	EndYear          pgtype.Int8  `json:"end_year"`
+	EndMonth         pgtype.Int2  `json:"end_month"`
+	EndDay           pgtype.Int2  `json:"end_day"`
+	EndMinuteOfDay   pgtype.Int2  `json:"end_minute_of_day"`
+	DatePrecision    pgtype.Text  `json:"date_precision"`
+	Tags             []export.Tag `json:"tags"`
+}
+
+// Row is one record of the file: a CSV line, a VEVENT or a JSON event.
+// Rows are numbered from 1; a CSV header isn't counted.
+type Row struct {
+	Row   int
+	Event Event
+	Err   error
+}
+
+// Document is everything read from a file.
+type Document struct {
+	Title       string
+	Description string
+	Rows        []Row
+}
+
+var ErrTooManyRows = errors.New("importer: too many events")
+
+// setDate parses an ISO 8601 date as written by the export package, such as
+// 1969, 1969-07, 1969-07-20, 1969-07-20T20:17Z or -0043-03-15, into year,
+// month, day and minute of day. It returns the precision of the date.
+func setDate(value string, year *pgtype.Int8, month, day, minuteOfDay *pgtype.Int2) (string, error) {
+	s := strings.TrimSpace(value)
+	invalid := fmt.Errorf("invalid date %q", value)
+
+	sign := int64(1)
+	if strings.HasPrefix(s, "-") || strings.HasPrefix(s, "+") {
+		if s[0] == '-' {
+			sign = -1
+		}
+		s = s[1:]
+	}
+
+	datePart, timePart, hasTime := strings.Cut(s, "T")
+	parts := strings.Split(datePart, "-")
+	if len(parts) > 3 || len(parts[0]) < 4 {
+		return "", invalid
+	}
+
+	y, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return "", invalid
+	}
+	*year = pgtype.Int8{Int64: sign * y, Valid: true}
+	precision := "year"
+
+	for i, p := range parts[1:] {
+		n, err := strconv.ParseInt(p, 10, 16)
+		if err != nil || len(p) != 2 {
+			return "", invalid
+		}
+		if i == 0 {
+			*month = pgtype.Int2{Int16: int16(n), Valid: true}
+			precision = "month"
+		} else {
+			*day = pgtype.Int2{Int16: int16(n), Valid: true}
+			precision = "day"
+		}
+	}
+
+	if hasTime {
+		if precision != "day" {
+			return "", invalid
+		}
+		timePart = strings.TrimSuffix(timePart, "Z")
+		hh, mm, ok := strings.Cut(timePart, ":")
+		h, err1 := strconv.Atoi(hh)
+		m, err2 := strconv.Atoi(strings.SplitN(mm, ":", 2)[0])
+		if !ok || err1 != nil || err2 != nil {
+			return "", invalid
+		}
+		*minuteOfDay = pgtype.Int2{Int16: int16(h*60 + m), Valid: true}
+		precision = "minute"
+	}
+
+	return precision, nil
+}
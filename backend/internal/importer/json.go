@@ -0,0 +1,69 @@
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/nabsk911/chronify/internal/db"
+	"github.com/nabsk911/chronify/internal/export"
+)
+
+type jsonDocument struct {
+	Version  int         `json:"version"`
+	Timeline db.Timeline `json:"timeline"`
+	Events   []jsonEvent `json:"events"`
+}
+
+type jsonEvent struct {
+	db.Event
+	Tags []export.Tag `json:"tags"`
+}
+
+// ReadJSON reads a document written by export.JSON, in any version up to the
+// current one. The events keep the order they had on the exported timeline,
+// and the names and colors of their tags. Version 1 documents have no tags.
+func ReadJSON(r io.Reader, maxRows int) (*Document, error) {
+	var doc jsonDocument
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, err
+	}
+	if doc.Version < 1 || doc.Version > export.Version {
+		return nil, fmt.Errorf("unsupported export version %d", doc.Version)
+	}
+	if len(doc.Events) > maxRows {
+		return nil, ErrTooManyRows
+	}
+
+	sort.SliceStable(doc.Events, func(i, j int) bool {
+		return doc.Events[i].Position < doc.Events[j].Position
+	})
+
+	document := &Document{
+		Title:       doc.Timeline.Title,
+		Description: doc.Timeline.Description.String,
+	}
+	for i, e := range doc.Events {
+		document.Rows = append(document.Rows, Row{
+			Row: i + 1,
+			Event: Event{
+				Title:            e.Title,
+				CardTitle:        e.CardTitle,
+				CardSubtitle:     e.CardSubtitle,
+				CardDetailedText: e.CardDetailedText,
+				StartYear:        e.StartYear,
+				StartMonth:       e.StartMonth,
+				StartDay:         e.StartDay,
+				StartMinuteOfDay: e.StartMinuteOfDay,
+				EndYear:          e.EndYear,
+				EndMonth:         e.EndMonth,
+				EndDay:           e.EndDay,
+				EndMinuteOfDay:   e.EndMinuteOfDay,
+				DatePrecision:    e.DatePrecision,
+				Tags:             e.Tags,
+			},
+		})
+	}
+	return document, nil
+}
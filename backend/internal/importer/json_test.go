@@ -0,0 +1,104 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/nabsk911/chronify/internal/db"
+	"github.com/nabsk911/chronify/internal/export"
+)
+
+const testJSONv1 = `{
+	"version": 1,
+	"exported_at": "2024-01-01T00:00:00Z",
+	"timeline": {"title": "Space race", "description": "From Sputnik to Apollo"},
+	"events": [
+		{"title": "1969", "card_title": "Moon landing", "start_year": 1969, "date_precision": "year", "position": "b"},
+		{"title": "1957", "card_title": "Sputnik", "start_year": 1957, "date_precision": "year", "position": "a"}
+	]
+}`
+
+func TestReadJSON(t *testing.T) {
+	var exported bytes.Buffer
+	err := export.JSON(&exported, db.Timeline{
+		Title:       "Space race",
+		Description: pgtype.Text{String: "From Sputnik to Apollo", Valid: true},
+	}, []export.Event{
+		{
+			Event: db.Event{Title: "1969", CardTitle: "Moon landing", StartYear: pgtype.Int8{Int64: 1969, Valid: true}, DatePrecision: pgtype.Text{String: "year", Valid: true}, Position: "b"},
+			Tags:  []export.Tag{{Name: "Apollo", Color: "#3366ff"}},
+		},
+		{
+			Event: db.Event{Title: "1957", CardTitle: "Sputnik", StartYear: pgtype.Int8{Int64: 1957, Valid: true}, DatePrecision: pgtype.Text{String: "year", Valid: true}, Position: "a"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var written struct {
+		Version int `json:"version"`
+		Events  []struct {
+			Tags []export.Tag `json:"tags"`
+		} `json:"events"`
+	}
+	if err := json.Unmarshal(exported.Bytes(), &written); err != nil {
+		t.Fatalf("export isn't valid JSON: %v\n%s", err, exported.String())
+	}
+	if written.Version != export.Version {
+		t.Errorf("exported version %d, want %d", written.Version, export.Version)
+	}
+	if len(written.Events) != 2 || len(written.Events[0].Tags) != 1 || written.Events[0].Tags[0].Name != "Apollo" || written.Events[1].Tags == nil {
+		t.Errorf("exported events have tags %+v, want Apollo on the first and none on the second", written.Events)
+	}
+
+	for _, tt := range []struct {
+		name     string
+		doc      string
+		wantTags []export.Tag
+	}{
+		{"version 1", testJSONv1, nil},
+		{"current version", exported.String(), []export.Tag{{Name: "Apollo", Color: "#3366ff"}}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := ReadJSON(strings.NewReader(tt.doc), 10)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if doc.Title != "Space race" || doc.Description != "From Sputnik to Apollo" {
+				t.Errorf("read timeline %q, %q", doc.Title, doc.Description)
+			}
+			if len(doc.Rows) != 2 || doc.Rows[0].Event.CardTitle != "Sputnik" || doc.Rows[1].Event.CardTitle != "Moon landing" {
+				t.Fatalf("read rows %+v, want them in position order", doc.Rows)
+			}
+			if got := doc.Rows[1].Event.StartYear; got.Int64 != 1969 || !got.Valid {
+				t.Errorf("start_year = %v, want 1969", got)
+			}
+			if got := doc.Rows[1].Event.Tags; !slices.Equal(got, tt.wantTags) {
+				t.Errorf("tags = %+v, want %+v", got, tt.wantTags)
+			}
+			if got := doc.Rows[0].Event.Tags; len(got) != 0 {
+				t.Errorf("untagged event read with tags %+v", got)
+			}
+		})
+	}
+}
+
+func TestReadJSONRejectsUnknownVersions(t *testing.T) {
+	for _, version := range []string{"0", "99"} {
+		doc := strings.Replace(testJSONv1, `"version": 1`, `"version": `+version, 1)
+		if _, err := ReadJSON(strings.NewReader(doc), 10); err == nil {
+			t.Errorf("version %s was read", version)
+		}
+	}
+}
+
+func TestReadJSONTooManyEvents(t *testing.T) {
+	if _, err := ReadJSON(strings.NewReader(testJSONv1), 1); err != ErrTooManyRows {
+		t.Errorf("err = %v, want ErrTooManyRows", err)
+	}
+}
@@ -0,0 +1,86 @@
+// Package mail sends the emails the app needs, such as address verification
+// and password reset links, through SMTP or a local sink for development.
+package mail
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+)
+
+// Message is a plain-text email.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer sends email.
+type Mailer interface {
+	Name() string
+	Send(ctx context.Context, msg Message) error
+}
+
+const (
+	DriverSMTP = "smtp"
+	DriverLog  = "log"
+	DriverFile = "file"
+
+	defaultSMTPPort = "587"
+	defaultAppURL   = "http://localhost:5173"
+)
+
+type Config struct {
+	Driver string
+	From   string
+	// AppURL is the root of the web app. Links in emails point at its pages.
+	AppURL       string
+	SMTPHost     string
+	SMTPPort     string
+	SMTPUsername string
+	SMTPPassword string
+	// File is where the file driver appends the messages.
+	File string
+}
+
+// ConfigFromEnv reads MAIL_DRIVER, MAIL_FROM, APP_URL, SMTP_HOST, SMTP_PORT,
+// SMTP_USERNAME, SMTP_PASSWORD and MAIL_FILE. MAIL_DRIVER has no default: the
+// log and file drivers don't send anything, so they have to be asked for.
+func ConfigFromEnv() Config {
+	cfg := Config{
+		Driver:       os.Getenv("MAIL_DRIVER"),
+		From:         os.Getenv("MAIL_FROM"),
+		AppURL:       os.Getenv("APP_URL"),
+		SMTPHost:     os.Getenv("SMTP_HOST"),
+		SMTPPort:     os.Getenv("SMTP_PORT"),
+		SMTPUsername: os.Getenv("SMTP_USERNAME"),
+		SMTPPassword: os.Getenv("SMTP_PASSWORD"),
+		File:         os.Getenv("MAIL_FILE"),
+	}
+	if cfg.SMTPPort == "" {
+		cfg.SMTPPort = defaultSMTPPort
+	}
+	if cfg.AppURL == "" {
+		cfg.AppURL = defaultAppURL
+	}
+	cfg.AppURL = strings.TrimSuffix(cfg.AppURL, "/")
+	return cfg
+}
+
+func New(cfg Config, logger *log.Logger) (Mailer, error) {
+	switch cfg.Driver {
+	case DriverSMTP:
+		return NewSMTP(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.From)
+	case DriverLog:
+		return NewLog(logger), nil
+	case DriverFile:
+		return NewFile(cfg.File)
+	case "":
+		return nil, errors.New("mail: MAIL_DRIVER is required: smtp, or log or file for development")
+	default:
+		return nil, fmt.Errorf("mail: unknown driver %q", cfg.Driver)
+	}
+}
@@ -0,0 +1,19 @@
+package mail
+
+import (
+	"io"
+	"log"
+	"testing"
+)
+
+func TestNewRequiresDriver(t *testing.T) {
+	t.Setenv("MAIL_DRIVER", "")
+	if m, err := New(ConfigFromEnv(), log.New(io.Discard, "", 0)); err == nil {
+		t.Errorf("without MAIL_DRIVER mail goes to the %s driver, want an error", m.Name())
+	}
+
+	t.Setenv("MAIL_DRIVER", DriverLog)
+	if m, err := New(ConfigFromEnv(), log.New(io.Discard, "", 0)); err != nil || m.Name() != DriverLog {
+		t.Errorf("MAIL_DRIVER=log gave %v, %v", m, err)
+	}
+}
@@ -0,0 +1,61 @@
+package mail
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// Log writes messages to the log instead of sending them, for development.
+type Log struct {
+	logger *log.Logger
+}
+
+func NewLog(logger *log.Logger) *Log {
+	return &Log{logger: logger}
+}
+
+func (l *Log) Name() string { return DriverLog }
+
+func (l *Log) Send(ctx context.Context, msg Message) error {
+	l.logger.Printf("Mail to %s: %s\n%s", msg.To, msg.Subject, msg.Body)
+	return nil
+}
+
+// File appends messages to a file instead of sending them, so tests and
+// local setups can read the links out of it.
+type File struct {
+	path string
+	mu   sync.Mutex
+}
+
+func NewFile(path string) (*File, error) {
+	if path == "" {
+		return nil, errors.New("mail: MAIL_FILE is required for the file driver")
+	}
+	return &File{path: path}, nil
+}
+
+func (f *File) Name() string { return DriverFile }
+
+func (f *File) Send(ctx context.Context, msg Message) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	file, err := os.OpenFile(f.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("mail: %w", err)
+	}
+	_, err = fmt.Fprintf(file, "Date: %s\nTo: %s\nSubject: %s\n\n%s\n\n", time.Now().Format(time.RFC1123Z), msg.To, msg.Subject, msg.Body)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("mail: %w", err)
+	}
+	return nil
+}
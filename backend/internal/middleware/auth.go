@@ -2,38 +2,122 @@ package middleware
 
 import (
 	"context"
+	"errors"
 	"net/http"
+	"slices"
 	"strings"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/nabsk911/chronify/internal/auth"
+	"github.com/nabsk911/chronify/internal/db"
 	"github.com/nabsk911/chronify/internal/utils"
 )
 
-func Authentication(next http.HandlerFunc) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		authHeader := r.Header.Get("Authorization")
+// TokenStore checks both kinds of bearer token. *db.Queries satisfies it.
+type TokenStore interface {
+	auth.SessionStore
+	UsePersonalAccessToken(ctx context.Context, tokenHash string) (db.UsePersonalAccessTokenRow, error)
+}
 
-		if authHeader == "" {
-			utils.WriteJSON(w, http.StatusUnauthorized, utils.Envelope{"error": "Authorization header required!"})
-			return
-		}
+// Authentication only lets requests with a session's access token through.
+// It guards account routes, which personal access tokens can't reach.
+func Authentication(keys *auth.KeySet, sessions auth.SessionStore) func(http.HandlerFunc) http.HandlerFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			tokenString, ok := readBearerToken(w, r)
+			if !ok {
+				return
+			}
 
-		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
+			if auth.IsPersonalAccessToken(tokenString) {
+				utils.WriteJSON(w, http.StatusForbidden, utils.Envelope{"error": "Personal access tokens can't be used here!"})
+				return
+			}
 
-		if tokenString == authHeader {
-			utils.WriteJSON(w, http.StatusUnauthorized, utils.Envelope{"error": "Bearer token required!"})
-			return
+			authenticateSession(w, r, keys, tokenString, sessions, next)
 		}
+	}
+}
 
-		claims, err := auth.ValidateToken(tokenString)
+// ScopedAuthentication lets requests through with a session's access token,
+// or with a personal access token that has the route's scope.
+func ScopedAuthentication(keys *auth.KeySet, tokens TokenStore) func(scope string, next http.HandlerFunc) http.HandlerFunc {
+	return func(scope string, next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			tokenString, ok := readBearerToken(w, r)
+			if !ok {
+				return
+			}
+
+			if !auth.IsPersonalAccessToken(tokenString) {
+				authenticateSession(w, r, keys, tokenString, tokens, next)
+				return
+			}
+
+			token, err := tokens.UsePersonalAccessToken(r.Context(), auth.HashPersonalAccessToken(tokenString))
+			if err != nil {
+				if !errors.Is(err, pgx.ErrNoRows) {
+					utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "Internal server error!"})
+					return
+				}
+				utils.WriteJSON(w, http.StatusUnauthorized, utils.Envelope{"error": "Invalid token!"})
+				return
+			}
+
+			if !slices.Contains(token.Scopes, scope) {
+				utils.WriteJSON(w, http.StatusForbidden, utils.Envelope{"error": "Token is missing the " + scope + " scope!"})
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), "userID", token.UserID.String())
+			ctx = context.WithValue(ctx, "scopes", token.Scopes)
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+		}
+	}
+}
 
-		if err != nil {
-			utils.WriteJSON(w, http.StatusUnauthorized, utils.Envelope{"error": "Invalid token!"})
+// RequireScope also requires scope of personal access tokens, for routes
+// that need more than the one ScopedAuthentication checks. It must run
+// inside ScopedAuthentication.
+func RequireScope(scope string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !utils.HasScope(r, scope) {
+			utils.WriteJSON(w, http.StatusForbidden, utils.Envelope{"error": "Token is missing the " + scope + " scope!"})
 			return
 		}
+		next.ServeHTTP(w, r)
+	}
+}
+
+func readBearerToken(w http.ResponseWriter, r *http.Request) (string, bool) {
+	authHeader := r.Header.Get("Authorization")
+
+	if authHeader == "" {
+		utils.WriteJSON(w, http.StatusUnauthorized, utils.Envelope{"error": "Authorization header required!"})
+		return "", false
+	}
 
-		ctx := context.WithValue(r.Context(), "userID", claims.UserID)
+	tokenString := strings.TrimPrefix(authHeader, "Bearer ")
 
-		next.ServeHTTP(w, r.WithContext(ctx))
+	if tokenString == authHeader {
+		utils.WriteJSON(w, http.StatusUnauthorized, utils.Envelope{"error": "Bearer token required!"})
+		return "", false
 	}
+
+	return tokenString, true
+}
+
+func authenticateSession(w http.ResponseWriter, r *http.Request, keys *auth.KeySet, tokenString string, sessions auth.SessionStore, next http.HandlerFunc) {
+	claims, err := keys.ValidateToken(r.Context(), tokenString, sessions)
+
+	if err != nil {
+		utils.WriteJSON(w, http.StatusUnauthorized, utils.Envelope{"error": "Invalid token!"})
+		return
+	}
+
+	ctx := context.WithValue(r.Context(), "userID", claims.UserID)
+	ctx = context.WithValue(ctx, "sessionID", claims.SessionID)
+
+	next.ServeHTTP(w, r.WithContext(ctx))
 }
@@ -0,0 +1,174 @@
+package oidc_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/nabsk911/chronify/internal/oidc"
+	"github.com/nabsk911/chronify/internal/oidc/oidctest"
+)
+
+const (
+	testNonce    = "nonce-1"
+	testVerifier = "verifier-1"
+	testRedirect = "https://chronify.example/sso/callback"
+)
+
+func newProvider(idp *oidctest.IdP) *oidc.Provider {
+	return oidc.NewProvider(oidc.Config{
+		Name:         "test",
+		Issuer:       idp.URL,
+		ClientID:     oidctest.ClientID,
+		ClientSecret: oidctest.ClientSecret,
+		RedirectURL:  testRedirect,
+	})
+}
+
+func TestExchange(t *testing.T) {
+	idp := oidctest.NewIdP(t)
+	p := newProvider(idp)
+
+	code := idp.IssueCode(idp.Sign(t, idp.Claims(testNonce)))
+	claims, err := p.Exchange(context.Background(), code, testVerifier, testNonce)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := oidc.Claims{Subject: "idp-user-1", Email: "ada@example.com", EmailVerified: true, Name: "Ada Lovelace"}
+	if *claims != want {
+		t.Errorf("claims = %+v, want %+v", *claims, want)
+	}
+
+	requests := idp.TokenRequests()
+	if len(requests) != 1 {
+		t.Fatalf("%d token requests, want 1", len(requests))
+	}
+	form := requests[0]
+	if form.Get("code") != code || form.Get("code_verifier") != testVerifier || form.Get("redirect_uri") != testRedirect {
+		t.Errorf("token request = %v, want the code, verifier and redirect URL", form)
+	}
+
+	// Codes can only be used once.
+	if _, err := p.Exchange(context.Background(), code, testVerifier, testNonce); err == nil {
+		t.Error("a used code was exchanged again")
+	}
+}
+
+func TestExchangeRejectsInvalidIDTokens(t *testing.T) {
+	idp := oidctest.NewIdP(t)
+	now := time.Now()
+
+	tests := []struct {
+		name   string
+		token  func(claims jwt.MapClaims) string
+		modify func(claims jwt.MapClaims)
+	}{
+		{name: "bad signature", token: func(c jwt.MapClaims) string { return idp.Forge(t, c) }},
+		{name: "wrong issuer", modify: func(c jwt.MapClaims) { c["iss"] = "https://evil.example" }},
+		{name: "wrong audience", modify: func(c jwt.MapClaims) { c["aud"] = "another-client" }},
+		{name: "another client's among several audiences", modify: func(c jwt.MapClaims) {
+			c["aud"] = []string{oidctest.ClientID, "another-client"}
+			c["azp"] = "another-client"
+		}},
+		{name: "expired", modify: func(c jwt.MapClaims) {
+			c["iat"] = now.Add(-2 * time.Hour).Unix()
+			c["exp"] = now.Add(-time.Hour).Unix()
+		}},
+		{name: "no expiry", modify: func(c jwt.MapClaims) { delete(c, "exp") }},
+		{name: "issued in the future", modify: func(c jwt.MapClaims) { c["iat"] = now.Add(time.Hour).Unix() }},
+		{name: "nonce mismatch", modify: func(c jwt.MapClaims) { c["nonce"] = "another login's nonce" }},
+		{name: "no nonce", modify: func(c jwt.MapClaims) { delete(c, "nonce") }},
+		{name: "no subject", modify: func(c jwt.MapClaims) { delete(c, "sub") }},
+		{name: "signed with the client secret", token: func(c jwt.MapClaims) string {
+			token := jwt.NewWithClaims(jwt.SigningMethodHS256, c)
+			token.Header["kid"] = "test-key"
+			signed, err := token.SignedString([]byte(oidctest.ClientSecret))
+			if err != nil {
+				t.Fatal(err)
+			}
+			return signed
+		}},
+		{name: "unsigned", token: func(c jwt.MapClaims) string {
+			signed, err := jwt.NewWithClaims(jwt.SigningMethodNone, c).SignedString(jwt.UnsafeAllowNoneSignatureType)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return signed
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := idp.Claims(testNonce)
+			if tt.modify != nil {
+				tt.modify(claims)
+			}
+			token := idp.Sign(t, claims)
+			if tt.token != nil {
+				token = tt.token(claims)
+			}
+
+			_, err := newProvider(idp).Exchange(context.Background(), idp.IssueCode(token), testVerifier, testNonce)
+			if !errors.Is(err, oidc.ErrInvalidIDToken) {
+				t.Errorf("Exchange error = %v, want ErrInvalidIDToken", err)
+			}
+		})
+	}
+}
+
+func TestExchangeReportsUnverifiedEmail(t *testing.T) {
+	idp := oidctest.NewIdP(t)
+	for _, verified := range []any{false, "false", nil, true, "true"} {
+		claims := idp.Claims(testNonce)
+		claims["email_verified"] = verified
+		code := idp.IssueCode(idp.Sign(t, claims))
+
+		got, err := newProvider(idp).Exchange(context.Background(), code, testVerifier, testNonce)
+		if err != nil {
+			t.Fatalf("email_verified %#v: %v", verified, err)
+		}
+		want := verified == true || verified == "true"
+		if got.EmailVerified != want {
+			t.Errorf("email_verified %#v read as %v, want %v", verified, got.EmailVerified, want)
+		}
+	}
+}
+
+func TestAuthCodeURL(t *testing.T) {
+	idp := oidctest.NewIdP(t)
+	raw, err := newProvider(idp).AuthCodeURL(context.Background(), "state-1", testNonce, testVerifier)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := u.Scheme + "://" + u.Host + u.Path; got != idp.URL+"/authorize" {
+		t.Errorf("login page = %s, want the authorization endpoint", got)
+	}
+
+	challenge := sha256.Sum256([]byte(testVerifier))
+	q := u.Query()
+	for name, want := range map[string]string{
+		"response_type":         "code",
+		"client_id":             oidctest.ClientID,
+		"redirect_uri":          testRedirect,
+		"scope":                 "openid email profile",
+		"state":                 "state-1",
+		"nonce":                 testNonce,
+		"code_challenge":        base64.RawURLEncoding.EncodeToString(challenge[:]),
+		"code_challenge_method": "S256",
+	} {
+		if got := q.Get(name); got != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+	if q.Has("code_verifier") {
+		t.Error("the code verifier was sent to the login page")
+	}
+}
@@ -0,0 +1,106 @@
+// Package ratelimit implements in-memory token buckets keyed by caller.
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Buckets that have refilled completely are dropped once there are this
+// many, since a full bucket behaves the same as a missing one.
+const pruneThreshold = 10000
+
+// Limiter holds a token bucket for each key. Each bucket holds up to burst
+// tokens and refills at rate tokens per second; a request takes one token.
+type Limiter struct {
+	rate  float64
+	burst float64
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+}
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// New returns a limiter allowing perMinute requests a minute with bursts of
+// up to burst requests.
+func New(perMinute float64, burst int) *Limiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &Limiter{
+		rate:    perMinute / 60,
+		burst:   float64(burst),
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// Allow takes a token from key's bucket. When the bucket is empty it reports
+// how long until the next token is available instead.
+func (l *Limiter) Allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	b := l.refill(key, now)
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+
+	return false, l.wait(b)
+}
+
+// Wait reports how long until key could make a request, without taking a
+// token. It is zero when key could make one now.
+func (l *Limiter) Wait(key string) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b := l.refill(key, time.Now())
+	if b.tokens >= 1 {
+		return 0
+	}
+	return l.wait(b)
+}
+
+func (l *Limiter) wait(b *bucket) time.Duration {
+	wait := (1 - b.tokens) / l.rate
+	return time.Duration(math.Ceil(wait * float64(time.Second)))
+}
+
+// Available reports how many requests key could make right now.
+func (l *Limiter) Available(key string) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return int(l.refill(key, time.Now()).tokens)
+}
+
+func (l *Limiter) refill(key string, now time.Time) *bucket {
+	b, ok := l.buckets[key]
+	if !ok {
+		if len(l.buckets) >= pruneThreshold {
+			l.prune(now)
+		}
+		b = &bucket{tokens: l.burst, updated: now}
+		l.buckets[key] = b
+		return b
+	}
+
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.updated).Seconds()*l.rate)
+	b.updated = now
+	return b
+}
+
+func (l *Limiter) prune(now time.Time) {
+	for key, b := range l.buckets {
+		if b.tokens+now.Sub(b.updated).Seconds()*l.rate >= l.burst {
+			delete(l.buckets, key)
+		}
+	}
+}
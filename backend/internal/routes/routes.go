@@ -4,22 +4,84 @@ import (
 	"net/http"
 
 	"github.com/nabsk911/chronify/internal/app"
+	"github.com/nabsk911/chronify/internal/auth"
 	"github.com/nabsk911/chronify/internal/middleware"
 )
 
 func SetupRoutes(app *app.Application) *http.ServeMux {
 	router := http.NewServeMux()
+	// Account routes only take a session; the rest also take a personal
+	// access token with the scope given, and any scope RequireScope adds.
+	// Generating AI events without previewing them also needs events:write,
+	// which HandleCreateAIEvents checks once it knows the mode.
+	authenticate := middleware.Authentication(app.TokenKeys, app.DB)
+	authorize := middleware.ScopedAuthentication(app.TokenKeys, app.DB)
+
+	router.HandleFunc("GET /.well-known/jwks.json", app.JWKSHandler.HandleGetJWKS)
 	router.HandleFunc("POST /register", app.UserHandler.HandleRegister)
 	router.HandleFunc("POST /login", app.UserHandler.HandleLogin)
-	router.HandleFunc("GET /timelines", middleware.Authentication(app.TimelineHandler.HandleGetTimelines))
-	router.HandleFunc("GET /timelines/{timelineId}", middleware.Authentication(app.TimelineHandler.HandleGetTimelineById))
-	router.HandleFunc("GET /timelines/search", middleware.Authentication(app.TimelineHandler.HandleSearchTimeline))
-	router.HandleFunc("POST /timelines", middleware.Authentication(app.TimelineHandler.HandleCreateTimeline))
-	router.HandleFunc("PUT /timelines/{timelineId}", middleware.Authentication(app.TimelineHandler.HandleUpdateTimeline))
-	router.HandleFunc("DELETE /timelines/{timelineId}", middleware.Authentication(app.TimelineHandler.HandleDeleteTimeline))
-	router.HandleFunc("GET /timelines/{timelineId}/events", middleware.Authentication(app.EventHandler.HandleGetEventsByTimelineId))
-	router.HandleFunc("POST /timelines/{timelineId}/events", middleware.Authentication(app.EventHandler.HandleUpsertEvents))
-	router.HandleFunc("POST /timelines/{timelineId}/aievents", middleware.Authentication(app.EventHandler.HandleCreateAIEvents))
-	router.HandleFunc("DELETE /timelines/{timelineId}/events/{eventId}", middleware.Authentication(app.EventHandler.HandleDeleteEvent))
+	router.HandleFunc("POST /login/2fa", app.UserHandler.HandleLoginTwoFactor)
+	router.HandleFunc("GET /auth/oidc/providers", app.SSOHandler.HandleGetProviders)
+	router.HandleFunc("POST /auth/oidc/{provider}/start", app.SSOHandler.HandleStartLogin)
+	router.HandleFunc("POST /auth/oidc/{provider}/callback", app.SSOHandler.HandleCallback)
+	router.HandleFunc("POST /token/refresh", app.UserHandler.HandleRefreshToken)
+	router.HandleFunc("POST /email/verify", app.UserHandler.HandleVerifyEmail)
+	router.HandleFunc("POST /email/verify/resend", app.UserHandler.HandleResendVerification)
+	router.HandleFunc("POST /password/forgot", app.UserHandler.HandleForgotPassword)
+	router.HandleFunc("POST /password/reset", app.UserHandler.HandleResetPassword)
+
+	// Public share links, readable without an account.
+	router.HandleFunc("GET /public/{shareToken}", app.PublicHandler.HandleGetSharedTimeline)
+
+	router.HandleFunc("POST /logout", authenticate(app.UserHandler.HandleLogout))
+	router.HandleFunc("POST /logout-all", authenticate(app.UserHandler.HandleLogoutAll))
+	router.HandleFunc("GET /me/ai-usage", authorize(auth.ScopeAIGenerate, app.EventHandler.HandleGetAIUsage))
+	router.HandleFunc("GET /me/2fa", authenticate(app.UserHandler.HandleGetTwoFactor))
+	router.HandleFunc("POST /me/2fa/enroll", authenticate(app.UserHandler.HandleEnrollTwoFactor))
+	router.HandleFunc("POST /me/2fa/confirm", authenticate(app.UserHandler.HandleConfirmTwoFactor))
+	router.HandleFunc("POST /me/2fa/recovery-codes", authenticate(app.UserHandler.HandleRegenerateRecoveryCodes))
+	router.HandleFunc("DELETE /me/2fa", authenticate(app.UserHandler.HandleDisableTwoFactor))
+	router.HandleFunc("GET /me/tokens", authenticate(app.UserHandler.HandleGetPersonalAccessTokens))
+	router.HandleFunc("POST /me/tokens", authenticate(app.UserHandler.HandleCreatePersonalAccessToken))
+	router.HandleFunc("DELETE /me/tokens/{tokenId}", authenticate(app.UserHandler.HandleRevokePersonalAccessToken))
+	router.HandleFunc("GET /search", authorize(auth.ScopeTimelinesRead, app.TimelineHandler.HandleSearch))
+	router.HandleFunc("GET /timelines", authorize(auth.ScopeTimelinesRead, app.TimelineHandler.HandleGetTimelines))
+	router.HandleFunc("GET /timelines/{timelineId}", authorize(auth.ScopeTimelinesRead, app.TimelineHandler.HandleGetTimelineById))
+	router.HandleFunc("GET /timelines/search", authorize(auth.ScopeTimelinesRead, app.TimelineHandler.HandleSearchTimeline))
+	router.HandleFunc("POST /timelines", authorize(auth.ScopeTimelinesWrite, app.TimelineHandler.HandleCreateTimeline))
+	router.HandleFunc("POST /timelines/import", authorize(auth.ScopeTimelinesWrite, middleware.RequireScope(auth.ScopeEventsWrite, app.EventHandler.HandleImportTimeline)))
+	router.HandleFunc("PUT /timelines/{timelineId}", authorize(auth.ScopeTimelinesWrite, app.TimelineHandler.HandleUpdateTimeline))
+	router.HandleFunc("DELETE /timelines/{timelineId}", authorize(auth.ScopeTimelinesWrite, app.TimelineHandler.HandleDeleteTimeline))
+	router.HandleFunc("GET /timelines/{timelineId}/members", authorize(auth.ScopeTimelinesRead, app.TimelineHandler.HandleGetTimelineMembers))
+	router.HandleFunc("POST /timelines/{timelineId}/members", authorize(auth.ScopeTimelinesWrite, app.TimelineHandler.HandleAddTimelineMember))
+	router.HandleFunc("PUT /timelines/{timelineId}/members/{userId}", authorize(auth.ScopeTimelinesWrite, app.TimelineHandler.HandleUpdateTimelineMember))
+	router.HandleFunc("DELETE /timelines/{timelineId}/members/{userId}", authorize(auth.ScopeTimelinesWrite, app.TimelineHandler.HandleRemoveTimelineMember))
+	router.HandleFunc("GET /timelines/{timelineId}/share-links", authorize(auth.ScopeTimelinesRead, app.TimelineHandler.HandleGetShareLinks))
+	router.HandleFunc("POST /timelines/{timelineId}/share-links", authorize(auth.ScopeTimelinesWrite, app.TimelineHandler.HandleCreateShareLink))
+	router.HandleFunc("DELETE /timelines/{timelineId}/share-links/{linkId}", authorize(auth.ScopeTimelinesWrite, app.TimelineHandler.HandleRevokeShareLink))
+	router.HandleFunc("PUT /timelines/{timelineId}/tags", authorize(auth.ScopeTimelinesWrite, app.TagHandler.HandleSetTimelineTags))
+	router.HandleFunc("GET /timelines/{timelineId}/revisions", authorize(auth.ScopeTimelinesRead, app.EventHandler.HandleGetRevisions))
+	router.HandleFunc("GET /timelines/{timelineId}/revisions/{revision}", authorize(auth.ScopeTimelinesRead, middleware.RequireScope(auth.ScopeEventsRead, app.EventHandler.HandleGetRevision)))
+	router.HandleFunc("POST /timelines/{timelineId}/revisions/{revision}/restore", authorize(auth.ScopeTimelinesWrite, middleware.RequireScope(auth.ScopeEventsWrite, app.EventHandler.HandleRestoreRevision)))
+	router.HandleFunc("GET /timelines/{timelineId}/events", authorize(auth.ScopeEventsRead, app.EventHandler.HandleGetEventsByTimelineId))
+	router.HandleFunc("POST /timelines/{timelineId}/events", authorize(auth.ScopeEventsWrite, app.EventHandler.HandleUpsertEvents))
+	router.HandleFunc("PATCH /timelines/{timelineId}/events/order", authorize(auth.ScopeEventsWrite, app.EventHandler.HandleReorderEvents))
+	router.HandleFunc("GET /timelines/{timelineId}/export", authorize(auth.ScopeTimelinesRead, middleware.RequireScope(auth.ScopeEventsRead, app.EventHandler.HandleExportTimeline)))
+	router.HandleFunc("POST /timelines/{timelineId}/aievents", authorize(auth.ScopeAIGenerate, app.EventHandler.HandleCreateAIEvents))
+	router.HandleFunc("POST /timelines/{timelineId}/aievents/commit", authorize(auth.ScopeEventsWrite, app.EventHandler.HandleCommitAIEvents))
+	router.HandleFunc("POST /timelines/{timelineId}/aievents/stream", authorize(auth.ScopeAIGenerate, middleware.RequireScope(auth.ScopeEventsWrite, app.EventHandler.HandleStreamAIEvents)))
+	router.HandleFunc("DELETE /timelines/{timelineId}/events/{eventId}", authorize(auth.ScopeEventsWrite, app.EventHandler.HandleDeleteEvent))
+	router.HandleFunc("GET /timelines/{timelineId}/events/{eventId}/attachments", authorize(auth.ScopeEventsRead, app.AttachmentHandler.HandleGetAttachments))
+	router.HandleFunc("POST /timelines/{timelineId}/events/{eventId}/attachments", authorize(auth.ScopeEventsWrite, app.AttachmentHandler.HandleUploadAttachment))
+	router.HandleFunc("GET /timelines/{timelineId}/events/{eventId}/attachments/{attachmentId}", authorize(auth.ScopeEventsRead, app.AttachmentHandler.HandleDownloadAttachment))
+	router.HandleFunc("DELETE /timelines/{timelineId}/events/{eventId}/attachments/{attachmentId}", authorize(auth.ScopeEventsWrite, app.AttachmentHandler.HandleDeleteAttachment))
+	router.HandleFunc("GET /tags", authorize(auth.ScopeTimelinesRead, app.TagHandler.HandleGetTags))
+	router.HandleFunc("POST /tags", authorize(auth.ScopeTimelinesWrite, app.TagHandler.HandleCreateTag))
+	router.HandleFunc("PUT /tags/{tagId}", authorize(auth.ScopeTimelinesWrite, app.TagHandler.HandleUpdateTag))
+	router.HandleFunc("DELETE /tags/{tagId}", authorize(auth.ScopeTimelinesWrite, app.TagHandler.HandleDeleteTag))
+	router.HandleFunc("GET /trash", authorize(auth.ScopeTimelinesRead, app.TrashHandler.HandleGetTrash))
+	router.HandleFunc("DELETE /trash", authorize(auth.ScopeTimelinesWrite, app.TrashHandler.HandleEmptyTrash))
+	router.HandleFunc("POST /trash/{id}/restore", authorize(auth.ScopeTimelinesWrite, app.TrashHandler.HandleRestoreTrashItem))
+	router.HandleFunc("DELETE /trash/{id}", authorize(auth.ScopeTimelinesWrite, app.TrashHandler.HandlePurgeTrashItem))
 	return router
 }
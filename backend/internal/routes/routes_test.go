@@ -0,0 +1,289 @@
+package routes
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/nabsk911/chronify/internal/ai"
+	"github.com/nabsk911/chronify/internal/app"
+	"github.com/nabsk911/chronify/internal/auth"
+	"github.com/nabsk911/chronify/internal/db"
+	"github.com/nabsk911/chronify/internal/dbtest"
+	"github.com/nabsk911/chronify/internal/handlers"
+	"github.com/nabsk911/chronify/internal/storage"
+)
+
+const (
+	ownerID    = "0195f3a0-0000-7000-8000-00000000000a"
+	strangerID = "0195f3a0-0000-7000-8000-00000000000b"
+	viewerID   = "0195f3a0-0000-7000-8000-00000000000c"
+	timelineID = "0195f3a0-0000-7000-8000-0000000000f1"
+	eventID    = "0195f3a0-0000-7000-8000-0000000000e1"
+	itemID     = "0195f3a0-0000-7000-8000-0000000000d1"
+)
+
+// timelineRoutes are the routes that act on one timeline, with the least role
+// a member needs. Each must check the caller's membership before touching
+// the timeline. Any member may leave a timeline, but only its owner can
+// remove someone else, as the requests here try to.
+var timelineRoutes = []struct {
+	method  string
+	path    string
+	minRole string
+}{
+	{"GET", "/timelines/{timelineId}", "viewer"},
+	{"PUT", "/timelines/{timelineId}", "editor"},
+	{"DELETE", "/timelines/{timelineId}", "owner"},
+	{"GET", "/timelines/{timelineId}/members", "viewer"},
+	{"POST", "/timelines/{timelineId}/members", "owner"},
+	{"PUT", "/timelines/{timelineId}/members/{userId}", "owner"},
+	{"DELETE", "/timelines/{timelineId}/members/{userId}", "owner"},
+	{"GET", "/timelines/{timelineId}/share-links", "owner"},
+	{"POST", "/timelines/{timelineId}/share-links", "owner"},
+	{"DELETE", "/timelines/{timelineId}/share-links/{linkId}", "owner"},
+	{"PUT", "/timelines/{timelineId}/tags", "viewer"},
+	{"GET", "/timelines/{timelineId}/revisions", "viewer"},
+	{"GET", "/timelines/{timelineId}/revisions/{revision}", "viewer"},
+	{"POST", "/timelines/{timelineId}/revisions/{revision}/restore", "editor"},
+	{"GET", "/timelines/{timelineId}/events", "viewer"},
+	{"POST", "/timelines/{timelineId}/events", "editor"},
+	{"PATCH", "/timelines/{timelineId}/events/order", "editor"},
+	{"GET", "/timelines/{timelineId}/export", "viewer"},
+	{"POST", "/timelines/{timelineId}/aievents", "editor"},
+	{"POST", "/timelines/{timelineId}/aievents/commit", "editor"},
+	{"POST", "/timelines/{timelineId}/aievents/stream", "editor"},
+	{"DELETE", "/timelines/{timelineId}/events/{eventId}", "editor"},
+	{"GET", "/timelines/{timelineId}/events/{eventId}/attachments", "viewer"},
+	{"POST", "/timelines/{timelineId}/events/{eventId}/attachments", "editor"},
+	{"GET", "/timelines/{timelineId}/events/{eventId}/attachments/{attachmentId}", "viewer"},
+	{"DELETE", "/timelines/{timelineId}/events/{eventId}/attachments/{attachmentId}", "editor"},
+}
+
+// authorizationQueries are the only queries a request may run before it is
+// turned away: checking the session and the caller's membership.
+var authorizationQueries = map[string]bool{
+	"IsSessionActive": true,
+	"GetTimeLineById": true,
+}
+
+func mustUUID(t *testing.T, s string) pgtype.UUID {
+	t.Helper()
+	var id pgtype.UUID
+	if err := id.Scan(s); err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+func newTestKeySet(t *testing.T) *auth.KeySet {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	data := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	if err := os.WriteFile(filepath.Join(dir, "test.pem"), data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	keys, err := auth.LoadKeySet(dir, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return keys
+}
+
+// newTestRouter serves the routes from a fake database holding one timeline,
+// owned by ownerID and shared with viewerID as a viewer.
+func newTestRouter(t *testing.T) (*http.ServeMux, *dbtest.DB, *auth.KeySet) {
+	t.Helper()
+	fake := dbtest.New()
+	fake.On("IsSessionActive", func(args []any) (any, error) {
+		return true, nil
+	})
+	owner, viewer := mustUUID(t, ownerID), mustUUID(t, viewerID)
+	timeline := mustUUID(t, timelineID)
+	fake.On("GetTimeLineById", func(args []any) (any, error) {
+		if args[0].(pgtype.UUID) != timeline {
+			return nil, nil
+		}
+		row := db.GetTimeLineByIdRow{ID: timeline, UserID: owner, Title: "Owner's timeline"}
+		switch args[1].(pgtype.UUID) {
+		case owner:
+			row.Role = "owner"
+		case viewer:
+			row.Role = "viewer"
+		default:
+			return nil, nil
+		}
+		return row, nil
+	})
+
+	files, err := storage.NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := newTestKeySet(t)
+	logger := log.New(io.Discard, "", 0)
+	queries := db.New(fake)
+	userHandler := handlers.NewUserHandler(queries, nil, keys, nil, "", false, nil, logger)
+	application := &app.Application{
+		DB:                queries,
+		Logger:            logger,
+		TokenKeys:         keys,
+		UserHandler:       userHandler,
+		TimelineHandler:   handlers.NewTimelineHandler(queries, fake, logger),
+		EventHandler:      handlers.NewEventHandler(queries, fake, ai.NewFake(nil), ai.Limits{}, logger),
+		PublicHandler:     handlers.NewPublicHandler(queries, logger),
+		TrashHandler:      handlers.NewTrashHandler(queries, nil, 0, logger),
+		TagHandler:        handlers.NewTagHandler(queries, nil, logger),
+		AttachmentHandler: handlers.NewAttachmentHandler(queries, files, 1<<20, logger),
+		SSOHandler:        handlers.NewSSOHandler(queries, fake, nil, userHandler, logger),
+		JWKSHandler:       handlers.NewJWKSHandler(keys),
+	}
+	return SetupRoutes(application), fake, keys
+}
+
+func newTimelineRequest(t *testing.T, keys *auth.KeySet, userID, method, pattern string) *http.Request {
+	t.Helper()
+	path := strings.NewReplacer(
+		"{timelineId}", timelineID,
+		"{userId}", ownerID,
+		"{linkId}", itemID,
+		"{revision}", "1",
+		"{eventId}", eventID,
+		"{attachmentId}", itemID,
+	).Replace(pattern)
+
+	token, err := keys.GenerateToken(userID, "0195f3a0-0000-7000-8000-000000000051")
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(method, path, strings.NewReader(`{"prompt":"x","title":"x","events":[{"title":"x"}]}`))
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+	return req
+}
+
+// checkTurnedAway checks that the request got the status and ran nothing but
+// the authorization queries.
+func checkTurnedAway(t *testing.T, fake *dbtest.DB, rec *httptest.ResponseRecorder, want int) {
+	t.Helper()
+	if rec.Code != want {
+		t.Errorf("status = %d, want %d: %s", rec.Code, want, rec.Body)
+	}
+	if fake.Called("GetTimeLineById") == 0 {
+		t.Error("membership was not checked")
+	}
+	for _, call := range fake.Calls() {
+		if !authorizationQueries[call.Name] {
+			t.Errorf("ran %s before turning the request away", call.Name)
+		}
+	}
+}
+
+func TestTimelineRoutesRejectNonMembers(t *testing.T) {
+	for _, route := range timelineRoutes {
+		t.Run(route.method+" "+route.path, func(t *testing.T) {
+			router, fake, keys := newTestRouter(t)
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, newTimelineRequest(t, keys, strangerID, route.method, route.path))
+			checkTurnedAway(t, fake, rec, http.StatusNotFound)
+		})
+	}
+}
+
+func TestTimelineRoutesRejectViewersBelowTheirRole(t *testing.T) {
+	for _, route := range timelineRoutes {
+		if route.minRole == "viewer" {
+			continue
+		}
+		t.Run(route.method+" "+route.path, func(t *testing.T) {
+			router, fake, keys := newTestRouter(t)
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, newTimelineRequest(t, keys, viewerID, route.method, route.path))
+			checkTurnedAway(t, fake, rec, http.StatusForbidden)
+		})
+	}
+}
+
+// scopedRoutes need more than one scope of a personal access token.
+var scopedRoutes = []struct {
+	method string
+	path   string
+	body   string
+	scopes []string
+}{
+	{"GET", "/timelines/{timelineId}/export", "", []string{auth.ScopeTimelinesRead, auth.ScopeEventsRead}},
+	{"GET", "/timelines/{timelineId}/revisions/{revision}", "", []string{auth.ScopeTimelinesRead, auth.ScopeEventsRead}},
+	{"POST", "/timelines/{timelineId}/revisions/{revision}/restore", "", []string{auth.ScopeTimelinesWrite, auth.ScopeEventsWrite}},
+	{"POST", "/timelines/import", "", []string{auth.ScopeTimelinesWrite, auth.ScopeEventsWrite}},
+	{"POST", "/timelines/{timelineId}/aievents", `{"prompt":"x"}`, []string{auth.ScopeAIGenerate, auth.ScopeEventsWrite}},
+	{"POST", "/timelines/{timelineId}/aievents", `{"prompt":"x","mode":"append"}`, []string{auth.ScopeAIGenerate, auth.ScopeEventsWrite}},
+	{"POST", "/timelines/{timelineId}/aievents", `{"prompt":"x","mode":"replace"}`, []string{auth.ScopeAIGenerate, auth.ScopeEventsWrite}},
+	{"POST", "/timelines/{timelineId}/aievents", `{"prompt":"x","mode":"preview"}`, []string{auth.ScopeAIGenerate}},
+	{"POST", "/timelines/{timelineId}/aievents/stream", `{"prompt":"x"}`, []string{auth.ScopeAIGenerate, auth.ScopeEventsWrite}},
+	{"POST", "/timelines/{timelineId}/aievents/stream", `{"prompt":"x","persist_incrementally":true}`, []string{auth.ScopeAIGenerate, auth.ScopeEventsWrite}},
+}
+
+// newTokenRequest is a request made with a personal access token that has
+// the scopes given.
+func newTokenRequest(t *testing.T, fake *dbtest.DB, scopes []string, method, pattern, body string) *http.Request {
+	t.Helper()
+	fake.On("UsePersonalAccessToken", func(args []any) (any, error) {
+		return db.UsePersonalAccessTokenRow{UserID: mustUUID(t, ownerID), Scopes: scopes}, nil
+	})
+	path := strings.NewReplacer("{timelineId}", timelineID, "{revision}", "1").Replace(pattern)
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+auth.PersonalAccessTokenPrefix+"test")
+	req.Header.Set("Content-Type", "application/json")
+	return req
+}
+
+func TestScopedRoutesNeedEveryScope(t *testing.T) {
+	for _, route := range scopedRoutes {
+		for i := range route.scopes {
+			missing := route.scopes[i]
+			scopes := append(slices.Clone(route.scopes[:i]), route.scopes[i+1:]...)
+			t.Run(route.method+" "+route.path+" "+route.body+" without "+missing, func(t *testing.T) {
+				router, fake, _ := newTestRouter(t)
+				rec := httptest.NewRecorder()
+				router.ServeHTTP(rec, newTokenRequest(t, fake, scopes, route.method, route.path, route.body))
+				if rec.Code != http.StatusForbidden || !strings.Contains(rec.Body.String(), missing) {
+					t.Errorf("status = %d, want %d for the missing %s scope: %s", rec.Code, http.StatusForbidden, missing, rec.Body)
+				}
+				for _, call := range fake.Calls() {
+					if call.Name != "UsePersonalAccessToken" && !authorizationQueries[call.Name] {
+						t.Errorf("ran %s before turning the request away", call.Name)
+					}
+				}
+			})
+		}
+
+		t.Run(route.method+" "+route.path+" "+route.body+" with every scope", func(t *testing.T) {
+			router, fake, _ := newTestRouter(t)
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, newTokenRequest(t, fake, route.scopes, route.method, route.path, route.body))
+			if strings.Contains(rec.Body.String(), "scope") {
+				t.Errorf("status = %d, want the scopes accepted: %s", rec.Code, rec.Body)
+			}
+		})
+	}
+}
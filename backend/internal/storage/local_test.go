@@ -0,0 +1,116 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLocalRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	l, err := NewLocal(filepath.Join(dir, "uploads"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	const key = "attachments/2024/photo.png"
+
+	if err := l.Put(ctx, key, strings.NewReader("png bytes"), 9, "image/png"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "uploads", "attachments", "2024", "photo.png")); err != nil {
+		t.Errorf("file isn't under the directory: %v", err)
+	}
+
+	rc, err := l.Get(ctx, key)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	got, err := io.ReadAll(rc)
+	rc.Close()
+	if err != nil || string(got) != "png bytes" {
+		t.Errorf("Get = %q, %v", got, err)
+	}
+
+	if err := l.Delete(ctx, key); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := l.Get(ctx, key); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get after Delete = %v, want ErrNotFound", err)
+	}
+	if err := l.Delete(ctx, key); err != nil {
+		t.Errorf("deleting a missing file = %v, want nil", err)
+	}
+}
+
+func TestLocalRejectsKeysOutsideItsDirectory(t *testing.T) {
+	dir := t.TempDir()
+	l, err := NewLocal(filepath.Join(dir, "uploads"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	outside := filepath.Join(dir, "secret")
+	if err := os.WriteFile(outside, []byte("secret"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	for _, key := range []string{
+		"",
+		"../secret",
+		"attachments/../../secret",
+		"..",
+		"/" + filepath.ToSlash(outside),
+	} {
+		t.Run(key, func(t *testing.T) {
+			if err := l.Put(ctx, key, strings.NewReader("overwritten"), 11, "text/plain"); err == nil {
+				t.Error("Put succeeded")
+			}
+			if rc, err := l.Get(ctx, key); err == nil {
+				rc.Close()
+				t.Error("Get succeeded")
+			}
+			if err := l.Delete(ctx, key); err == nil {
+				t.Error("Delete succeeded")
+			}
+		})
+	}
+
+	if got, err := os.ReadFile(outside); err != nil || string(got) != "secret" {
+		t.Errorf("file outside the directory is now %q, %v", got, err)
+	}
+}
+
+// failingReader fails partway through an upload.
+type failingReader struct{}
+
+func (failingReader) Read(p []byte) (int, error) {
+	return 0, errors.New("connection reset")
+}
+
+func TestLocalFailedPutLeavesNoFile(t *testing.T) {
+	dir := t.TempDir()
+	l, err := NewLocal(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	if err := l.Put(ctx, "a/file", io.MultiReader(strings.NewReader("partial"), failingReader{}), 100, "text/plain"); err == nil {
+		t.Fatal("Put succeeded")
+	}
+	if _, err := l.Get(ctx, "a/file"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get = %v, want ErrNotFound", err)
+	}
+	entries, err := os.ReadDir(filepath.Join(dir, "a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("left %d files behind", len(entries))
+	}
+}
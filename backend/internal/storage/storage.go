@@ -0,0 +1,96 @@
+// Package storage keeps the bytes of uploaded files, on the local disk or in
+// an S3-compatible bucket.
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+)
+
+// Store saves, reads and deletes files by key. Keys are made by the caller
+// and use slashes as separators.
+type Store interface {
+	Name() string
+	Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error
+	// Get returns ErrNotFound when there is no file with the key.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete succeeds when there is no file with the key.
+	Delete(ctx context.Context, key string) error
+}
+
+const (
+	BackendLocal = "local"
+	BackendS3    = "s3"
+
+	defaultDir      = "uploads"
+	defaultRegion   = "us-east-1"
+	defaultMaxBytes = 10 << 20
+)
+
+var ErrNotFound = errors.New("storage: file not found")
+
+type Config struct {
+	Backend string
+	// Dir is where the local backend keeps files.
+	Dir string
+	// Endpoint is the root URL of an S3-compatible server, such as
+	// http://localhost:9000 for MinIO. It defaults to AWS in Region.
+	Endpoint        string
+	Region          string
+	Bucket          string
+	AccessKeyID     string
+	SecretAccessKey string
+}
+
+// ConfigFromEnv reads STORAGE_BACKEND, STORAGE_DIR, S3_ENDPOINT, S3_REGION,
+// S3_BUCKET, S3_ACCESS_KEY_ID and S3_SECRET_ACCESS_KEY.
+func ConfigFromEnv() Config {
+	cfg := Config{
+		Backend:         os.Getenv("STORAGE_BACKEND"),
+		Dir:             os.Getenv("STORAGE_DIR"),
+		Endpoint:        os.Getenv("S3_ENDPOINT"),
+		Region:          os.Getenv("S3_REGION"),
+		Bucket:          os.Getenv("S3_BUCKET"),
+		AccessKeyID:     os.Getenv("S3_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv("S3_SECRET_ACCESS_KEY"),
+	}
+	if cfg.Backend == "" {
+		cfg.Backend = BackendLocal
+	}
+	if cfg.Dir == "" {
+		cfg.Dir = defaultDir
+	}
+	if cfg.Region == "" {
+		cfg.Region = defaultRegion
+	}
+	return cfg
+}
+
+func New(cfg Config) (Store, error) {
+	switch cfg.Backend {
+	case BackendLocal:
+		return NewLocal(cfg.Dir)
+	case BackendS3:
+		return NewS3(cfg.Endpoint, cfg.Region, cfg.Bucket, cfg.AccessKeyID, cfg.SecretAccessKey)
+	default:
+		return nil, fmt.Errorf("storage: unknown backend %q", cfg.Backend)
+	}
+}
+
+// MaxBytesFromEnv reads ATTACHMENT_MAX_BYTES, the largest file that can be
+// uploaded. It defaults to 10 MiB.
+func MaxBytesFromEnv() (int64, error) {
+	v := os.Getenv("ATTACHMENT_MAX_BYTES")
+	if v == "" {
+		return defaultMaxBytes, nil
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("storage: invalid ATTACHMENT_MAX_BYTES %q", v)
+	}
+	return n, nil
+}
@@ -0,0 +1,130 @@
+package trash
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/nabsk911/chronify/internal/db"
+	"github.com/nabsk911/chronify/internal/storage"
+)
+
+const (
+	defaultRetentionDays = 30
+	purgeInterval        = time.Hour
+	attachmentBatchSize  = 500
+)
+
+// RetentionFromEnv reads TRASH_RETENTION_DAYS, how long trashed timelines
+// and events are kept before they are purged. It defaults to 30 days.
+func RetentionFromEnv() (time.Duration, error) {
+	days := defaultRetentionDays
+	if v := os.Getenv("TRASH_RETENTION_DAYS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return 0, fmt.Errorf("trash: invalid TRASH_RETENTION_DAYS %q", v)
+		}
+		days = n
+	}
+	return time.Duration(days) * 24 * time.Hour, nil
+}
+
+// Purger permanently deletes what has been in the trash for longer than the
+// retention period, and the files attached to events that are gone.
+type Purger struct {
+	store     *db.Queries
+	files     storage.Store
+	retention time.Duration
+	logger    *log.Logger
+}
+
+func NewPurger(store *db.Queries, files storage.Store, retention time.Duration, logger *log.Logger) *Purger {
+	return &Purger{
+		store:     store,
+		files:     files,
+		retention: retention,
+		logger:    logger,
+	}
+}
+
+// Run purges the trash straight away and then every hour until ctx is done.
+func (p *Purger) Run(ctx context.Context) {
+	ticker := time.NewTicker(purgeInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := p.Purge(ctx); err != nil {
+			p.logger.Printf("Failed to purge trash: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Purge deletes the timelines and events trashed before the retention period,
+// then the attachments left without an event.
+func (p *Purger) Purge(ctx context.Context) error {
+	cutoff := pgtype.Timestamptz{Time: time.Now().Add(-p.retention), Valid: true}
+
+	events, err := p.store.PurgeTrashedEvents(ctx, cutoff)
+	if err != nil {
+		return err
+	}
+	timelines, err := p.store.PurgeTrashedTimelines(ctx, cutoff)
+	if err != nil {
+		return err
+	}
+
+	if events > 0 || timelines > 0 {
+		p.logger.Printf("Purged %d timelines and %d events from the trash", timelines, events)
+	}
+
+	return p.purgeAttachments(ctx)
+}
+
+// purgeAttachments deletes the attachments of events that have been purged,
+// however that happened. A file is deleted before its row, so a file that
+// can't be deleted keeps its row and is retried on the next run; the rest
+// are purged meanwhile.
+func (p *Purger) purgeAttachments(ctx context.Context) error {
+	purged, failed := 0, 0
+	// The zero UUID sorts before every other.
+	after := pgtype.UUID{Valid: true}
+	for {
+		orphans, err := p.store.GetOrphanedAttachments(ctx, db.GetOrphanedAttachmentsParams{
+			After:     after,
+			BatchSize: attachmentBatchSize,
+		})
+		if err != nil {
+			return err
+		}
+		for _, a := range orphans {
+			after = a.ID
+			if err := p.files.Delete(ctx, a.StorageKey); err != nil {
+				p.logger.Printf("Failed to delete attachment %s: %v", a.ID, err)
+				failed++
+				continue
+			}
+			if err := p.store.PurgeAttachment(ctx, a.ID); err != nil {
+				return err
+			}
+			purged++
+		}
+		if len(orphans) < attachmentBatchSize {
+			break
+		}
+	}
+
+	if purged > 0 || failed > 0 {
+		p.logger.Printf("Purged %d attachments of deleted events, %d left to retry", purged, failed)
+	}
+	return nil
+}
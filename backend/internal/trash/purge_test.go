@@ -0,0 +1,83 @@
+package trash
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/nabsk911/chronify/internal/db"
+	"github.com/nabsk911/chronify/internal/dbtest"
+)
+
+// files is a storage.Store that fails to delete some keys.
+type files struct {
+	failing map[string]bool
+	deleted []string
+}
+
+func (f *files) Name() string { return "test" }
+
+func (f *files) Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error {
+	return nil
+}
+
+func (f *files) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (f *files) Delete(ctx context.Context, key string) error {
+	if f.failing[key] {
+		return errors.New("access denied")
+	}
+	f.deleted = append(f.deleted, key)
+	return nil
+}
+
+func TestPurgeAttachmentsSkipsFailedDeletes(t *testing.T) {
+	// More than a page of orphans, the first of which can't be deleted.
+	var orphans []db.GetOrphanedAttachmentsRow
+	for i := range attachmentBatchSize + 2 {
+		id := pgtype.UUID{Bytes: [16]byte{14: byte(i >> 8), 15: byte(i)}, Valid: true}
+		orphans = append(orphans, db.GetOrphanedAttachmentsRow{ID: id, StorageKey: id.String()})
+	}
+	store := &files{failing: map[string]bool{orphans[0].StorageKey: true}}
+
+	fake := dbtest.New()
+	purged := make(map[pgtype.UUID]bool)
+	fake.On("GetOrphanedAttachments", func(args []any) (any, error) {
+		after, limit := args[0].(pgtype.UUID), int(args[1].(int32))
+		var page []db.GetOrphanedAttachmentsRow
+		for _, a := range orphans {
+			if !purged[a.ID] && bytes.Compare(a.ID.Bytes[:], after.Bytes[:]) > 0 && len(page) < limit {
+				page = append(page, a)
+			}
+		}
+		return page, nil
+	})
+	fake.On("PurgeAttachment", func(args []any) (any, error) {
+		purged[args[0].(pgtype.UUID)] = true
+		return nil, nil
+	})
+	fake.On("PurgeTrashedEvents", func(args []any) (any, error) { return int64(0), nil })
+	fake.On("PurgeTrashedTimelines", func(args []any) (any, error) { return int64(0), nil })
+
+	p := NewPurger(db.New(fake), store, time.Hour, log.New(io.Discard, "", 0))
+	if err := p.Purge(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(purged) != len(orphans)-1 || purged[orphans[0].ID] {
+		t.Errorf("purged %d attachments, want all %d but the one that failed", len(purged), len(orphans)-1)
+	}
+	if len(store.deleted) != len(orphans)-1 {
+		t.Errorf("deleted %d files, want %d: each page should start after the last", len(store.deleted), len(orphans)-1)
+	}
+	if calls := fake.Called("GetOrphanedAttachments"); calls != 2 {
+		t.Errorf("%d pages read, want 2", calls)
+	}
+}
@@ -0,0 +1,33 @@
+package utils
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func ReadUserID(r *http.Request) (pgtype.UUID, error) {
+	return readContextID(r, "userID")
+}
+
+func ReadSessionID(r *http.Request) (pgtype.UUID, error) {
+	return readContextID(r, "sessionID")
+}
+
+func readContextID(r *http.Request, key string) (pgtype.UUID, error) {
+	idStr, _ := r.Context().Value(key).(string)
+	var id pgtype.UUID
+	err := id.Scan(idStr)
+	if err != nil {
+		return id, err
+	}
+	return id, nil
+}
+
+// HasScope reports whether the request may do what scope allows. Sessions
+// may do everything; personal access tokens only what their scopes allow.
+func HasScope(r *http.Request, scope string) bool {
+	scopes, ok := r.Context().Value("scopes").([]string)
+	return !ok || slices.Contains(scopes, scope)
+}
@@ -0,0 +1,46 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// EventStream writes Server-Sent Events, flushing each one to the client as
+// soon as it is written.
+type EventStream struct {
+	w  http.ResponseWriter
+	rc *http.ResponseController
+}
+
+// NewEventStream starts an event stream. It lifts the server's write
+// deadline, since a stream is expected to outlive it. It fails before
+// writing anything when w can't stream, so the caller can still answer with
+// an error; a failed first flush shows up on the first Send instead.
+func NewEventStream(w http.ResponseWriter) (*EventStream, error) {
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		return nil, err
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	rc.Flush()
+	return &EventStream{w: w, rc: rc}, nil
+}
+
+func (s *EventStream) Send(event string, data any) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(s.w, "event: %s\ndata: %s\n\n", event, payload); err != nil {
+		return err
+	}
+	return s.rc.Flush()
+}
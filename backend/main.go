@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"log"
 	"net/http"
 	"time"
@@ -23,6 +24,9 @@ func main() {
 	}
 
 	defer app.DBConn.Close()
+
+	go app.TrashPurger.Run(context.Background())
+
 	app.Logger.Println("Starting server on port 8080")
 
 	r := routes.SetupRoutes(app)
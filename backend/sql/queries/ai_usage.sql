@@ -0,0 +1,32 @@
+-- name: GetAIUsageSince :one
+-- Reservations count until they are settled, unless the server that made
+-- them went away before settling: no generation runs for 15 minutes.
+SELECT COUNT(*) AS requests,
+    COALESCE(SUM(
+        prompt_tokens + output_tokens
+        + CASE WHEN created_at > CURRENT_TIMESTAMP - INTERVAL '15 minutes' THEN reserved_tokens ELSE 0 END
+    ), 0)::BIGINT AS tokens
+FROM ai_usage
+WHERE user_id = $1 AND created_at >= $2;
+
+-- name: LockAIUsage :exec
+-- Serializes a user's quota checks, so concurrent requests can't each reserve
+-- the same remaining tokens.
+SELECT id FROM users
+WHERE id = $1
+FOR NO KEY UPDATE;
+
+-- name: ReserveAIUsage :one
+INSERT INTO ai_usage (user_id, timeline_id, provider, model, reserved_tokens, latency_ms, success)
+VALUES ($1, $2, $3, $4, $5, 0, FALSE)
+RETURNING id;
+
+-- name: SettleAIUsage :exec
+-- Records what a generation actually used, releasing its reservation.
+UPDATE ai_usage
+SET prompt_tokens = $2,
+    output_tokens = $3,
+    latency_ms = $4,
+    success = $5,
+    reserved_tokens = 0
+WHERE id = $1;
@@ -0,0 +1,43 @@
+-- name: CreateAttachment :one
+INSERT INTO attachments (event_id, uploaded_by, filename, content_type, size_bytes, width, height, storage_key)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+RETURNING *;
+
+-- name: GetAttachmentsByEventId :many
+SELECT * FROM attachments
+WHERE event_id = $1
+ORDER BY created_at ASC;
+
+-- name: GetAttachment :one
+-- The attachment, if its event is live and in the timeline.
+SELECT attachments.* FROM attachments
+JOIN events ON events.id = attachments.event_id
+WHERE attachments.id = $1
+    AND attachments.event_id = $2
+    AND events.timeline_id = $3
+    AND events.deleted_at IS NULL;
+
+-- name: DeleteAttachment :one
+-- Deletes the attachment if its event is live and in the timeline, returning
+-- the key of its file.
+DELETE FROM attachments
+WHERE id = $1 AND event_id = $2 AND EXISTS (
+    SELECT 1 FROM events
+    WHERE events.id = attachments.event_id
+        AND events.timeline_id = $3
+        AND events.deleted_at IS NULL
+)
+RETURNING storage_key;
+
+-- name: GetOrphanedAttachments :many
+-- For the purge job: attachments whose event has been purged, a page at a
+-- time in ID order, starting after the given ID.
+SELECT id, storage_key FROM attachments
+WHERE id > sqlc.arg(after)::UUID
+    AND NOT EXISTS (SELECT 1 FROM events WHERE events.id = attachments.event_id)
+ORDER BY id
+LIMIT sqlc.arg(batch_size);
+
+-- name: PurgeAttachment :exec
+DELETE FROM attachments
+WHERE id = $1;
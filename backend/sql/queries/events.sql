@@ -1,11 +1,46 @@
 -- name: BulkCreateEvents :copyfrom
-INSERT INTO events ( timeline_id, title, card_title, card_subtitle, card_detailed_text)
-VALUES ( $1, $2, $3, $4, $5);
+INSERT INTO events (
+    timeline_id, title, card_title, card_subtitle, card_detailed_text,
+    start_year, start_month, start_day, start_minute_of_day,
+    end_year, end_month, end_day, end_minute_of_day, date_precision, position
+)
+VALUES ( $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15);
+
+-- name: CreateEvent :one
+INSERT INTO events (
+    timeline_id, title, card_title, card_subtitle, card_detailed_text,
+    start_year, start_month, start_day, start_minute_of_day,
+    end_year, end_month, end_day, end_minute_of_day, date_precision, position
+)
+VALUES ( $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+RETURNING *;
 
 -- name: GetEventsByTimelineId :many
+-- Undated events sort after dated ones; a coarser date sorts before finer
+-- dates in the same period.
+SELECT * FROM events
+WHERE timeline_id = $1 AND deleted_at IS NULL
+ORDER BY
+    start_year ASC NULLS LAST,
+    start_month ASC NULLS FIRST,
+    start_day ASC NULLS FIRST,
+    start_minute_of_day ASC NULLS FIRST,
+    created_at ASC;
+
+-- name: GetEventsByTimelineIdByPosition :many
 SELECT * FROM events
-WHERE timeline_id = $1
-ORDER BY created_at ASC;
+WHERE timeline_id = $1 AND deleted_at IS NULL
+ORDER BY position ASC, created_at ASC;
+
+-- name: GetEventById :one
+SELECT * FROM events
+WHERE id = $1 AND timeline_id = $2 AND deleted_at IS NULL;
+
+-- name: GetEventPositions :many
+SELECT id, position FROM events
+WHERE timeline_id = $1 AND deleted_at IS NULL
+ORDER BY position ASC
+FOR UPDATE;
 
 -- name: BulkUpdateEvents :batchexec
 UPDATE events
@@ -14,11 +49,177 @@ SET
     card_title = $3, 
     card_subtitle = $4, 
     card_detailed_text = $5,
+    start_year = $6,
+    start_month = $7,
+    start_day = $8,
+    start_minute_of_day = $9,
+    end_year = $10,
+    end_month = $11,
+    end_day = $12,
+    end_minute_of_day = $13,
+    date_precision = $14,
     updated_at = CURRENT_TIMESTAMP
-WHERE id = $1;
+WHERE id = $1 AND timeline_id = $15 AND deleted_at IS NULL;
+
+-- name: UpdateEventPositions :batchexec
+UPDATE events
+SET position = $2, updated_at = CURRENT_TIMESTAMP
+WHERE id = $1 AND timeline_id = $3 AND deleted_at IS NULL;
+
+-- name: TrashEvent :execrows
+UPDATE events
+SET deleted_at = CURRENT_TIMESTAMP
+WHERE id = $1 AND timeline_id = $2 AND deleted_at IS NULL;
+
+-- name: TrashEventsByTimelineId :exec
+UPDATE events
+SET deleted_at = CURRENT_TIMESTAMP
+WHERE timeline_id = $1 AND deleted_at IS NULL;
+
+-- name: PurgeEventsByIds :exec
+-- Makes way for RestoreEvents to put the events back with the same IDs.
+DELETE FROM events
+WHERE timeline_id = $1 AND id = ANY(sqlc.arg(ids)::UUID[]);
+
+-- name: RestoreEvents :copyfrom
+INSERT INTO events (
+    id, timeline_id, title, card_title, card_subtitle, card_detailed_text,
+    start_year, start_month, start_day, start_minute_of_day,
+    end_year, end_month, end_day, end_minute_of_day, date_precision, position, created_at
+)
+VALUES ( $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17);
+
+-- name: GetTrashedEventsByUserId :many
+-- Events trashed from timelines the user can edit. Events of a trashed
+-- timeline are left out; they come back with it.
+SELECT events.id, events.timeline_id, timelines.title AS timeline_title,
+    events.title, events.card_title, events.deleted_at
+FROM events
+JOIN timelines ON timelines.id = events.timeline_id
+JOIN timeline_members ON timeline_members.timeline_id = events.timeline_id
+WHERE timeline_members.user_id = $1
+    AND timeline_members.role IN ('editor', 'owner')
+    AND timelines.deleted_at IS NULL
+    AND events.deleted_at IS NOT NULL
+ORDER BY events.deleted_at DESC;
+
+-- name: GetTrashedEventTimelineId :one
+SELECT events.timeline_id FROM events
+JOIN timelines ON timelines.id = events.timeline_id
+JOIN timeline_members ON timeline_members.timeline_id = events.timeline_id
+WHERE events.id = $1
+    AND timeline_members.user_id = $2
+    AND timeline_members.role IN ('editor', 'owner')
+    AND timelines.deleted_at IS NULL
+    AND events.deleted_at IS NOT NULL;
 
--- name: DeleteEvent :exec
+-- name: RestoreEvent :execrows
+UPDATE events
+SET deleted_at = NULL, updated_at = CURRENT_TIMESTAMP
+WHERE id = $1 AND timeline_id = $2 AND deleted_at IS NOT NULL;
+
+-- name: PurgeEvent :execrows
 DELETE FROM events
-WHERE id = $1;
+WHERE id = $1 AND deleted_at IS NOT NULL AND timeline_id IN (
+    SELECT timelines.id FROM timelines
+    JOIN timeline_members ON timeline_members.timeline_id = timelines.id
+    WHERE timeline_members.user_id = $2
+        AND timeline_members.role IN ('editor', 'owner')
+        AND timelines.deleted_at IS NULL
+);
 
+-- name: PurgeEventsByUserId :execrows
+DELETE FROM events
+WHERE deleted_at IS NOT NULL AND timeline_id IN (
+    SELECT timelines.id FROM timelines
+    JOIN timeline_members ON timeline_members.timeline_id = timelines.id
+    WHERE timeline_members.user_id = $1
+        AND timeline_members.role IN ('editor', 'owner')
+        AND timelines.deleted_at IS NULL
+);
+
+-- name: PurgeTrashedEvents :execrows
+-- For the purge job: removes events trashed before the cutoff.
+DELETE FROM events
+WHERE deleted_at < $1;
+
+-- name: GetEventsPageByDate :many
+-- A page of the timeline's events in the order of GetEventsByTimelineId,
+-- starting after the cursor when one is given. Missing date parts are
+-- replaced with values that sort the same way, so rows can be compared.
+-- With tag_ids, only events the user tagged with one of them match.
+SELECT * FROM events
+WHERE timeline_id = sqlc.arg(timeline_id)
+    AND deleted_at IS NULL
+    AND (
+        sqlc.narg(cursor_id)::UUID IS NULL
+        OR (
+            COALESCE(start_year, 9223372036854775807),
+            COALESCE(start_month, 0),
+            COALESCE(start_day, 0),
+            COALESCE(start_minute_of_day, -1),
+            created_at,
+            id
+        ) > (
+            COALESCE(sqlc.narg(cursor_start_year)::BIGINT, 9223372036854775807),
+            COALESCE(sqlc.narg(cursor_start_month)::SMALLINT, 0),
+            COALESCE(sqlc.narg(cursor_start_day)::SMALLINT, 0),
+            COALESCE(sqlc.narg(cursor_start_minute_of_day)::SMALLINT, -1),
+            sqlc.narg(cursor_created_at)::TIMESTAMPTZ,
+            sqlc.narg(cursor_id)::UUID
+        )
+    )
+    AND (
+        sqlc.narg(tag_ids)::UUID[] IS NULL
+        OR EXISTS (
+            SELECT 1 FROM event_tags
+            JOIN tags ON tags.id = event_tags.tag_id
+            WHERE event_tags.event_id = events.id
+                AND tags.user_id = sqlc.arg(user_id)
+                AND event_tags.tag_id = ANY(sqlc.narg(tag_ids)::UUID[])
+        )
+    )
+ORDER BY
+    COALESCE(start_year, 9223372036854775807),
+    COALESCE(start_month, 0),
+    COALESCE(start_day, 0),
+    COALESCE(start_minute_of_day, -1),
+    created_at,
+    id
+LIMIT sqlc.arg(max_rows);
+
+-- name: GetEventsPageByPosition :many
+SELECT * FROM events
+WHERE timeline_id = sqlc.arg(timeline_id)
+    AND deleted_at IS NULL
+    AND (
+        sqlc.narg(cursor_id)::UUID IS NULL
+        OR (position, created_at, id) > (sqlc.narg(cursor_position)::TEXT, sqlc.narg(cursor_created_at)::TIMESTAMPTZ, sqlc.narg(cursor_id)::UUID)
+    )
+    AND (
+        sqlc.narg(tag_ids)::UUID[] IS NULL
+        OR EXISTS (
+            SELECT 1 FROM event_tags
+            JOIN tags ON tags.id = event_tags.tag_id
+            WHERE event_tags.event_id = events.id
+                AND tags.user_id = sqlc.arg(user_id)
+                AND event_tags.tag_id = ANY(sqlc.narg(tag_ids)::UUID[])
+        )
+    )
+ORDER BY position ASC, created_at ASC, id ASC
+LIMIT sqlc.arg(max_rows);
 
+-- name: CountEventsByTimelineId :one
+SELECT COUNT(*) FROM events
+WHERE timeline_id = sqlc.arg(timeline_id)
+    AND deleted_at IS NULL
+    AND (
+        sqlc.narg(tag_ids)::UUID[] IS NULL
+        OR EXISTS (
+            SELECT 1 FROM event_tags
+            JOIN tags ON tags.id = event_tags.tag_id
+            WHERE event_tags.event_id = events.id
+                AND tags.user_id = sqlc.arg(user_id)
+                AND event_tags.tag_id = ANY(sqlc.narg(tag_ids)::UUID[])
+        )
+    );
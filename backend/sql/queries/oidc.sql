@@ -0,0 +1,28 @@
+-- name: CreateOIDCLoginState :exec
+INSERT INTO oidc_login_states (provider, state_hash, nonce, code_verifier, expires_at)
+VALUES ($1, $2, $3, $4, $5);
+
+-- name: UseOIDCLoginState :one
+-- Removes the login attempt and returns it, unless it has expired. A state
+-- can only be used once.
+DELETE FROM oidc_login_states
+WHERE state_hash = $1 AND provider = $2 AND expires_at > CURRENT_TIMESTAMP
+RETURNING nonce, code_verifier;
+
+-- name: DeleteExpiredOIDCLoginStates :exec
+DELETE FROM oidc_login_states
+WHERE expires_at <= CURRENT_TIMESTAMP;
+
+-- name: GetUserByIdentity :one
+-- The user the provider's account is linked to, recording the login.
+UPDATE user_identities
+SET last_login_at = CURRENT_TIMESTAMP
+FROM users
+WHERE users.id = user_identities.user_id
+    AND user_identities.provider = $1
+    AND user_identities.subject = $2
+RETURNING users.*;
+
+-- name: CreateUserIdentity :exec
+INSERT INTO user_identities (user_id, provider, subject, email)
+VALUES ($1, $2, $3, $4);
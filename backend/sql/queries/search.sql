@@ -0,0 +1,52 @@
+-- name: SearchTimelines :many
+-- Timelines the user can see that match the query themselves or through one
+-- of their events, best match first.
+SELECT timelines.id, timeline_members.role,
+    ts_headline('english', timelines.title, query, 'HighlightAll=true, StartSel=<mark>, StopSel=</mark>')::TEXT AS title,
+    ts_headline('english', COALESCE(timelines.description, ''), query, 'MaxWords=30, MinWords=10, StartSel=<mark>, StopSel=</mark>')::TEXT AS snippet,
+    GREATEST(
+        ts_rank(timelines.search_vector, query),
+        COALESCE((
+            SELECT MAX(ts_rank(events.search_vector, query)) FROM events
+            WHERE events.timeline_id = timelines.id
+                AND events.deleted_at IS NULL
+                AND events.search_vector @@ query
+        ), 0)
+    )::REAL AS rank
+FROM timelines
+JOIN timeline_members ON timeline_members.timeline_id = timelines.id
+CROSS JOIN websearch_to_tsquery('english', sqlc.arg(query)) AS query
+WHERE timeline_members.user_id = sqlc.arg(user_id)
+    AND timelines.deleted_at IS NULL
+    AND (
+        timelines.search_vector @@ query
+        OR EXISTS (
+            SELECT 1 FROM events
+            WHERE events.timeline_id = timelines.id
+                AND events.deleted_at IS NULL
+                AND events.search_vector @@ query
+        )
+    )
+ORDER BY rank DESC, timelines.created_at DESC
+LIMIT sqlc.arg(max_results);
+
+-- name: SearchEvents :many
+-- The best matching events of each timeline, up to hits_per_timeline. Only
+-- pass timelines from SearchTimelines, which checks the user can see them.
+SELECT events.id, events.timeline_id,
+    ts_headline('english', events.title, query, 'HighlightAll=true, StartSel=<mark>, StopSel=</mark>')::TEXT AS title,
+    ts_headline('english', concat_ws(' ', events.card_title, events.card_subtitle, events.card_detailed_text), query, 'MaxWords=30, MinWords=10, StartSel=<mark>, StopSel=</mark>')::TEXT AS snippet,
+    hits.rank
+FROM (
+    SELECT events.id, ts_rank(events.search_vector, query)::REAL AS rank,
+        row_number() OVER (PARTITION BY events.timeline_id ORDER BY ts_rank(events.search_vector, query) DESC) AS n
+    FROM events
+    CROSS JOIN websearch_to_tsquery('english', sqlc.arg(query)) AS query
+    WHERE events.timeline_id = ANY(sqlc.arg(timeline_ids)::UUID[])
+        AND events.deleted_at IS NULL
+        AND events.search_vector @@ query
+) AS hits
+JOIN events ON events.id = hits.id
+CROSS JOIN websearch_to_tsquery('english', sqlc.arg(query)) AS query
+WHERE hits.n <= sqlc.arg(hits_per_timeline)::INTEGER
+ORDER BY hits.rank DESC;
@@ -0,0 +1,40 @@
+-- name: CreateSession :one
+INSERT INTO sessions (user_id, refresh_token_hash, expires_at)
+VALUES ($1, $2, $3)
+RETURNING *;
+
+-- name: CreateRotatedSession :one
+INSERT INTO sessions (user_id, family_id, refresh_token_hash, expires_at)
+VALUES ($1, $2, $3, $4)
+RETURNING *;
+
+-- name: RotateSession :one
+UPDATE sessions
+SET rotated_at = CURRENT_TIMESTAMP
+WHERE refresh_token_hash = $1
+    AND rotated_at IS NULL
+    AND revoked_at IS NULL
+    AND expires_at > CURRENT_TIMESTAMP
+RETURNING *;
+
+-- name: GetSessionByRefreshTokenHash :one
+SELECT * FROM sessions
+WHERE refresh_token_hash = $1;
+
+-- name: IsSessionActive :one
+SELECT EXISTS (
+    SELECT 1 FROM sessions
+    WHERE family_id = $1
+        AND revoked_at IS NULL
+        AND expires_at > CURRENT_TIMESTAMP
+);
+
+-- name: RevokeSessionFamily :exec
+UPDATE sessions
+SET revoked_at = CURRENT_TIMESTAMP
+WHERE family_id = $1 AND user_id = $2 AND revoked_at IS NULL;
+
+-- name: RevokeUserSessions :exec
+UPDATE sessions
+SET revoked_at = CURRENT_TIMESTAMP
+WHERE user_id = $1 AND revoked_at IS NULL;
@@ -0,0 +1,21 @@
+-- name: CreateShareLink :one
+INSERT INTO share_links (timeline_id, token_hash, password_hash, expires_at, created_by)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING id, timeline_id, expires_at, revoked_at, created_by, created_at, (password_hash IS NOT NULL)::BOOLEAN AS has_password;
+
+-- name: GetShareLinksByTimelineId :many
+SELECT id, timeline_id, expires_at, revoked_at, created_by, created_at, (password_hash IS NOT NULL)::BOOLEAN AS has_password
+FROM share_links
+WHERE timeline_id = $1
+ORDER BY created_at DESC;
+
+-- name: GetActiveShareLinkByTokenHash :one
+SELECT * FROM share_links
+WHERE token_hash = $1
+    AND revoked_at IS NULL
+    AND (expires_at IS NULL OR expires_at > CURRENT_TIMESTAMP);
+
+-- name: RevokeShareLink :execrows
+UPDATE share_links
+SET revoked_at = CURRENT_TIMESTAMP
+WHERE id = $1 AND timeline_id = $2 AND revoked_at IS NULL;
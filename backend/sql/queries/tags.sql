@@ -0,0 +1,88 @@
+-- name: CreateTag :one
+INSERT INTO tags (user_id, name, color)
+VALUES ($1, $2, $3)
+RETURNING *;
+
+-- name: CreateTags :exec
+-- Gives the user the tags they don't have yet, pairing names[i] with
+-- colors[i]. A tag whose name the user already has, ignoring case, is
+-- skipped and keeps its color.
+INSERT INTO tags (user_id, name, color)
+SELECT sqlc.arg(user_id)::UUID, pairs.name, pairs.color
+FROM unnest(sqlc.arg(names)::VARCHAR[], sqlc.arg(colors)::VARCHAR[]) AS pairs(name, color)
+ON CONFLICT (user_id, lower(name)) DO NOTHING;
+
+-- name: GetTagsByNames :many
+-- The user's tags with the given names, ignoring case.
+SELECT id, name FROM tags
+WHERE user_id = sqlc.arg(user_id)
+    AND lower(name) IN (SELECT lower(unnest(sqlc.arg(names)::VARCHAR[])));
+
+-- name: GetTagsByUserId :many
+SELECT * FROM tags
+WHERE user_id = $1
+ORDER BY lower(name) ASC;
+
+-- name: UpdateTag :one
+UPDATE tags
+SET name = $3, color = $4, updated_at = CURRENT_TIMESTAMP
+WHERE id = $1 AND user_id = $2
+RETURNING *;
+
+-- name: DeleteTag :execrows
+DELETE FROM tags
+WHERE id = $1 AND user_id = $2;
+
+-- name: GetTagIdsByUserId :many
+-- The given tags that belong to the user.
+SELECT id FROM tags
+WHERE user_id = sqlc.arg(user_id) AND id = ANY(sqlc.arg(ids)::UUID[]);
+
+-- name: GetTimelineTagsByTimelineIds :many
+-- The user's own tags on the timelines. Other members' tags are not shown.
+SELECT timeline_tags.timeline_id, tags.id, tags.name, tags.color FROM timeline_tags
+JOIN tags ON tags.id = timeline_tags.tag_id
+WHERE tags.user_id = sqlc.arg(user_id)
+    AND timeline_tags.timeline_id = ANY(sqlc.arg(timeline_ids)::UUID[])
+ORDER BY lower(tags.name) ASC;
+
+-- name: DeleteTimelineTagsByUserId :exec
+-- Removes the user's own tags from the timeline, leaving other members' tags.
+DELETE FROM timeline_tags
+WHERE timeline_id = $1 AND tag_id IN (
+    SELECT id FROM tags WHERE user_id = $2
+);
+
+-- name: AddTimelineTags :exec
+INSERT INTO timeline_tags (timeline_id, tag_id)
+SELECT sqlc.arg(timeline_id)::UUID, unnest(sqlc.arg(tag_ids)::UUID[])
+ON CONFLICT DO NOTHING;
+
+-- name: GetEventTagsByEventIds :many
+SELECT event_tags.event_id, tags.id, tags.name, tags.color FROM event_tags
+JOIN tags ON tags.id = event_tags.tag_id
+WHERE event_tags.event_id = ANY(sqlc.arg(event_ids)::UUID[])
+ORDER BY lower(tags.name) ASC;
+
+-- name: GetTagsByIds :many
+-- The given tags, whoever they belong to. Tags deleted since are left out.
+SELECT id, name, color FROM tags
+WHERE id = ANY(sqlc.arg(ids)::UUID[])
+ORDER BY lower(name) ASC;
+
+-- name: DeleteEventTagsByUserId :exec
+-- Removes the user's own tags from the events, leaving other members' tags.
+DELETE FROM event_tags
+WHERE event_id = ANY(sqlc.arg(event_ids)::UUID[]) AND tag_id IN (
+    SELECT id FROM tags WHERE user_id = sqlc.arg(user_id)
+);
+
+-- name: AddEventTags :exec
+-- Tags the events pairwise: event_ids[i] gets tag_ids[i]. Tags that no
+-- longer exist are skipped, so a revision can be restored after one of its
+-- tags was deleted.
+INSERT INTO event_tags (event_id, tag_id)
+SELECT pairs.event_id, pairs.tag_id
+FROM unnest(sqlc.arg(event_ids)::UUID[], sqlc.arg(tag_ids)::UUID[]) AS pairs(event_id, tag_id)
+WHERE EXISTS (SELECT 1 FROM tags WHERE tags.id = pairs.tag_id)
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,21 @@
+-- name: AddTimelineMember :one
+INSERT INTO timeline_members (timeline_id, user_id, role)
+VALUES ($1, $2, $3)
+RETURNING *;
+
+-- name: GetTimelineMembers :many
+SELECT timeline_members.user_id, users.username, users.email, timeline_members.role, timeline_members.created_at
+FROM timeline_members
+JOIN users ON users.id = timeline_members.user_id
+WHERE timeline_members.timeline_id = $1
+ORDER BY timeline_members.created_at ASC;
+
+-- name: UpdateTimelineMemberRole :one
+UPDATE timeline_members
+SET role = $3, updated_at = CURRENT_TIMESTAMP
+WHERE timeline_id = $1 AND user_id = $2
+RETURNING *;
+
+-- name: RemoveTimelineMember :execrows
+DELETE FROM timeline_members
+WHERE timeline_id = $1 AND user_id = $2;
@@ -0,0 +1,28 @@
+-- name: CreateTimelineRevision :exec
+-- Callers hold LockTimeline, so revision numbers can't collide.
+INSERT INTO timeline_revisions (timeline_id, revision, user_id, action, restored_from, snapshot)
+SELECT
+    sqlc.arg(timeline_id)::UUID,
+    COALESCE(MAX(revision), 0) + 1,
+    sqlc.arg(user_id)::UUID,
+    sqlc.arg(action)::VARCHAR,
+    sqlc.narg(restored_from)::INTEGER,
+    timeline_snapshot(sqlc.arg(timeline_id)::UUID)
+FROM timeline_revisions
+WHERE timeline_id = sqlc.arg(timeline_id)::UUID;
+
+-- name: GetTimelineRevisions :many
+SELECT timeline_revisions.revision, timeline_revisions.user_id, users.username,
+    timeline_revisions.action, timeline_revisions.restored_from, timeline_revisions.created_at
+FROM timeline_revisions
+LEFT JOIN users ON users.id = timeline_revisions.user_id
+WHERE timeline_revisions.timeline_id = $1
+ORDER BY timeline_revisions.revision DESC;
+
+-- name: GetTimelineRevision :one
+SELECT timeline_revisions.revision, timeline_revisions.user_id, users.username,
+    timeline_revisions.action, timeline_revisions.restored_from, timeline_revisions.snapshot,
+    timeline_revisions.created_at
+FROM timeline_revisions
+LEFT JOIN users ON users.id = timeline_revisions.user_id
+WHERE timeline_revisions.timeline_id = $1 AND timeline_revisions.revision = $2;
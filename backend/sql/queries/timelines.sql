@@ -3,27 +3,172 @@ INSERT INTO TIMELINES (user_id, title, description)
 VALUES ($1, $2, $3)
 RETURNING id, user_id, title, description, created_at;
 
+-- name: TimelineTitleExists :one
+-- Matches the timelines_user_id_title_key index.
+SELECT EXISTS (
+    SELECT 1 FROM timelines
+    WHERE user_id = sqlc.arg(user_id) AND lower(title) = lower(sqlc.arg(title)::TEXT) AND deleted_at IS NULL
+);
+
 -- name: GetTimeLineById :one
-SELECT * FROM timelines
-WHERE id = $1;
+SELECT timelines.*, timeline_members.role FROM timelines
+JOIN timeline_members ON timeline_members.timeline_id = timelines.id
+WHERE timelines.id = $1 AND timeline_members.user_id = $2 AND timelines.deleted_at IS NULL;
 
 -- name: GetTimelinesByUserId :many
-SELECT * FROM timelines
-WHERE user_id = $1
-ORDER BY created_at DESC;
-
--- name: GetTimelinesByUserIdAndTitle :many
-SELECT * FROM timelines
-WHERE user_id = $1 AND title ILIKE '%' || $2 || '%'
-ORDER BY created_at DESC;
+SELECT timelines.*, timeline_members.role FROM timelines
+JOIN timeline_members ON timeline_members.timeline_id = timelines.id
+WHERE timeline_members.user_id = $1 AND timelines.deleted_at IS NULL
+ORDER BY timelines.created_at DESC;
 
 -- name: UpdateTimeline :one
 UPDATE timelines
 SET title = $2, description = $3
-WHERE id = $1
+WHERE id = $1 AND deleted_at IS NULL AND EXISTS (
+    SELECT 1 FROM timeline_members
+    WHERE timeline_members.timeline_id = timelines.id
+        AND timeline_members.user_id = $4
+        AND timeline_members.role IN ('editor', 'owner')
+)
 RETURNING id, user_id, title, description;
 
--- name: DeleteTimeline :exec
+-- name: TrashTimeline :execrows
+UPDATE timelines
+SET deleted_at = CURRENT_TIMESTAMP
+WHERE id = $1 AND deleted_at IS NULL AND EXISTS (
+    SELECT 1 FROM timeline_members
+    WHERE timeline_members.timeline_id = timelines.id
+        AND timeline_members.user_id = $2
+        AND timeline_members.role = 'owner'
+);
+
+-- name: GetSharedTimeline :one
+-- For public share links, so it doesn't check membership.
+SELECT id, title, description, created_at, updated_at FROM timelines
+WHERE id = $1 AND deleted_at IS NULL;
+
+-- name: LockTimeline :exec
+-- Serializes changes to a timeline, so each gets its own revision number.
+-- Take it before touching the timeline's events to avoid deadlocks.
+SELECT id FROM timelines
+WHERE id = $1
+FOR NO KEY UPDATE;
+
+-- name: GetTrashedTimelinesByUserId :many
+SELECT timelines.id, timelines.title, timelines.description, timelines.created_at, timelines.deleted_at
+FROM timelines
+JOIN timeline_members ON timeline_members.timeline_id = timelines.id
+WHERE timeline_members.user_id = $1
+    AND timeline_members.role = 'owner'
+    AND timelines.deleted_at IS NOT NULL
+ORDER BY timelines.deleted_at DESC;
+
+-- name: RestoreTimeline :execrows
+UPDATE timelines
+SET deleted_at = NULL
+WHERE id = $1 AND deleted_at IS NOT NULL AND EXISTS (
+    SELECT 1 FROM timeline_members
+    WHERE timeline_members.timeline_id = timelines.id
+        AND timeline_members.user_id = $2
+        AND timeline_members.role = 'owner'
+);
+
+-- name: PurgeTimeline :execrows
+DELETE FROM timelines
+WHERE id = $1 AND deleted_at IS NOT NULL AND EXISTS (
+    SELECT 1 FROM timeline_members
+    WHERE timeline_members.timeline_id = timelines.id
+        AND timeline_members.user_id = $2
+        AND timeline_members.role = 'owner'
+);
+
+-- name: PurgeTimelinesByUserId :execrows
 DELETE FROM timelines
-WHERE id = $1;
+WHERE deleted_at IS NOT NULL AND EXISTS (
+    SELECT 1 FROM timeline_members
+    WHERE timeline_members.timeline_id = timelines.id
+        AND timeline_members.user_id = $1
+        AND timeline_members.role = 'owner'
+);
+
+-- name: PurgeTrashedTimelines :execrows
+-- For the purge job: removes timelines trashed before the cutoff.
+DELETE FROM timelines
+WHERE deleted_at < $1;
+
+-- name: GetTimelinesPageByCreatedAt :many
+-- A page of the user's timelines by creation time, starting after the
+-- cursor when one is given. An empty title matches every timeline; with
+-- tag_ids, only timelines the user tagged with one of them match.
+SELECT timelines.*, timeline_members.role FROM timelines
+JOIN timeline_members ON timeline_members.timeline_id = timelines.id
+WHERE timeline_members.user_id = sqlc.arg(user_id)
+    AND timelines.deleted_at IS NULL
+    AND timelines.title ILIKE '%' || sqlc.arg(title)::TEXT || '%'
+    AND (
+        sqlc.narg(cursor_id)::UUID IS NULL
+        OR (sqlc.arg(descending)::BOOLEAN AND (timelines.created_at, timelines.id) < (sqlc.narg(cursor_created_at)::TIMESTAMPTZ, sqlc.narg(cursor_id)::UUID))
+        OR (NOT sqlc.arg(descending)::BOOLEAN AND (timelines.created_at, timelines.id) > (sqlc.narg(cursor_created_at)::TIMESTAMPTZ, sqlc.narg(cursor_id)::UUID))
+    )
+    AND (
+        sqlc.narg(tag_ids)::UUID[] IS NULL
+        OR EXISTS (
+            SELECT 1 FROM timeline_tags
+            JOIN tags ON tags.id = timeline_tags.tag_id
+            WHERE timeline_tags.timeline_id = timelines.id
+                AND tags.user_id = sqlc.arg(user_id)
+                AND timeline_tags.tag_id = ANY(sqlc.narg(tag_ids)::UUID[])
+        )
+    )
+ORDER BY
+    CASE WHEN sqlc.arg(descending)::BOOLEAN THEN timelines.created_at END DESC,
+    CASE WHEN sqlc.arg(descending)::BOOLEAN THEN timelines.id END DESC,
+    timelines.created_at ASC,
+    timelines.id ASC
+LIMIT sqlc.arg(max_rows);
+
+-- name: GetTimelinesPageByTitle :many
+-- Like GetTimelinesPageByCreatedAt, ordered by title ignoring case.
+SELECT timelines.*, timeline_members.role FROM timelines
+JOIN timeline_members ON timeline_members.timeline_id = timelines.id
+WHERE timeline_members.user_id = sqlc.arg(user_id)
+    AND timelines.deleted_at IS NULL
+    AND timelines.title ILIKE '%' || sqlc.arg(title)::TEXT || '%'
+    AND (
+        sqlc.narg(cursor_id)::UUID IS NULL
+        OR (sqlc.arg(descending)::BOOLEAN AND (lower(timelines.title), timelines.id) < (lower(sqlc.narg(cursor_title)::TEXT), sqlc.narg(cursor_id)::UUID))
+        OR (NOT sqlc.arg(descending)::BOOLEAN AND (lower(timelines.title), timelines.id) > (lower(sqlc.narg(cursor_title)::TEXT), sqlc.narg(cursor_id)::UUID))
+    )
+    AND (
+        sqlc.narg(tag_ids)::UUID[] IS NULL
+        OR EXISTS (
+            SELECT 1 FROM timeline_tags
+            JOIN tags ON tags.id = timeline_tags.tag_id
+            WHERE timeline_tags.timeline_id = timelines.id
+                AND tags.user_id = sqlc.arg(user_id)
+                AND timeline_tags.tag_id = ANY(sqlc.narg(tag_ids)::UUID[])
+        )
+    )
+ORDER BY
+    CASE WHEN sqlc.arg(descending)::BOOLEAN THEN lower(timelines.title) END DESC,
+    CASE WHEN sqlc.arg(descending)::BOOLEAN THEN timelines.id END DESC,
+    lower(timelines.title) ASC,
+    timelines.id ASC
+LIMIT sqlc.arg(max_rows);
 
+-- name: CountTimelinesByUserId :one
+SELECT COUNT(*) FROM timelines
+JOIN timeline_members ON timeline_members.timeline_id = timelines.id
+WHERE timeline_members.user_id = sqlc.arg(user_id)
+    AND timelines.deleted_at IS NULL
+    AND timelines.title ILIKE '%' || sqlc.arg(title)::TEXT || '%'
+    AND (
+        sqlc.narg(tag_ids)::UUID[] IS NULL
+        OR EXISTS (
+            SELECT 1 FROM timeline_tags
+            JOIN tags ON tags.id = timeline_tags.tag_id
+            WHERE timeline_tags.timeline_id = timelines.id
+                AND tags.user_id = sqlc.arg(user_id)
+                AND timeline_tags.tag_id = ANY(sqlc.narg(tag_ids)::UUID[])
+        )
+    );
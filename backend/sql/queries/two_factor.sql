@@ -0,0 +1,67 @@
+-- name: CreatePendingTOTP :execrows
+-- Starts enrolling with a new secret, replacing any unconfirmed one. Does
+-- nothing if two-factor authentication is already on.
+INSERT INTO user_totp (user_id, secret)
+VALUES ($1, $2)
+ON CONFLICT (user_id) DO UPDATE
+SET secret = EXCLUDED.secret, last_used_step = 0, created_at = CURRENT_TIMESTAMP
+WHERE user_totp.confirmed_at IS NULL;
+
+-- name: GetTOTPByUserId :one
+SELECT * FROM user_totp
+WHERE user_id = $1;
+
+-- name: ConfirmTOTP :execrows
+UPDATE user_totp
+SET confirmed_at = CURRENT_TIMESTAMP, last_used_step = $2
+WHERE user_id = $1 AND confirmed_at IS NULL;
+
+-- name: UseTOTPStep :execrows
+-- Accepts a code's time step only if it is later than the last one used, so
+-- each code works once.
+UPDATE user_totp
+SET last_used_step = $2
+WHERE user_id = $1 AND confirmed_at IS NOT NULL AND last_used_step < $2;
+
+-- name: DeleteTOTP :exec
+DELETE FROM user_totp
+WHERE user_id = $1;
+
+-- name: CreateRecoveryCodes :exec
+INSERT INTO totp_recovery_codes (user_id, code_hash)
+SELECT sqlc.arg(user_id), unnest(sqlc.arg(code_hashes)::text[]);
+
+-- name: CountUnusedRecoveryCodes :one
+SELECT COUNT(*) FROM totp_recovery_codes
+WHERE user_id = $1 AND used_at IS NULL;
+
+-- name: UseRecoveryCode :execrows
+UPDATE totp_recovery_codes
+SET used_at = CURRENT_TIMESTAMP
+WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL;
+
+-- name: DeleteRecoveryCodes :exec
+DELETE FROM totp_recovery_codes
+WHERE user_id = $1;
+
+-- name: CreateLoginChallenge :exec
+INSERT INTO login_challenges (user_id, token_hash, expires_at)
+VALUES ($1, $2, $3);
+
+-- name: AttemptLoginChallenge :one
+-- Counts an attempt at the challenge and returns its user, unless it has
+-- expired or run out of attempts.
+UPDATE login_challenges
+SET attempts = attempts + 1
+WHERE token_hash = sqlc.arg(token_hash)
+    AND expires_at > CURRENT_TIMESTAMP
+    AND attempts < sqlc.arg(max_attempts)::int
+RETURNING user_id;
+
+-- name: DeleteLoginChallenge :exec
+DELETE FROM login_challenges
+WHERE token_hash = $1;
+
+-- name: DeleteExpiredLoginChallenges :exec
+DELETE FROM login_challenges
+WHERE user_id = $1 AND expires_at <= CURRENT_TIMESTAMP;
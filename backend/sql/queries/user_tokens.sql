@@ -0,0 +1,21 @@
+-- name: CreateUserToken :exec
+INSERT INTO user_tokens (user_id, purpose, token_hash, expires_at)
+VALUES ($1, $2, $3, $4);
+
+-- name: UseUserToken :one
+-- Marks the token used and returns its user, if it is for the purpose,
+-- unused and unexpired. A token can only be used once.
+UPDATE user_tokens
+SET used_at = CURRENT_TIMESTAMP
+WHERE token_hash = $1
+    AND purpose = $2
+    AND used_at IS NULL
+    AND expires_at > CURRENT_TIMESTAMP
+RETURNING user_id;
+
+-- name: ExpireUserTokens :exec
+-- Makes the user's unused tokens for the purpose unusable, so only the
+-- latest link sent works.
+UPDATE user_tokens
+SET used_at = CURRENT_TIMESTAMP
+WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL;
@@ -6,3 +6,30 @@ RETURNING id, email, username;
 -- name: GetUserByEmail :one
 SELECT * FROM users
 WHERE email = $1;
+
+-- name: GetUserByUsernameOrEmail :one
+SELECT * FROM users
+WHERE username = sqlc.arg(identifier) OR email = sqlc.arg(identifier);
+
+-- name: MarkEmailVerified :exec
+UPDATE users
+SET email_verified_at = COALESCE(email_verified_at, CURRENT_TIMESTAMP)
+WHERE id = $1;
+
+-- name: UpdateUserPassword :exec
+UPDATE users
+SET password_hash = $2, updated_at = CURRENT_TIMESTAMP
+WHERE id = $1;
+
+-- name: GetUserById :one
+SELECT * FROM users
+WHERE id = $1;
+
+-- name: CreateUserWithoutPassword :one
+-- For users who sign up through an SSO provider. The empty hash matches no
+-- password; one can be set later with a password reset. A taken username
+-- returns no row, rather than failing the transaction it runs in.
+INSERT INTO users (email, username, password_hash, email_verified_at)
+VALUES ($1, $2, '', CURRENT_TIMESTAMP)
+ON CONFLICT (username) DO NOTHING
+RETURNING *;
@@ -0,0 +1,36 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE timeline_members (
+    timeline_id UUID NOT NULL REFERENCES timelines(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role VARCHAR(20) NOT NULL CHECK (role IN ('viewer', 'editor', 'owner')),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (timeline_id, user_id)
+);
+
+CREATE INDEX timeline_members_user_id_idx ON timeline_members(user_id);
+
+-- The creator of a timeline is always its first owner.
+CREATE FUNCTION add_timeline_owner() RETURNS TRIGGER AS $$
+BEGIN
+    INSERT INTO timeline_members (timeline_id, user_id, role)
+    VALUES (NEW.id, NEW.user_id, 'owner');
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER timelines_add_owner
+AFTER INSERT ON timelines
+FOR EACH ROW EXECUTE FUNCTION add_timeline_owner();
+
+INSERT INTO timeline_members (timeline_id, user_id, role)
+SELECT id, user_id, 'owner' FROM timelines;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TRIGGER IF EXISTS timelines_add_owner ON timelines;
+DROP FUNCTION IF EXISTS add_timeline_owner();
+DROP TABLE timeline_members;
+-- +goose StatementEnd
@@ -0,0 +1,34 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Years are astronomical (1 BCE is year 0, 44 BCE is -43) and stored as
+-- BIGINT so deep-time events fit. Times of day are minutes after midnight UTC.
+ALTER TABLE events
+    ADD COLUMN start_year BIGINT,
+    ADD COLUMN start_month SMALLINT CHECK (start_month BETWEEN 1 AND 12),
+    ADD COLUMN start_day SMALLINT CHECK (start_day BETWEEN 1 AND 31),
+    ADD COLUMN start_minute_of_day SMALLINT CHECK (start_minute_of_day BETWEEN 0 AND 1439),
+    ADD COLUMN end_year BIGINT,
+    ADD COLUMN end_month SMALLINT CHECK (end_month BETWEEN 1 AND 12),
+    ADD COLUMN end_day SMALLINT CHECK (end_day BETWEEN 1 AND 31),
+    ADD COLUMN end_minute_of_day SMALLINT CHECK (end_minute_of_day BETWEEN 0 AND 1439),
+    ADD COLUMN date_precision VARCHAR(10) CHECK (date_precision IN ('year', 'month', 'day', 'minute'));
+
+CREATE INDEX events_timeline_id_start_idx
+    ON events(timeline_id, start_year, start_month, start_day, start_minute_of_day);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS events_timeline_id_start_idx;
+
+ALTER TABLE events
+    DROP COLUMN start_year,
+    DROP COLUMN start_month,
+    DROP COLUMN start_day,
+    DROP COLUMN start_minute_of_day,
+    DROP COLUMN end_year,
+    DROP COLUMN end_month,
+    DROP COLUMN end_day,
+    DROP COLUMN end_minute_of_day,
+    DROP COLUMN date_precision;
+-- +goose StatementEnd
@@ -0,0 +1,24 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Positions are fractional index keys (see internal/fractional) and must be
+-- compared bytewise, hence the "C" collation.
+ALTER TABLE events ADD COLUMN position TEXT COLLATE "C";
+
+UPDATE events
+SET position = ranked.position
+FROM (
+    SELECT id, lpad(row_number() OVER (PARTITION BY timeline_id ORDER BY created_at, id)::text, 10, '0') || 'V' AS position
+    FROM events
+) AS ranked
+WHERE events.id = ranked.id;
+
+ALTER TABLE events ALTER COLUMN position SET NOT NULL;
+
+CREATE INDEX events_timeline_id_position_idx ON events(timeline_id, position);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS events_timeline_id_position_idx;
+ALTER TABLE events DROP COLUMN position;
+-- +goose StatementEnd
@@ -0,0 +1,40 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE timeline_revisions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    timeline_id UUID NOT NULL REFERENCES timelines(id) ON DELETE CASCADE,
+    revision INTEGER NOT NULL,
+    user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    action VARCHAR(50) NOT NULL,
+    restored_from INTEGER,
+    snapshot JSONB NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (timeline_id, revision)
+);
+
+-- The timeline and its events as they stand, in the shape of the API's
+-- timeline and event objects.
+CREATE FUNCTION timeline_snapshot(timeline UUID) RETURNS JSONB AS $$
+    SELECT jsonb_build_object(
+        'title', timelines.title,
+        'description', timelines.description,
+        'events', COALESCE(
+            (SELECT jsonb_agg(to_jsonb(events) ORDER BY events.position)
+             FROM events WHERE events.timeline_id = timelines.id),
+            '[]'::jsonb
+        )
+    )
+    FROM timelines
+    WHERE timelines.id = timeline;
+$$ LANGUAGE sql STABLE;
+
+-- Existing timelines start their history from how they are now.
+INSERT INTO timeline_revisions (timeline_id, revision, user_id, action, snapshot)
+SELECT id, 1, user_id, 'initial', timeline_snapshot(id) FROM timelines;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE timeline_revisions;
+DROP FUNCTION IF EXISTS timeline_snapshot(UUID);
+-- +goose StatementEnd
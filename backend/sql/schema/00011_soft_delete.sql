@@ -0,0 +1,46 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE timelines ADD COLUMN deleted_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE events ADD COLUMN deleted_at TIMESTAMP WITH TIME ZONE;
+
+-- For listing and purging the trash.
+CREATE INDEX timelines_deleted_at_idx ON timelines(deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX events_deleted_at_idx ON events(deleted_at) WHERE deleted_at IS NOT NULL;
+
+-- Trashed events are not part of the timeline's history.
+CREATE OR REPLACE FUNCTION timeline_snapshot(timeline UUID) RETURNS JSONB AS $$
+    SELECT jsonb_build_object(
+        'title', timelines.title,
+        'description', timelines.description,
+        'events', COALESCE(
+            (SELECT jsonb_agg(to_jsonb(events) ORDER BY events.position)
+             FROM events WHERE events.timeline_id = timelines.id AND events.deleted_at IS NULL),
+            '[]'::jsonb
+        )
+    )
+    FROM timelines
+    WHERE timelines.id = timeline;
+$$ LANGUAGE sql STABLE;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION timeline_snapshot(timeline UUID) RETURNS JSONB AS $$
+    SELECT jsonb_build_object(
+        'title', timelines.title,
+        'description', timelines.description,
+        'events', COALESCE(
+            (SELECT jsonb_agg(to_jsonb(events) ORDER BY events.position)
+             FROM events WHERE events.timeline_id = timelines.id),
+            '[]'::jsonb
+        )
+    )
+    FROM timelines
+    WHERE timelines.id = timeline;
+$$ LANGUAGE sql STABLE;
+
+DROP INDEX IF EXISTS events_deleted_at_idx;
+DROP INDEX IF EXISTS timelines_deleted_at_idx;
+ALTER TABLE events DROP COLUMN deleted_at;
+ALTER TABLE timelines DROP COLUMN deleted_at;
+-- +goose StatementEnd
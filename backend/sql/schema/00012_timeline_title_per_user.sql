@@ -0,0 +1,79 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE timelines DROP CONSTRAINT timelines_title_key;
+
+-- Titles only need to be unique per user, ignoring case. Timelines that now
+-- clash with another of the same user's get the lowest number added to the
+-- title that the user doesn't already have, so "Trip" becomes "Trip (3)" if
+-- they also have a "Trip (2)".
+DO $$
+DECLARE
+    clash RECORD;
+    n INTEGER;
+    renamed TEXT;
+BEGIN
+    FOR clash IN
+        SELECT id, user_id, title FROM (
+            SELECT id, user_id, title, created_at,
+                row_number() OVER (PARTITION BY user_id, lower(title) ORDER BY created_at, id) AS rank
+            FROM timelines
+            WHERE deleted_at IS NULL
+        ) AS ranked
+        WHERE rank > 1
+        ORDER BY created_at, id
+    LOOP
+        n := 2;
+        LOOP
+            renamed := left(clash.title, 240) || ' (' || n || ')';
+            EXIT WHEN NOT EXISTS (
+                SELECT 1 FROM timelines
+                WHERE user_id = clash.user_id AND lower(title) = lower(renamed) AND deleted_at IS NULL
+            );
+            n := n + 1;
+        END LOOP;
+        UPDATE timelines SET title = renamed WHERE id = clash.id;
+    END LOOP;
+END
+$$;
+
+-- Trashed timelines don't hold on to their titles.
+CREATE UNIQUE INDEX timelines_user_id_title_key ON timelines(user_id, lower(title))
+WHERE deleted_at IS NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS timelines_user_id_title_key;
+
+-- Titles go back to being unique across every user, trashed timelines
+-- included. Rolling back doesn't restore the titles Up renamed; instead, a
+-- timeline whose title another timeline already has, such as one two users
+-- both picked since, is renamed the same way.
+DO $$
+DECLARE
+    clash RECORD;
+    n INTEGER;
+    renamed TEXT;
+BEGIN
+    FOR clash IN
+        SELECT id, title FROM (
+            SELECT id, title, created_at,
+                row_number() OVER (PARTITION BY title ORDER BY created_at, id) AS rank
+            FROM timelines
+        ) AS ranked
+        WHERE rank > 1
+        ORDER BY created_at, id
+    LOOP
+        n := 2;
+        LOOP
+            renamed := left(clash.title, 240) || ' (' || n || ')';
+            EXIT WHEN NOT EXISTS (SELECT 1 FROM timelines WHERE title = renamed);
+            n := n + 1;
+        END LOOP;
+        UPDATE timelines SET title = renamed WHERE id = clash.id;
+    END LOOP;
+END
+$$;
+
+ALTER TABLE timelines ADD CONSTRAINT timelines_title_key UNIQUE (title);
+-- +goose StatementEnd
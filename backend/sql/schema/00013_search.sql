@@ -0,0 +1,55 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE timelines ADD COLUMN search_vector TSVECTOR GENERATED ALWAYS AS (
+    setweight(to_tsvector('english', title), 'A') ||
+    setweight(to_tsvector('english', COALESCE(description, '')), 'B')
+) STORED;
+
+ALTER TABLE events ADD COLUMN search_vector TSVECTOR GENERATED ALWAYS AS (
+    setweight(to_tsvector('english', title), 'A') ||
+    setweight(to_tsvector('english', card_title), 'A') ||
+    setweight(to_tsvector('english', COALESCE(card_subtitle, '')), 'B') ||
+    setweight(to_tsvector('english', COALESCE(card_detailed_text, '')), 'C')
+) STORED;
+
+CREATE INDEX timelines_search_vector_idx ON timelines USING GIN (search_vector);
+CREATE INDEX events_search_vector_idx ON events USING GIN (search_vector);
+
+-- The search vector is derived from the other columns, so snapshots leave
+-- it out.
+CREATE OR REPLACE FUNCTION timeline_snapshot(timeline UUID) RETURNS JSONB AS $$
+    SELECT jsonb_build_object(
+        'title', timelines.title,
+        'description', timelines.description,
+        'events', COALESCE(
+            (SELECT jsonb_agg(to_jsonb(events) - 'search_vector' ORDER BY events.position)
+             FROM events WHERE events.timeline_id = timelines.id AND events.deleted_at IS NULL),
+            '[]'::jsonb
+        )
+    )
+    FROM timelines
+    WHERE timelines.id = timeline;
+$$ LANGUAGE sql STABLE;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION timeline_snapshot(timeline UUID) RETURNS JSONB AS $$
+    SELECT jsonb_build_object(
+        'title', timelines.title,
+        'description', timelines.description,
+        'events', COALESCE(
+            (SELECT jsonb_agg(to_jsonb(events) ORDER BY events.position)
+             FROM events WHERE events.timeline_id = timelines.id AND events.deleted_at IS NULL),
+            '[]'::jsonb
+        )
+    )
+    FROM timelines
+    WHERE timelines.id = timeline;
+$$ LANGUAGE sql STABLE;
+
+DROP INDEX IF EXISTS events_search_vector_idx;
+DROP INDEX IF EXISTS timelines_search_vector_idx;
+ALTER TABLE events DROP COLUMN search_vector;
+ALTER TABLE timelines DROP COLUMN search_vector;
+-- +goose StatementEnd
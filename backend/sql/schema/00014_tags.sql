@@ -0,0 +1,73 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE tags (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(50) NOT NULL,
+    color VARCHAR(7) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX tags_user_id_name_key ON tags(user_id, lower(name));
+
+-- A user's own labels for the timelines they can see.
+CREATE TABLE timeline_tags (
+    timeline_id UUID NOT NULL REFERENCES timelines(id) ON DELETE CASCADE,
+    tag_id UUID NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+    PRIMARY KEY (timeline_id, tag_id)
+);
+
+CREATE INDEX timeline_tags_tag_id_idx ON timeline_tags(tag_id);
+
+-- Event tags are seen by everyone who can see the timeline.
+CREATE TABLE event_tags (
+    event_id UUID NOT NULL REFERENCES events(id) ON DELETE CASCADE,
+    tag_id UUID NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+    PRIMARY KEY (event_id, tag_id)
+);
+
+CREATE INDEX event_tags_tag_id_idx ON event_tags(tag_id);
+
+-- Snapshots keep each event's tags, so restoring a revision restores them.
+CREATE OR REPLACE FUNCTION timeline_snapshot(timeline UUID) RETURNS JSONB AS $$
+    SELECT jsonb_build_object(
+        'title', timelines.title,
+        'description', timelines.description,
+        'events', COALESCE(
+            (SELECT jsonb_agg(
+                (to_jsonb(events) - 'search_vector') || jsonb_build_object('tag_ids', COALESCE(
+                    (SELECT jsonb_agg(event_tags.tag_id) FROM event_tags WHERE event_tags.event_id = events.id),
+                    '[]'::jsonb
+                ))
+                ORDER BY events.position
+             )
+             FROM events WHERE events.timeline_id = timelines.id AND events.deleted_at IS NULL),
+            '[]'::jsonb
+        )
+    )
+    FROM timelines
+    WHERE timelines.id = timeline;
+$$ LANGUAGE sql STABLE;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION timeline_snapshot(timeline UUID) RETURNS JSONB AS $$
+    SELECT jsonb_build_object(
+        'title', timelines.title,
+        'description', timelines.description,
+        'events', COALESCE(
+            (SELECT jsonb_agg(to_jsonb(events) - 'search_vector' ORDER BY events.position)
+             FROM events WHERE events.timeline_id = timelines.id AND events.deleted_at IS NULL),
+            '[]'::jsonb
+        )
+    )
+    FROM timelines
+    WHERE timelines.id = timeline;
+$$ LANGUAGE sql STABLE;
+
+DROP TABLE event_tags;
+DROP TABLE timeline_tags;
+DROP TABLE tags;
+-- +goose StatementEnd
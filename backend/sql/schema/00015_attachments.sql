@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+-- event_id has no foreign key: restoring a revision puts events back under
+-- the same IDs, and their attachments have to survive that. The purge job
+-- removes attachments whose event is gone, along with their files.
+CREATE TABLE attachments (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    event_id UUID NOT NULL,
+    uploaded_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    filename VARCHAR(255) NOT NULL,
+    content_type VARCHAR(100) NOT NULL,
+    size_bytes BIGINT NOT NULL,
+    width INTEGER,
+    height INTEGER,
+    storage_key TEXT NOT NULL UNIQUE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX attachments_event_id_idx ON attachments(event_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE attachments;
+-- +goose StatementEnd
@@ -0,0 +1,45 @@
+-- +goose Up
+-- +goose StatementBegin
+-- A user's TOTP secret, encrypted with TOTP_ENCRYPTION_KEY. Two-factor
+-- authentication is on once the secret is confirmed with a first code.
+CREATE TABLE user_totp (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    secret BYTEA NOT NULL,
+    confirmed_at TIMESTAMP WITH TIME ZONE,
+    -- The time step of the last code accepted, so a code can't be replayed.
+    last_used_step BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- One-time codes for logging in without the authenticator. Only their
+-- hashes are stored.
+CREATE TABLE totp_recovery_codes (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code_hash TEXT NOT NULL,
+    used_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX totp_recovery_codes_user_id_idx ON totp_recovery_codes(user_id);
+
+-- Issued when the password is right but a second factor is still needed.
+-- Only their hashes are stored.
+CREATE TABLE login_challenges (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash TEXT UNIQUE NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX login_challenges_user_id_idx ON login_challenges(user_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE login_challenges;
+DROP TABLE totp_recovery_codes;
+DROP TABLE user_totp;
+-- +goose StatementEnd
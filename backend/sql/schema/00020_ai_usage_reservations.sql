@@ -0,0 +1,12 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Tokens held against a user's quotas while a generation runs, so requests
+-- made meanwhile can't spend them too. They are released once the real usage
+-- is recorded.
+ALTER TABLE ai_usage ADD COLUMN reserved_tokens INTEGER NOT NULL DEFAULT 0;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE ai_usage DROP COLUMN reserved_tokens;
+-- +goose StatementEnd